@@ -28,7 +28,26 @@ else
   echo "[pre-commit] clang-format not found; skipping formatting check"
 fi
 
-# 2) Build with warnings captured; fail on any warnings
+# 2) Smalltalk formatting check (stfmt): only staged chunk files are checked
+if command -v go >/dev/null 2>&1; then
+  ST_FILES=()
+  while IFS= read -r f; do
+    if [[ -f "$f" ]]; then
+      ST_FILES+=("$ROOT_DIR/$f")
+    fi
+  done < <(git diff --cached --name-only --diff-filter=ACM -- '*.st')
+  if [[ ${#ST_FILES[@]} -gt 0 ]]; then
+    echo "[pre-commit] Checking Smalltalk formatting (stfmt)"
+    if ! (cd "$ROOT_DIR/src/interpreter" && go run ./cmd/stfmt -check "${ST_FILES[@]}"); then
+      echo "[pre-commit] Formatting issues found. Please run stfmt on the listed files and re-stage."
+      exit 1
+    fi
+  fi
+else
+  echo "[pre-commit] go not found; skipping Smalltalk formatting check"
+fi
+
+# 3) Build with warnings captured; fail on any warnings
 echo "[pre-commit] Building project (capturing warnings)"
 BUILD_LOG="$(mktemp)"
 set +e
@@ -52,7 +71,7 @@ if grep -Ei "warning:" "$BUILD_LOG" >/dev/null 2>&1; then
   exit 1
 fi
 
-# 3) Run expression tests (fast gate)
+# 4) Run expression tests (fast gate)
 echo "[pre-commit] Running expression test suite"
 EXP_LOG="$(mktemp)"
 if ! "$ROOT_DIR"/src/cpp/tests/run_expression_tests.sh >"$EXP_LOG" 2>&1; then
@@ -61,7 +80,7 @@ if ! "$ROOT_DIR"/src/cpp/tests/run_expression_tests.sh >"$EXP_LOG" 2>&1; then
   exit 1
 fi
 
-# 4) Run full test suite (builds test binaries and runs them)
+# 5) Run full test suite (builds test binaries and runs them)
 echo "[pre-commit] Running full test suite"
 SUITE_LOG="$(mktemp)"
 if ! "$ROOT_DIR"/src/cpp/run_all_tests.sh >"$SUITE_LOG" 2>&1; then
@@ -75,7 +94,7 @@ if grep -Ei "warning:" "$SUITE_LOG" >/dev/null 2>&1; then
     exit 1
 fi
 
-# 5) Detect disallowed temporary files at repo root (common mistakes)
+# 6) Detect disallowed temporary files at repo root (common mistakes)
 DISALLOWED=("compile:in:" "start:")
 FOUND=()
 for f in "${DISALLOWED[@]}"; do
@@ -1,6 +1,9 @@
 package ast
 
 import (
+	"unicode"
+	"unicode/utf8"
+
 	"smalltalklsp/interpreter/pile"
 )
 
@@ -8,6 +11,9 @@ import (
 type Node interface {
 	// Accept accepts a visitor
 	Accept(visitor Visitor) interface{}
+
+	// Range returns the range of the node in the source code
+	Range() SourceRange
 }
 
 // Visitor is the interface for visitors
@@ -21,12 +27,21 @@ type Visitor interface {
 	// VisitSelfNode visits a self node
 	VisitSelfNode(node *SelfNode) interface{}
 
+	// VisitSuperNode visits a super node
+	VisitSuperNode(node *SuperNode) interface{}
+
+	// VisitThisContextNode visits a thisContext node
+	VisitThisContextNode(node *ThisContextNode) interface{}
+
 	// VisitLiteralNode visits a literal node
 	VisitLiteralNode(node *LiteralNode) interface{}
 
 	// VisitVariableNode visits a variable node
 	VisitVariableNode(node *VariableNode) interface{}
 
+	// VisitGlobalNode visits a global variable node
+	VisitGlobalNode(node *GlobalNode) interface{}
+
 	// VisitAssignmentNode visits an assignment node
 	VisitAssignmentNode(node *AssignmentNode) interface{}
 
@@ -35,6 +50,21 @@ type Visitor interface {
 
 	// VisitBlockNode visits a block node
 	VisitBlockNode(node *BlockNode) interface{}
+
+	// VisitCascadeNode visits a cascade node
+	VisitCascadeNode(node *CascadeNode) interface{}
+
+	// VisitSequenceNode visits a sequence node
+	VisitSequenceNode(node *SequenceNode) interface{}
+
+	// VisitDynamicArrayNode visits a dynamic array node
+	VisitDynamicArrayNode(node *DynamicArrayNode) interface{}
+
+	// VisitErrorNode visits an error node
+	VisitErrorNode(node *ErrorNode) interface{}
+
+	// VisitPragmaNode visits a pragma node
+	VisitPragmaNode(node *PragmaNode) interface{}
 }
 
 // MethodNode represents a method definition
@@ -48,11 +78,20 @@ type MethodNode struct {
 	// Temporaries are the method temporaries
 	Temporaries []string
 
+	// Pragmas are the pragmas of the method, such as <primitive: 1>
+	Pragmas []*PragmaNode
+
 	// Body is the method body
 	Body Node
 
 	// Class is the method class
 	Class *pile.Object
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
 }
 
 // Accept implements the Node interface
@@ -60,11 +99,22 @@ func (n *MethodNode) Accept(visitor Visitor) interface{} {
 	return visitor.VisitMethodNode(n)
 }
 
+// Range implements the Node interface
+func (n *MethodNode) Range() SourceRange {
+	return n.Source
+}
+
 
 // ReturnNode represents a return statement
 type ReturnNode struct {
 	// Expression is the expression to return
 	Expression Node
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
 }
 
 // Accept implements the Node interface
@@ -72,20 +122,83 @@ func (n *ReturnNode) Accept(visitor Visitor) interface{} {
 	return visitor.VisitReturnNode(n)
 }
 
+// Range implements the Node interface
+func (n *ReturnNode) Range() SourceRange {
+	return n.Source
+}
+
 
 // SelfNode represents the self reference
-type SelfNode struct{}
+type SelfNode struct {
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
 
 // Accept implements the Node interface
 func (n *SelfNode) Accept(visitor Visitor) interface{} {
 	return visitor.VisitSelfNode(n)
 }
 
+// Range implements the Node interface
+func (n *SelfNode) Range() SourceRange {
+	return n.Source
+}
+
+
+// SuperNode represents the super reference, which is self with method
+// lookup starting in the superclass of the method's class
+type SuperNode struct {
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
+
+// Accept implements the Node interface
+func (n *SuperNode) Accept(visitor Visitor) interface{} {
+	return visitor.VisitSuperNode(n)
+}
+
+// Range implements the Node interface
+func (n *SuperNode) Range() SourceRange {
+	return n.Source
+}
+
+
+// ThisContextNode represents the thisContext reference to the current context
+type ThisContextNode struct {
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
+
+// Accept implements the Node interface
+func (n *ThisContextNode) Accept(visitor Visitor) interface{} {
+	return visitor.VisitThisContextNode(n)
+}
+
+// Range implements the Node interface
+func (n *ThisContextNode) Range() SourceRange {
+	return n.Source
+}
+
 
 // LiteralNode represents a literal value
 type LiteralNode struct {
 	// Value is the literal value
 	Value *pile.Object
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
 }
 
 // Accept implements the Node interface
@@ -93,11 +206,22 @@ func (n *LiteralNode) Accept(visitor Visitor) interface{} {
 	return visitor.VisitLiteralNode(n)
 }
 
+// Range implements the Node interface
+func (n *LiteralNode) Range() SourceRange {
+	return n.Source
+}
+
 
 // VariableNode represents a variable reference
 type VariableNode struct {
 	// Name is the variable name
 	Name string
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
 }
 
 // Accept implements the Node interface
@@ -105,6 +229,41 @@ func (n *VariableNode) Accept(visitor Visitor) interface{} {
 	return visitor.VisitVariableNode(n)
 }
 
+// Range implements the Node interface
+func (n *VariableNode) Range() SourceRange {
+	return n.Source
+}
+
+// GlobalNode represents a reference to a global variable such as a class.
+// The global is looked up when the method runs, not when it is compiled.
+type GlobalNode struct {
+	// Name is the name of the global
+	Name string
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
+
+// IsGlobalName returns true if a variable name refers to a global, which is
+// the case for names starting with an uppercase letter
+func IsGlobalName(name string) bool {
+	first, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(first)
+}
+
+// Accept implements the Node interface
+func (n *GlobalNode) Accept(visitor Visitor) interface{} {
+	return visitor.VisitGlobalNode(n)
+}
+
+// Range implements the Node interface
+func (n *GlobalNode) Range() SourceRange {
+	return n.Source
+}
+
 
 // AssignmentNode represents an assignment
 type AssignmentNode struct {
@@ -113,6 +272,12 @@ type AssignmentNode struct {
 
 	// Expression is the expression to assign
 	Expression Node
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
 }
 
 // Accept implements the Node interface
@@ -120,6 +285,11 @@ func (n *AssignmentNode) Accept(visitor Visitor) interface{} {
 	return visitor.VisitAssignmentNode(n)
 }
 
+// Range implements the Node interface
+func (n *AssignmentNode) Range() SourceRange {
+	return n.Source
+}
+
 
 // MessageSendNode represents a message send
 type MessageSendNode struct {
@@ -131,6 +301,12 @@ type MessageSendNode struct {
 
 	// Arguments are the message arguments
 	Arguments []Node
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
 }
 
 // Accept implements the Node interface
@@ -138,6 +314,11 @@ func (n *MessageSendNode) Accept(visitor Visitor) interface{} {
 	return visitor.VisitMessageSendNode(n)
 }
 
+// Range implements the Node interface
+func (n *MessageSendNode) Range() SourceRange {
+	return n.Source
+}
+
 
 // BlockNode represents a block
 type BlockNode struct {
@@ -149,9 +330,160 @@ type BlockNode struct {
 
 	// Body is the block body
 	Body Node
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
 }
 
 // Accept implements the Node interface
 func (n *BlockNode) Accept(visitor Visitor) interface{} {
 	return visitor.VisitBlockNode(n)
-}
\ No newline at end of file
+}
+
+// Range implements the Node interface
+func (n *BlockNode) Range() SourceRange {
+	return n.Source
+}
+
+
+// CascadeNode represents a cascade of messages sent to the same receiver,
+// e.g. Transcript show: 'hello'; cr
+type CascadeNode struct {
+	// Receiver is the receiver shared by every message in the cascade
+	Receiver Node
+
+	// Messages are the cascaded messages in source order. Each message's
+	// receiver chain ends in Receiver, so a part like "; foo bar" is stored
+	// as bar sent to (foo sent to Receiver)
+	Messages []*MessageSendNode
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
+
+// Accept implements the Node interface
+func (n *CascadeNode) Accept(visitor Visitor) interface{} {
+	return visitor.VisitCascadeNode(n)
+}
+
+// Range implements the Node interface
+func (n *CascadeNode) Range() SourceRange {
+	return n.Source
+}
+
+
+// SequenceNode represents the temporaries and statements of a method,
+// block or doIt
+type SequenceNode struct {
+	// Temporaries are the temporaries declared at the start of the sequence
+	Temporaries []string
+
+	// Statements are the statements in source order
+	Statements []Node
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
+
+// Accept implements the Node interface
+func (n *SequenceNode) Accept(visitor Visitor) interface{} {
+	return visitor.VisitSequenceNode(n)
+}
+
+// Range implements the Node interface
+func (n *SequenceNode) Range() SourceRange {
+	return n.Source
+}
+
+
+// DynamicArrayNode represents a brace array such as {a. b + 1. c},
+// whose elements are evaluated at runtime
+type DynamicArrayNode struct {
+	// Elements are the element expressions in source order
+	Elements []Node
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
+
+// Accept implements the Node interface
+func (n *DynamicArrayNode) Accept(visitor Visitor) interface{} {
+	return visitor.VisitDynamicArrayNode(n)
+}
+
+// Range implements the Node interface
+func (n *DynamicArrayNode) Range() SourceRange {
+	return n.Source
+}
+
+// ErrorNode is a placeholder for code that could not be parsed,
+// it is only created when the parser recovers from errors
+type ErrorNode struct {
+	// Message describes the parse error
+	Message string
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
+
+// Accept implements the Node interface
+func (n *ErrorNode) Accept(visitor Visitor) interface{} {
+	return visitor.VisitErrorNode(n)
+}
+
+// Range implements the Node interface
+func (n *ErrorNode) Range() SourceRange {
+	return n.Source
+}
+
+// PragmaNode represents a method annotation such as <primitive: 60 error: ec>
+// or <category: 'accessing'>
+type PragmaNode struct {
+	// Selector is the selector of the pragma, e.g. primitive:error:
+	Selector string
+
+	// Arguments are the arguments in source order. Each one is a LiteralNode,
+	// or a VariableNode for a name such as the error code of a primitive.
+	Arguments []Node
+
+	// Source is the range of the node in the source code
+	Source SourceRange
+
+	// Comments are the comments attached to the node
+	Comments
+}
+
+// Accept implements the Node interface
+func (n *PragmaNode) Accept(visitor Visitor) interface{} {
+	return visitor.VisitPragmaNode(n)
+}
+
+// Range implements the Node interface
+func (n *PragmaNode) Range() SourceRange {
+	return n.Source
+}
+
+// Pragma returns the first pragma of the method with the given selector, or nil
+func (n *MethodNode) Pragma(selector string) *PragmaNode {
+	for _, pragma := range n.Pragmas {
+		if pragma.Selector == selector {
+			return pragma
+		}
+	}
+	return nil
+}
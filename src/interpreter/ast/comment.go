@@ -0,0 +1,35 @@
+package ast
+
+// Comment is a "comment" in the source code
+type Comment struct {
+	// Text is the text between the double quotes
+	Text string
+
+	// Source is the range of the comment including the double quotes
+	Source SourceRange
+}
+
+// Commented is implemented by nodes that comments can be attached to
+type Commented interface {
+	// AttachComment attaches a comment to the node
+	AttachComment(comment Comment)
+
+	// NodeComments returns the comments attached to the node in source order
+	NodeComments() []Comment
+}
+
+// Comments holds the comments attached to a node, it is embedded in every node
+type Comments struct {
+	// Comments are the comments attached to the node in source order
+	Comments []Comment
+}
+
+// AttachComment implements the Commented interface
+func (c *Comments) AttachComment(comment Comment) {
+	c.Comments = append(c.Comments, comment)
+}
+
+// NodeComments implements the Commented interface
+func (c *Comments) NodeComments() []Comment {
+	return c.Comments
+}
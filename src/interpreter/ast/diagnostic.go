@@ -0,0 +1,51 @@
+package ast
+
+import "fmt"
+
+// Severity is the severity of a diagnostic
+type Severity int
+
+const (
+	// SeverityError marks code that can't be compiled
+	SeverityError Severity = iota
+
+	// SeverityWarning marks code that compiles but is likely wrong
+	SeverityWarning
+
+	// SeverityInfo marks remarks about code that is fine
+	SeverityInfo
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a problem found in the source code
+type Diagnostic struct {
+	// Range is the range of the source code the diagnostic applies to
+	Range SourceRange
+
+	// Severity is the severity of the diagnostic
+	Severity Severity
+
+	// Code identifies the kind of problem, e.g. "missing-delimiter"
+	Code string
+
+	// Message describes the problem
+	Message string
+}
+
+// String returns the diagnostic as line:column: severity: message [code]
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", d.Range.Start, d.Severity, d.Message, d.Code)
+}
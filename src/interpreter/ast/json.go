@@ -0,0 +1,655 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"smalltalklsp/interpreter/pile"
+)
+
+// The JSON form of an AST is an object for every node, with a "type" such
+// as "MessageSendNode" and the fields of the node:
+//
+//	{"type": "MessageSendNode",
+//	 "receiver": {"type": "VariableNode", "name": "x"},
+//	 "selector": "max:",
+//	 "arguments": [{"type": "LiteralNode", "value": {"type": "Integer", "value": 3}}],
+//	 "range": {"start": {"offset": 0, "line": 1, "column": 1}, "end": {...}}}
+//
+// Literal values are objects with their own "type": Integer, Float,
+// ScaledDecimal, Character, String, Symbol, Boolean, Nil, Array with
+// "elements" and ByteArray with "bytes". A scaled decimal has its printed
+// "value", such as "1.25s2", and its exact "fraction", such as "5/4".
+//
+// In the messages of a cascade, the receiver at the end of each receiver
+// chain is the receiver of the cascade and is written as null. The pragmas
+// of a method and the comments of a node are left out when there are none.
+// The class of a method is not part of the JSON form.
+
+// LiteralFactory creates the objects of literals, it is implemented by the VM
+type LiteralFactory interface {
+	NewInteger(value int64) *pile.Object
+	NewFloat(value float64) *pile.Object
+	NewScaledDecimal(value *big.Rat, scale int) *pile.Object
+	NewCharacter(value rune) *pile.Object
+	NewString(value string) *pile.Object
+	NewSymbol(value string) *pile.Object
+	NewArray(size int) *pile.Object
+	NewByteArray(size int) *pile.Object
+}
+
+// JSONOptions configures the JSON form of an AST
+type JSONOptions struct {
+	// OmitRanges leaves out the source ranges of nodes and comments
+	OmitRanges bool
+
+	// OmitComments leaves out the comments attached to nodes
+	OmitComments bool
+
+	// Indent indents nested objects and arrays, JSON is compact if empty
+	Indent string
+}
+
+// EncodeJSON returns the JSON form of an AST
+func EncodeJSON(node Node, options JSONOptions) ([]byte, error) {
+	e := &jsonEncoder{options: options}
+	value, err := e.node(node, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Indent != "" {
+		return json.MarshalIndent(value, "", options.Indent)
+	}
+	return json.Marshal(value)
+}
+
+// DecodeJSON returns the AST of a JSON form, creating the literals with the factory
+func DecodeJSON(data []byte, literals LiteralFactory) (Node, error) {
+	d := &jsonDecoder{literals: literals}
+	return d.node(data, nil)
+}
+
+// jsonField is a field of a JSON object
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// jsonObject is a JSON object that keeps the order of its fields
+type jsonObject []jsonField
+
+// MarshalJSON implements json.Marshaler
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// jsonEncoder builds the JSON form of an AST
+type jsonEncoder struct {
+	options JSONOptions
+}
+
+// node returns the JSON object of a node. In a message part of a cascade,
+// the receiver of the cascade is written as null.
+func (e *jsonEncoder) node(node Node, cascadeReceiver Node) (interface{}, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	var o jsonObject
+	add := func(key string, value interface{}) {
+		o = append(o, jsonField{key, value})
+	}
+	var err error
+	child := func(key string, node Node) {
+		if err != nil {
+			return
+		}
+		var value interface{}
+		value, err = e.node(node, nil)
+		add(key, value)
+	}
+	children := func(key string, nodes []Node) {
+		values := []interface{}{}
+		for _, node := range nodes {
+			if err != nil {
+				return
+			}
+			var value interface{}
+			value, err = e.node(node, nil)
+			values = append(values, value)
+		}
+		add(key, values)
+	}
+
+	switch n := node.(type) {
+	case *MethodNode:
+		add("type", "MethodNode")
+		add("selector", n.Selector)
+		add("parameters", names(n.Parameters))
+		add("temporaries", names(n.Temporaries))
+		if len(n.Pragmas) > 0 {
+			pragmas := make([]Node, len(n.Pragmas))
+			for i, pragma := range n.Pragmas {
+				pragmas[i] = pragma
+			}
+			children("pragmas", pragmas)
+		}
+		child("body", n.Body)
+	case *ReturnNode:
+		add("type", "ReturnNode")
+		child("expression", n.Expression)
+	case *SelfNode:
+		add("type", "SelfNode")
+	case *SuperNode:
+		add("type", "SuperNode")
+	case *ThisContextNode:
+		add("type", "ThisContextNode")
+	case *LiteralNode:
+		add("type", "LiteralNode")
+		var value interface{}
+		value, err = encodeLiteral(n.Value)
+		add("value", value)
+	case *VariableNode:
+		add("type", "VariableNode")
+		add("name", n.Name)
+	case *GlobalNode:
+		add("type", "GlobalNode")
+		add("name", n.Name)
+	case *AssignmentNode:
+		add("type", "AssignmentNode")
+		add("variable", n.Variable)
+		child("expression", n.Expression)
+	case *MessageSendNode:
+		add("type", "MessageSendNode")
+		switch {
+		case cascadeReceiver != nil && n.Receiver == cascadeReceiver:
+			add("receiver", nil)
+		case cascadeReceiver != nil:
+			var receiver interface{}
+			receiver, err = e.node(n.Receiver, cascadeReceiver)
+			add("receiver", receiver)
+		default:
+			child("receiver", n.Receiver)
+		}
+		add("selector", n.Selector)
+		children("arguments", n.Arguments)
+	case *BlockNode:
+		add("type", "BlockNode")
+		add("parameters", names(n.Parameters))
+		add("temporaries", names(n.Temporaries))
+		child("body", n.Body)
+	case *CascadeNode:
+		add("type", "CascadeNode")
+		child("receiver", n.Receiver)
+		messages := []interface{}{}
+		for _, message := range n.Messages {
+			if err != nil {
+				break
+			}
+			var value interface{}
+			value, err = e.node(message, n.Receiver)
+			messages = append(messages, value)
+		}
+		add("messages", messages)
+	case *SequenceNode:
+		add("type", "SequenceNode")
+		add("temporaries", names(n.Temporaries))
+		children("statements", n.Statements)
+	case *DynamicArrayNode:
+		add("type", "DynamicArrayNode")
+		children("elements", n.Elements)
+	case *ErrorNode:
+		add("type", "ErrorNode")
+		add("message", n.Message)
+	case *PragmaNode:
+		add("type", "PragmaNode")
+		add("selector", n.Selector)
+		children("arguments", n.Arguments)
+	default:
+		return nil, fmt.Errorf("can't encode a %T", node)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if commented, ok := node.(Commented); ok && !e.options.OmitComments && len(commented.NodeComments()) > 0 {
+		comments := []interface{}{}
+		for _, comment := range commented.NodeComments() {
+			c := jsonObject{{"text", comment.Text}}
+			if !e.options.OmitRanges {
+				c = append(c, jsonField{"range", encodeRange(comment.Source)})
+			}
+			comments = append(comments, c)
+		}
+		add("comments", comments)
+	}
+	if !e.options.OmitRanges {
+		add("range", encodeRange(node.Range()))
+	}
+
+	return o, nil
+}
+
+// names returns a list of names that is written as [] when empty
+func names(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// encodeRange returns the JSON object of a source range
+func encodeRange(source SourceRange) jsonObject {
+	position := func(p Position) jsonObject {
+		return jsonObject{{"offset", p.Offset}, {"line", p.Line}, {"column", p.Column}}
+	}
+	return jsonObject{{"start", position(source.Start)}, {"end", position(source.End)}}
+}
+
+// encodeLiteral returns the JSON object of a literal value
+func encodeLiteral(value *pile.Object) (interface{}, error) {
+	switch {
+	case value == nil:
+		return nil, nil
+	case pile.IsIntegerImmediate(value):
+		return jsonObject{{"type", "Integer"}, {"value", pile.GetIntegerImmediate(value)}}, nil
+	case pile.IsTrueImmediate(value):
+		return jsonObject{{"type", "Boolean"}, {"value", true}}, nil
+	case pile.IsFalseImmediate(value):
+		return jsonObject{{"type", "Boolean"}, {"value", false}}, nil
+	case pile.IsNilImmediate(value):
+		return jsonObject{{"type", "Nil"}}, nil
+	case pile.IsFloatImmediate(value):
+		return jsonObject{{"type", "Float"}, {"value", floatNumber(value)}}, nil
+	case pile.IsCharacterImmediate(value):
+		return jsonObject{{"type", "Character"}, {"value", string(pile.GetCharacterImmediate(value))}}, nil
+	}
+
+	switch value.Type() {
+	case pile.OBJ_SCALED_DECIMAL:
+		decimal := pile.ObjectToScaledDecimal(value)
+		return jsonObject{{"type", "ScaledDecimal"}, {"value", decimal.String()}, {"fraction", decimal.GetValue().String()}}, nil
+	case pile.OBJ_STRING:
+		return jsonObject{{"type", "String"}, {"value", pile.ObjectToString(value).GetValue()}}, nil
+	case pile.OBJ_SYMBOL:
+		return jsonObject{{"type", "Symbol"}, {"value", pile.ObjectToSymbol(value).GetValue()}}, nil
+	case pile.OBJ_ARRAY:
+		array := pile.ObjectToArray(value)
+		elements := make([]interface{}, array.Size())
+		for i := range elements {
+			element, err := encodeLiteral(array.At(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return jsonObject{{"type", "Array"}, {"elements", elements}}, nil
+	case pile.OBJ_BYTE_ARRAY:
+		byteArray := pile.ObjectToByteArray(value)
+		bytes := make([]int, byteArray.Size())
+		for i := range bytes {
+			bytes[i] = int(byteArray.At(i))
+		}
+		return jsonObject{{"type", "ByteArray"}, {"bytes", bytes}}, nil
+	}
+
+	return nil, fmt.Errorf("can't encode a literal of type %d", value.Type())
+}
+
+// floatNumber returns the shortest decimal form of an immediate float that
+// gives the same immediate float back. Immediate floats drop the lowest bits,
+// so 3.14 is not exactly the float64 closest to 3.14 anymore.
+func floatNumber(value *pile.Object) json.Number {
+	f := pile.GetFloatImmediate(value)
+	for precision := 1; precision < 17; precision++ {
+		text := strconv.FormatFloat(f, 'g', precision, 64)
+		parsed, err := strconv.ParseFloat(text, 64)
+		if err == nil && pile.MakeFloatImmediate(parsed) == value {
+			return json.Number(text)
+		}
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// jsonNode holds the fields of the JSON object of any node
+type jsonNode struct {
+	Type        string            `json:"type"`
+	Selector    string            `json:"selector"`
+	Name        string            `json:"name"`
+	Variable    string            `json:"variable"`
+	Message     string            `json:"message"`
+	Parameters  []string          `json:"parameters"`
+	Temporaries []string          `json:"temporaries"`
+	Pragmas     []json.RawMessage `json:"pragmas"`
+	Body        json.RawMessage   `json:"body"`
+	Expression  json.RawMessage   `json:"expression"`
+	Receiver    json.RawMessage   `json:"receiver"`
+	Arguments   []json.RawMessage `json:"arguments"`
+	Messages    []json.RawMessage `json:"messages"`
+	Statements  []json.RawMessage `json:"statements"`
+	Elements    []json.RawMessage `json:"elements"`
+	Value       json.RawMessage   `json:"value"`
+	Comments    []jsonComment     `json:"comments"`
+	Range       *jsonRange        `json:"range"`
+}
+
+// jsonComment is the JSON object of a comment
+type jsonComment struct {
+	Text  string     `json:"text"`
+	Range *jsonRange `json:"range"`
+}
+
+// jsonRange is the JSON object of a source range
+type jsonRange struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+// jsonPosition is the JSON object of a position
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// sourceRange returns the source range of a JSON range, or the empty range
+func (r *jsonRange) sourceRange() SourceRange {
+	if r == nil {
+		return SourceRange{}
+	}
+	return SourceRange{
+		Start: Position{Offset: r.Start.Offset, Line: r.Start.Line, Column: r.Start.Column},
+		End:   Position{Offset: r.End.Offset, Line: r.End.Line, Column: r.End.Column},
+	}
+}
+
+// jsonLiteral holds the fields of the JSON object of any literal value
+type jsonLiteral struct {
+	Type     string            `json:"type"`
+	Value    json.RawMessage   `json:"value"`
+	Fraction string            `json:"fraction"`
+	Elements []json.RawMessage `json:"elements"`
+	Bytes    []int             `json:"bytes"`
+}
+
+// jsonDecoder builds an AST from its JSON form
+type jsonDecoder struct {
+	literals LiteralFactory
+}
+
+// isNull returns true if a JSON value is missing or null
+func isNull(data json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(data))
+	return trimmed == "" || trimmed == "null"
+}
+
+// node returns the node of a JSON object. In a message part of a cascade,
+// a null receiver stands for the receiver of the cascade.
+func (d *jsonDecoder) node(data json.RawMessage, cascadeReceiver Node) (Node, error) {
+	if isNull(data) {
+		return nil, nil
+	}
+
+	var j jsonNode
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+
+	var err error
+	child := func(data json.RawMessage) Node {
+		if err != nil {
+			return nil
+		}
+		var node Node
+		node, err = d.node(data, nil)
+		return node
+	}
+	children := func(list []json.RawMessage) []Node {
+		var nodes []Node
+		for _, data := range list {
+			node := child(data)
+			if err != nil {
+				return nil
+			}
+			if node == nil {
+				err = fmt.Errorf("missing %s child", j.Type)
+				return nil
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	source := j.Range.sourceRange()
+	var node Node
+	switch j.Type {
+	case "MethodNode":
+		method := &MethodNode{Selector: j.Selector, Parameters: j.Parameters, Temporaries: j.Temporaries, Source: source}
+		for _, node := range children(j.Pragmas) {
+			pragma, ok := node.(*PragmaNode)
+			if !ok {
+				return nil, fmt.Errorf("a pragma can't be a %T", node)
+			}
+			method.Pragmas = append(method.Pragmas, pragma)
+		}
+		method.Body = child(j.Body)
+		node = method
+	case "ReturnNode":
+		node = &ReturnNode{Expression: child(j.Expression), Source: source}
+	case "SelfNode":
+		node = &SelfNode{Source: source}
+	case "SuperNode":
+		node = &SuperNode{Source: source}
+	case "ThisContextNode":
+		node = &ThisContextNode{Source: source}
+	case "LiteralNode":
+		value, literalErr := d.literal(j.Value)
+		if literalErr != nil {
+			return nil, literalErr
+		}
+		node = &LiteralNode{Value: value, Source: source}
+	case "VariableNode":
+		node = &VariableNode{Name: j.Name, Source: source}
+	case "GlobalNode":
+		node = &GlobalNode{Name: j.Name, Source: source}
+	case "AssignmentNode":
+		node = &AssignmentNode{Variable: j.Variable, Expression: child(j.Expression), Source: source}
+	case "MessageSendNode":
+		message := &MessageSendNode{Selector: j.Selector, Source: source}
+		switch {
+		case cascadeReceiver != nil && isNull(j.Receiver):
+			message.Receiver = cascadeReceiver
+		case cascadeReceiver != nil:
+			message.Receiver, err = d.node(j.Receiver, cascadeReceiver)
+		default:
+			message.Receiver = child(j.Receiver)
+			if err == nil && message.Receiver == nil {
+				err = fmt.Errorf("message %s without a receiver", j.Selector)
+			}
+		}
+		message.Arguments = children(j.Arguments)
+		node = message
+	case "BlockNode":
+		node = &BlockNode{Parameters: j.Parameters, Temporaries: j.Temporaries, Body: child(j.Body), Source: source}
+	case "CascadeNode":
+		cascade := &CascadeNode{Receiver: child(j.Receiver), Source: source}
+		if err == nil && cascade.Receiver == nil {
+			return nil, fmt.Errorf("cascade without a receiver")
+		}
+		for _, data := range j.Messages {
+			if err != nil {
+				break
+			}
+			var part Node
+			part, err = d.node(data, cascade.Receiver)
+			message, ok := part.(*MessageSendNode)
+			if err == nil && !ok {
+				err = fmt.Errorf("a cascade message can't be a %T", part)
+			}
+			cascade.Messages = append(cascade.Messages, message)
+		}
+		node = cascade
+	case "SequenceNode":
+		node = &SequenceNode{Temporaries: j.Temporaries, Statements: children(j.Statements), Source: source}
+	case "DynamicArrayNode":
+		node = &DynamicArrayNode{Elements: children(j.Elements), Source: source}
+	case "ErrorNode":
+		node = &ErrorNode{Message: j.Message, Source: source}
+	case "PragmaNode":
+		node = &PragmaNode{Selector: j.Selector, Arguments: children(j.Arguments), Source: source}
+	default:
+		return nil, fmt.Errorf("unknown node type %q", j.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if commented, ok := node.(Commented); ok {
+		for _, comment := range j.Comments {
+			commented.AttachComment(Comment{Text: comment.Text, Source: comment.Range.sourceRange()})
+		}
+	}
+
+	return node, nil
+}
+
+// literal returns the object of a literal value
+func (d *jsonDecoder) literal(data json.RawMessage) (*pile.Object, error) {
+	if isNull(data) {
+		return nil, nil
+	}
+
+	var j jsonLiteral
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+
+	value := strings.TrimSpace(string(j.Value))
+	switch j.Type {
+	case "Integer":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Integer %s", value)
+		}
+		return d.literals.NewInteger(i), nil
+
+	case "Float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Float %s", value)
+		}
+		return d.literals.NewFloat(f), nil
+
+	case "ScaledDecimal":
+		var printed string
+		if err := json.Unmarshal(j.Value, &printed); err != nil {
+			return nil, fmt.Errorf("invalid ScaledDecimal %s", value)
+		}
+		return d.scaledDecimal(printed, j.Fraction)
+
+	case "Boolean":
+		var b bool
+		if err := json.Unmarshal(j.Value, &b); err != nil {
+			return nil, fmt.Errorf("invalid Boolean %s", value)
+		}
+		return pile.NewBoolean(b).(*pile.Object), nil
+
+	case "Nil":
+		return pile.MakeNilImmediate(), nil
+	}
+
+	var s string
+	if j.Type == "Character" || j.Type == "String" || j.Type == "Symbol" {
+		if err := json.Unmarshal(j.Value, &s); err != nil {
+			return nil, fmt.Errorf("invalid %s %s", j.Type, value)
+		}
+	}
+
+	switch j.Type {
+	case "Character":
+		runes := []rune(s)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("invalid Character %s", value)
+		}
+		return d.literals.NewCharacter(runes[0]), nil
+
+	case "String":
+		return d.literals.NewString(s), nil
+
+	case "Symbol":
+		return d.literals.NewSymbol(s), nil
+
+	case "Array":
+		object := d.literals.NewArray(len(j.Elements))
+		array := pile.ObjectToArray(object)
+		for i, data := range j.Elements {
+			element, err := d.literal(data)
+			if err != nil {
+				return nil, err
+			}
+			array.AtPut(i, element)
+		}
+		return object, nil
+
+	case "ByteArray":
+		object := d.literals.NewByteArray(len(j.Bytes))
+		byteArray := pile.ObjectToByteArray(object)
+		for i, b := range j.Bytes {
+			if b < 0 || b > 255 {
+				return nil, fmt.Errorf("invalid byte %d", b)
+			}
+			byteArray.AtPut(i, byte(b))
+		}
+		return object, nil
+	}
+
+	return nil, fmt.Errorf("unknown literal type %q", j.Type)
+}
+
+// scaledDecimal returns a scaled decimal from its printed form, such as
+// 1.25s2, and its exact fraction if known
+func (d *jsonDecoder) scaledDecimal(printed string, fraction string) (*pile.Object, error) {
+	s := strings.LastIndexByte(printed, 's')
+	if s < 0 {
+		return nil, fmt.Errorf("invalid ScaledDecimal %q", printed)
+	}
+	scale, err := strconv.Atoi(printed[s+1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ScaledDecimal %q", printed)
+	}
+
+	exact := fraction
+	if exact == "" {
+		exact = printed[:s]
+	}
+	value, ok := new(big.Rat).SetString(exact)
+	if !ok {
+		return nil, fmt.Errorf("invalid ScaledDecimal %q", printed)
+	}
+
+	return d.literals.NewScaledDecimal(value, scale), nil
+}
@@ -0,0 +1,151 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/parser"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestJSONRoundTrip tests that decoding the JSON form of an AST gives the
+// same AST, including ranges, comments and literals
+func TestJSONRoundTrip(t *testing.T) {
+	sources := []string{
+		"foo: x <primitive: 60> \"Answer\" | a | a := x + 1. ^a",
+		"bar ^#(1 $a #sym 'str' (2.5 nil true) #[1 2 255]) , {3.14. 1.25s2. 1.2345s2. -7. thisContext}",
+		"baz Transcript show: 'a'; show: super printString; cr \"done\"",
+		"qux ^[:a :b | | t | t := a @ b. t] value: 1 value: 2",
+		"wide ^'héllo' , $é asString",
+	}
+
+	for _, source := range sources {
+		t.Run(source, func(t *testing.T) {
+			node := parseMethod(t, source)
+
+			encoded, err := ast.EncodeJSON(node, ast.JSONOptions{})
+			if err != nil {
+				t.Fatalf("Error encoding: %v", err)
+			}
+			decoded, err := ast.DecodeJSON(encoded, vm.NewVM())
+			if err != nil {
+				t.Fatalf("Error decoding %s: %v", encoded, err)
+			}
+			again, err := ast.EncodeJSON(decoded, ast.JSONOptions{})
+			if err != nil {
+				t.Fatalf("Error encoding the decoded AST: %v", err)
+			}
+
+			if string(encoded) != string(again) {
+				t.Errorf("Expected the decoded AST to encode to\n%s\ngot\n%s", encoded, again)
+			}
+		})
+	}
+}
+
+// TestJSONCascade tests that decoded cascade messages share the receiver
+func TestJSONCascade(t *testing.T) {
+	node, err := ast.DecodeJSON([]byte(`{"type":"CascadeNode",
+		"receiver":{"type":"VariableNode","name":"x"},
+		"messages":[
+			{"type":"MessageSendNode","receiver":null,"selector":"foo","arguments":[]},
+			{"type":"MessageSendNode","receiver":{"type":"MessageSendNode","receiver":null,"selector":"bar","arguments":[]},"selector":"baz","arguments":[]}]}`), vm.NewVM())
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	cascade := node.(*ast.CascadeNode)
+	if cascade.Messages[0].Receiver != cascade.Receiver {
+		t.Errorf("Expected foo to be sent to the cascade receiver")
+	}
+	inner, ok := cascade.Messages[1].Receiver.(*ast.MessageSendNode)
+	if !ok || inner.Receiver != cascade.Receiver {
+		t.Errorf("Expected bar baz to end in the cascade receiver")
+	}
+}
+
+// TestJSONOptions tests leaving out ranges and comments, and indenting
+func TestJSONOptions(t *testing.T) {
+	node, err := parser.NewParser("x \"comment\"", nil, vm.NewVM()).ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing: %v", err)
+	}
+
+	tests := []struct {
+		options  ast.JSONOptions
+		expected string
+	}{
+		{ast.JSONOptions{OmitRanges: true, OmitComments: true}, `{"type":"VariableNode","name":"x"}`},
+		{ast.JSONOptions{OmitRanges: true}, `{"type":"VariableNode","name":"x","comments":[{"text":"comment"}]}`},
+		{ast.JSONOptions{OmitRanges: true, OmitComments: true, Indent: "  "}, "{\n  \"type\": \"VariableNode\",\n  \"name\": \"x\"\n}"},
+	}
+
+	for _, test := range tests {
+		encoded, err := ast.EncodeJSON(node, test.options)
+		if err != nil {
+			t.Fatalf("Error encoding: %v", err)
+		}
+		if string(encoded) != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, encoded)
+		}
+	}
+
+	encoded, err := ast.EncodeJSON(node, ast.JSONOptions{OmitComments: true})
+	if err != nil {
+		t.Fatalf("Error encoding: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatalf("Error unmarshalling %s: %v", encoded, err)
+	}
+	expectedRange := map[string]interface{}{
+		"start": map[string]interface{}{"offset": 0.0, "line": 1.0, "column": 1.0},
+		"end":   map[string]interface{}{"offset": 1.0, "line": 1.0, "column": 2.0},
+	}
+	if !reflect.DeepEqual(fields["range"], expectedRange) {
+		t.Errorf("Expected range %v, got %v", expectedRange, fields["range"])
+	}
+}
+
+// TestJSONFloat tests that floats are written in their shortest form
+func TestJSONFloat(t *testing.T) {
+	node, err := parser.NewParser("3.14", nil, vm.NewVM()).ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing: %v", err)
+	}
+
+	encoded, err := ast.EncodeJSON(node, ast.JSONOptions{OmitRanges: true})
+	if err != nil {
+		t.Fatalf("Error encoding: %v", err)
+	}
+	expected := `{"type":"LiteralNode","value":{"type":"Float","value":3.14}}`
+	if string(encoded) != expected {
+		t.Errorf("Expected %s, got %s", expected, encoded)
+	}
+}
+
+// TestJSONDecodeErrors tests JSON that is not the form of an AST
+func TestJSONDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"type":"FooNode"}`, `unknown node type "FooNode"`},
+		{`{"type":"MessageSendNode","selector":"foo","arguments":[]}`, "message foo without a receiver"},
+		{`{"type":"MethodNode","selector":"foo","pragmas":[{"type":"SelfNode"}]}`, "a pragma can't be a *ast.SelfNode"},
+		{`{"type":"LiteralNode","value":{"type":"Character","value":"ab"}}`, `invalid Character "ab"`},
+		{`{"type":"LiteralNode","value":{"type":"ByteArray","bytes":[256]}}`, "invalid byte 256"},
+		{`{"type":"LiteralNode","value":{"type":"Fraction"}}`, `unknown literal type "Fraction"`},
+		{`[1, 2]`, "cannot unmarshal array"},
+	}
+
+	for _, test := range tests {
+		_, err := ast.DecodeJSON([]byte(test.input), vm.NewVM())
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Decoding %s: expected error %q, got %v", test.input, test.expected, err)
+		}
+	}
+}
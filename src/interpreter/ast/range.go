@@ -0,0 +1,45 @@
+package ast
+
+import "fmt"
+
+// Position is a location in the source code
+type Position struct {
+	// Offset is the byte offset, starting at 0
+	Offset int
+
+	// Line is the line number, starting at 1
+	Line int
+
+	// Column is the byte column in the line, starting at 1
+	Column int
+}
+
+// String returns the position as line:column
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// SourceRange is the part of the source code a token or node was parsed from.
+// Start is the position of the first byte and End the position just after the last byte.
+type SourceRange struct {
+	// Start is the position of the first byte
+	Start Position
+
+	// End is the position just after the last byte
+	End Position
+}
+
+// String returns the range as line:column-line:column
+func (r SourceRange) String() string {
+	return fmt.Sprintf("%s-%s", r.Start, r.End)
+}
+
+// Len returns the length of the range in bytes
+func (r SourceRange) Len() int {
+	return r.End.Offset - r.Start.Offset
+}
+
+// Contains returns true if the offset lies within the range
+func (r SourceRange) Contains(offset int) bool {
+	return offset >= r.Start.Offset && offset < r.End.Offset
+}
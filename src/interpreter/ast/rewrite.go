@@ -0,0 +1,321 @@
+package ast
+
+import (
+	"fmt"
+)
+
+// Rewrite returns a copy of an AST in which nodes are replaced by the result
+// of f. f is called for every node after its children have been rewritten,
+// with the node itself or, if a child was replaced, a copy of it holding the
+// new children. It returns the node to put in its place, or the node it got
+// to keep it. Returning nil removes a statement, an element of a
+// dynamic array or a pragma. The original AST is never modified, nodes that
+// don't change are shared with it.
+//
+// An error is returned if a node is replaced by one that can't take its
+// place, such as a pragma by a literal, a removed receiver or argument, or a
+// cascade message that doesn't end in the receiver of the cascade.
+func Rewrite(root Node, f func(Node) Node) (Node, error) {
+	if root == nil {
+		return nil, nil
+	}
+
+	r := &rewriter{f: f}
+	return r.rewrite(root)
+}
+
+// rewriter rewrites the nodes of an AST with a function
+type rewriter struct {
+	f func(Node) Node
+}
+
+// rewrite rewrites the children of a node and then the node itself
+func (r *rewriter) rewrite(node Node) (Node, error) {
+	switch n := node.(type) {
+	case *MethodNode:
+		pragmas, changed, err := r.rewritePragmas(n.Pragmas)
+		if err != nil {
+			return nil, err
+		}
+		body, err := r.rewriteRequired(n.Body, "method body")
+		if err != nil {
+			return nil, err
+		}
+		if changed || body != n.Body {
+			c := *n
+			c.Pragmas = pragmas
+			c.Body = body
+			node = &c
+		}
+
+	case *ReturnNode:
+		expression, err := r.rewriteRequired(n.Expression, "returned expression")
+		if err != nil {
+			return nil, err
+		}
+		if expression != n.Expression {
+			c := *n
+			c.Expression = expression
+			node = &c
+		}
+
+	case *AssignmentNode:
+		expression, err := r.rewriteRequired(n.Expression, "assigned expression")
+		if err != nil {
+			return nil, err
+		}
+		if expression != n.Expression {
+			c := *n
+			c.Expression = expression
+			node = &c
+		}
+
+	case *MessageSendNode:
+		receiver, err := r.rewriteRequired(n.Receiver, "message receiver")
+		if err != nil {
+			return nil, err
+		}
+		arguments, changed, err := r.rewriteList(n.Arguments, "message argument", false)
+		if err != nil {
+			return nil, err
+		}
+		if changed || receiver != n.Receiver {
+			c := *n
+			c.Receiver = receiver
+			c.Arguments = arguments
+			node = &c
+		}
+
+	case *BlockNode:
+		body, err := r.rewriteRequired(n.Body, "block body")
+		if err != nil {
+			return nil, err
+		}
+		if body != n.Body {
+			c := *n
+			c.Body = body
+			node = &c
+		}
+
+	case *CascadeNode:
+		cascade, err := r.rewriteCascade(n)
+		if err != nil {
+			return nil, err
+		}
+		node = cascade
+
+	case *SequenceNode:
+		statements, changed, err := r.rewriteList(n.Statements, "statement", true)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			c := *n
+			c.Statements = statements
+			node = &c
+		}
+
+	case *DynamicArrayNode:
+		elements, changed, err := r.rewriteList(n.Elements, "array element", true)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			c := *n
+			c.Elements = elements
+			node = &c
+		}
+
+	case *PragmaNode:
+		arguments, changed, err := r.rewriteList(n.Arguments, "pragma argument", false)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			c := *n
+			c.Arguments = arguments
+			node = &c
+		}
+	}
+
+	return r.f(node), nil
+}
+
+// rewriteRequired rewrites a child that can't be removed. A missing child
+// stays missing.
+func (r *rewriter) rewriteRequired(node Node, what string) (Node, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	result, err := r.rewrite(node)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("a %s can't be removed", what)
+	}
+	return result, nil
+}
+
+// rewriteList rewrites a list of children, dropping the removed ones if
+// removable is set. It returns the original list if no child changed.
+func (r *rewriter) rewriteList(nodes []Node, what string, removable bool) ([]Node, bool, error) {
+	result := make([]Node, 0, len(nodes))
+	changed := false
+
+	for _, node := range nodes {
+		if node == nil {
+			result = append(result, nil)
+			continue
+		}
+
+		rewritten, err := r.rewrite(node)
+		if err != nil {
+			return nil, false, err
+		}
+		if rewritten == nil && !removable {
+			return nil, false, fmt.Errorf("a %s can't be removed", what)
+		}
+		if rewritten != node {
+			changed = true
+		}
+		if rewritten != nil {
+			result = append(result, rewritten)
+		}
+	}
+
+	if !changed {
+		return nodes, false, nil
+	}
+	return result, true, nil
+}
+
+// rewritePragmas rewrites the pragmas of a method, which can only be
+// replaced by other pragmas
+func (r *rewriter) rewritePragmas(pragmas []*PragmaNode) ([]*PragmaNode, bool, error) {
+	result := make([]*PragmaNode, 0, len(pragmas))
+	changed := false
+
+	for _, pragma := range pragmas {
+		if pragma == nil {
+			result = append(result, nil)
+			continue
+		}
+
+		rewritten, err := r.rewrite(pragma)
+		if err != nil {
+			return nil, false, err
+		}
+		if rewritten == nil {
+			changed = true
+			continue
+		}
+
+		replacement, ok := rewritten.(*PragmaNode)
+		if !ok {
+			return nil, false, fmt.Errorf("a pragma can't be replaced by a %T", rewritten)
+		}
+		if replacement != pragma {
+			changed = true
+		}
+		result = append(result, replacement)
+	}
+
+	if !changed {
+		return pragmas, false, nil
+	}
+	return result, true, nil
+}
+
+// rewriteCascade rewrites a cascade. The receiver is rewritten once, and the
+// message parts are rewritten so that their receiver chains end in the new
+// receiver.
+func (r *rewriter) rewriteCascade(n *CascadeNode) (Node, error) {
+	receiver, err := r.rewriteRequired(n.Receiver, "cascade receiver")
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*MessageSendNode, len(n.Messages))
+	changed := receiver != n.Receiver
+	for i, message := range n.Messages {
+		if message == nil {
+			continue
+		}
+
+		messages[i], err = r.rewriteCascadePart(message, n.Receiver, receiver)
+		if err != nil {
+			return nil, err
+		}
+		if messages[i] != message {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return n, nil
+	}
+
+	c := *n
+	c.Receiver = receiver
+	c.Messages = messages
+	return &c, nil
+}
+
+// rewriteCascadePart rewrites a message part of a cascade, replacing the old
+// receiver of the cascade at the end of its receiver chain by the new one
+func (r *rewriter) rewriteCascadePart(message *MessageSendNode, oldReceiver Node, newReceiver Node) (*MessageSendNode, error) {
+	receiver := newReceiver
+	if message.Receiver != oldReceiver {
+		inner, ok := message.Receiver.(*MessageSendNode)
+		if !ok {
+			return nil, fmt.Errorf("invalid cascade message receiver: %T", message.Receiver)
+		}
+
+		var err error
+		receiver, err = r.rewriteCascadePart(inner, oldReceiver, newReceiver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	arguments, changed, err := r.rewriteList(message.Arguments, "message argument", false)
+	if err != nil {
+		return nil, err
+	}
+
+	node := Node(message)
+	if changed || receiver != message.Receiver {
+		c := *message
+		c.Receiver = receiver
+		c.Arguments = arguments
+		node = &c
+	}
+
+	rewritten := r.f(node)
+	replacement, ok := rewritten.(*MessageSendNode)
+	if !ok {
+		return nil, fmt.Errorf("a cascade message can't be replaced by a %T", rewritten)
+	}
+	if !endsIn(replacement, newReceiver) {
+		return nil, fmt.Errorf("a cascade message must be sent to the receiver of the cascade")
+	}
+
+	return replacement, nil
+}
+
+// endsIn returns true if the receiver chain of a message ends in the receiver
+func endsIn(message *MessageSendNode, receiver Node) bool {
+	for {
+		if message.Receiver == receiver {
+			return true
+		}
+
+		inner, ok := message.Receiver.(*MessageSendNode)
+		if !ok {
+			return false
+		}
+		message = inner
+	}
+}
@@ -0,0 +1,160 @@
+package ast
+
+// WalkVisitor is called by Walk for every node of an AST. If Visit returns
+// a non-nil visitor, Walk visits the children of the node with it and then
+// calls its Visit with nil.
+type WalkVisitor interface {
+	Visit(node Node) (w WalkVisitor)
+}
+
+// Children returns the child nodes of a node in source order, skipping
+// missing ones. The message parts of a cascade end in the receiver of the
+// cascade, so the receiver is a child of the cascade and of its innermost
+// message part.
+func Children(node Node) []Node {
+	var result []Node
+	add := func(nodes ...Node) {
+		for _, child := range nodes {
+			if child != nil {
+				result = append(result, child)
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *MethodNode:
+		for _, pragma := range n.Pragmas {
+			if pragma != nil {
+				add(pragma)
+			}
+		}
+		add(n.Body)
+	case *ReturnNode:
+		add(n.Expression)
+	case *AssignmentNode:
+		add(n.Expression)
+	case *MessageSendNode:
+		add(n.Receiver)
+		add(n.Arguments...)
+	case *BlockNode:
+		add(n.Body)
+	case *CascadeNode:
+		add(n.Receiver)
+		for _, message := range n.Messages {
+			if message != nil {
+				add(message)
+			}
+		}
+	case *SequenceNode:
+		add(n.Statements...)
+	case *DynamicArrayNode:
+		add(n.Elements...)
+	case *PragmaNode:
+		add(n.Arguments...)
+	}
+
+	return result
+}
+
+// Walk traverses an AST in depth-first order: it calls v.Visit(node), and if
+// that returns a visitor w, walks each child of the node with w, followed by
+// a call of w.Visit(nil). The receiver shared by the messages of a cascade is
+// walked once, as a child of the cascade.
+func Walk(v WalkVisitor, node Node) {
+	walk(v, node, nil)
+}
+
+// walk walks a node that is a message part of the cascade with the given
+// receiver, or any other node if the receiver is nil
+func walk(v WalkVisitor, node Node, cascadeReceiver Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *CascadeNode:
+		if n.Receiver != nil {
+			walk(v, n.Receiver, nil)
+		}
+		for _, message := range n.Messages {
+			if message != nil {
+				walk(v, message, n.Receiver)
+			}
+		}
+	case *MessageSendNode:
+		// The receiver of a message part is the previous part or the shared receiver
+		if n.Receiver != nil && n.Receiver != cascadeReceiver {
+			walk(v, n.Receiver, cascadeReceiver)
+		}
+		for _, argument := range n.Arguments {
+			if argument != nil {
+				walk(v, argument, nil)
+			}
+		}
+	default:
+		for _, child := range Children(node) {
+			walk(v, child, nil)
+		}
+	}
+
+	v.Visit(nil)
+}
+
+// inspector is the WalkVisitor of Inspect
+type inspector func(Node) bool
+
+// Visit implements WalkVisitor
+func (f inspector) Visit(node Node) WalkVisitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: it calls f(node), and if
+// that returns true, inspects each child of the node, followed by a call of
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Parents holds the parent of every node of an AST. Nodes don't link to
+// their parents themselves, tools that need to go up the tree build the links
+// with NewParents.
+type Parents map[Node]Node
+
+// NewParents returns the parents of the nodes of the AST with the given root
+func NewParents(root Node) Parents {
+	parents := Parents{}
+	var stack []Node
+
+	Inspect(root, func(node Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+
+		if len(stack) > 0 {
+			parents[node] = stack[len(stack)-1]
+		}
+		stack = append(stack, node)
+		return true
+	})
+
+	return parents
+}
+
+// Parent returns the parent of a node, or nil for the root and nodes that
+// are not part of the AST
+func (p Parents) Parent(node Node) Node {
+	return p[node]
+}
+
+// Ancestors returns the parent of a node, its parent and so on up to the root
+func (p Parents) Ancestors(node Node) []Node {
+	var ancestors []Node
+	for parent := p[node]; parent != nil; parent = p[parent] {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
@@ -0,0 +1,331 @@
+package ast_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/parser"
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// parseMethod parses the source code of a method
+func parseMethod(t *testing.T, source string) ast.Node {
+	t.Helper()
+
+	node, err := parser.NewParser(source, nil, vm.NewVM()).Parse()
+	if err != nil {
+		t.Fatalf("Error parsing %q: %v", source, err)
+	}
+	return node
+}
+
+// describe returns a short description of a node for comparing traversals
+func describe(node ast.Node) string {
+	switch n := node.(type) {
+	case nil:
+		return "end"
+	case *ast.MethodNode:
+		return "method " + n.Selector
+	case *ast.MessageSendNode:
+		return "send " + n.Selector
+	case *ast.VariableNode:
+		return "variable " + n.Name
+	case *ast.GlobalNode:
+		return "global " + n.Name
+	case *ast.LiteralNode:
+		return "literal " + n.Value.String()
+	case *ast.PragmaNode:
+		return "pragma " + n.Selector
+	}
+	return fmt.Sprintf("%T", node)[len("*ast."):]
+}
+
+// TestInspect tests the order nodes are visited in
+func TestInspect(t *testing.T) {
+	node := parseMethod(t, "foo: x <primitive: 1> ^Transcript show: x; cr")
+
+	var visited []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			visited = append(visited, describe(n))
+		}
+		return true
+	})
+
+	expected := []string{
+		"method foo:",
+		"pragma primitive:",
+		"literal 1",
+		"SequenceNode",
+		"ReturnNode",
+		"CascadeNode",
+		"global Transcript",
+		"send show:",
+		"variable x",
+		"send cr",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected %v, got %v", expected, visited)
+	}
+}
+
+// TestInspectSkipsChildren tests that returning false skips the children of a node
+func TestInspectSkipsChildren(t *testing.T) {
+	node := parseMethod(t, "foo ^[:a | a + 1] value: 2")
+
+	var visited []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			visited = append(visited, describe(n))
+		}
+		_, isBlock := n.(*ast.BlockNode)
+		return !isBlock
+	})
+
+	expected := []string{"method foo", "SequenceNode", "ReturnNode", "send value:", "BlockNode", "literal 2"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected %v, got %v", expected, visited)
+	}
+}
+
+// countingVisitor counts the nodes it visits and the calls with nil
+type countingVisitor struct {
+	nodes int
+	ends  int
+}
+
+// Visit implements ast.WalkVisitor
+func (v *countingVisitor) Visit(node ast.Node) ast.WalkVisitor {
+	if node == nil {
+		v.ends++
+	} else {
+		v.nodes++
+	}
+	return v
+}
+
+// TestWalk tests that every visited node is followed by a call with nil
+func TestWalk(t *testing.T) {
+	node := parseMethod(t, "foo | a | a := {1. self bar}. ^a")
+
+	v := &countingVisitor{}
+	ast.Walk(v, node)
+
+	if v.nodes != 9 {
+		t.Errorf("Expected 9 nodes, got %d", v.nodes)
+	}
+	if v.ends != v.nodes {
+		t.Errorf("Expected %d calls with nil, got %d", v.nodes, v.ends)
+	}
+}
+
+// TestParents tests the parent links of the nodes of an AST
+func TestParents(t *testing.T) {
+	node := parseMethod(t, "foo ^Transcript show: 1 + 2; cr")
+	parents := ast.NewParents(node)
+
+	var two ast.Node
+	var cascade *ast.CascadeNode
+	ast.Inspect(node, func(n ast.Node) bool {
+		if literal, ok := n.(*ast.LiteralNode); ok && literal.Value == pile.MakeIntegerImmediate(2) {
+			two = literal
+		}
+		if c, ok := n.(*ast.CascadeNode); ok {
+			cascade = c
+		}
+		return true
+	})
+	if two == nil || cascade == nil {
+		t.Fatalf("Expected the literal 2 and a cascade in %v", node)
+	}
+
+	var ancestors []string
+	for _, ancestor := range parents.Ancestors(two) {
+		ancestors = append(ancestors, describe(ancestor))
+	}
+	expected := []string{"send +", "send show:", "CascadeNode", "ReturnNode", "SequenceNode", "method foo"}
+	if !reflect.DeepEqual(ancestors, expected) {
+		t.Errorf("Expected ancestors %v, got %v", expected, ancestors)
+	}
+
+	if parents.Parent(cascade.Receiver) != cascade {
+		t.Errorf("Expected the cascade to be the parent of its receiver")
+	}
+	if parents.Parent(node) != nil {
+		t.Errorf("Expected the root to have no parent")
+	}
+}
+
+// TestRewrite tests replacing nodes and that the original AST is unchanged
+func TestRewrite(t *testing.T) {
+	node := parseMethod(t, "foo | a | a := x + 1. ^a * x")
+	method := node.(*ast.MethodNode)
+	body := method.Body.(*ast.SequenceNode)
+	assignment := body.Statements[0]
+
+	rewritten, err := ast.Rewrite(node, func(n ast.Node) ast.Node {
+		if variable, ok := n.(*ast.VariableNode); ok && variable.Name == "x" {
+			return &ast.VariableNode{Name: "y", Source: variable.Source}
+		}
+		return n
+	})
+	if err != nil {
+		t.Fatalf("Error rewriting: %v", err)
+	}
+
+	var names []string
+	ast.Inspect(rewritten, func(n ast.Node) bool {
+		if variable, ok := n.(*ast.VariableNode); ok {
+			names = append(names, variable.Name)
+		}
+		return true
+	})
+	if !reflect.DeepEqual(names, []string{"y", "a", "y"}) {
+		t.Errorf("Expected variables [y a y], got %v", names)
+	}
+
+	if method.Body != body || body.Statements[0] != assignment {
+		t.Errorf("Expected the original AST to be unchanged")
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if variable, ok := n.(*ast.VariableNode); ok && variable.Name == "y" {
+			t.Errorf("Expected the original AST to keep x")
+		}
+		return true
+	})
+
+	unchanged, err := ast.Rewrite(node, func(n ast.Node) ast.Node { return n })
+	if err != nil || unchanged != node {
+		t.Errorf("Expected the identity rewrite to return the same AST, got %v, %v", unchanged, err)
+	}
+}
+
+// TestRewriteRemove tests removing statements and pragmas
+func TestRewriteRemove(t *testing.T) {
+	node := parseMethod(t, "foo <bar> self a. self b. ^self c")
+
+	rewritten, err := ast.Rewrite(node, func(n ast.Node) ast.Node {
+		if _, ok := n.(*ast.PragmaNode); ok {
+			return nil
+		}
+		if send, ok := n.(*ast.MessageSendNode); ok && send.Selector == "b" {
+			return nil
+		}
+		return n
+	})
+	if err != nil {
+		t.Fatalf("Error rewriting: %v", err)
+	}
+
+	method := rewritten.(*ast.MethodNode)
+	if len(method.Pragmas) != 0 {
+		t.Errorf("Expected no pragmas, got %d", len(method.Pragmas))
+	}
+	if statements := method.Body.(*ast.SequenceNode).Statements; len(statements) != 2 {
+		t.Errorf("Expected 2 statements, got %d", len(statements))
+	}
+}
+
+// TestRewriteErrors tests replacements that can't take the place of a node
+func TestRewriteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		replace  func(ast.Node) ast.Node
+		expected string
+	}{
+		{
+			name:   "pragma replaced by a literal",
+			source: "foo <bar> ^1",
+			replace: func(n ast.Node) ast.Node {
+				if _, ok := n.(*ast.PragmaNode); ok {
+					return &ast.LiteralNode{Value: pile.MakeIntegerImmediate(1)}
+				}
+				return n
+			},
+			expected: "a pragma can't be replaced by a *ast.LiteralNode",
+		},
+		{
+			name:   "removed argument",
+			source: "foo ^self bar: 1",
+			replace: func(n ast.Node) ast.Node {
+				if _, ok := n.(*ast.LiteralNode); ok {
+					return nil
+				}
+				return n
+			},
+			expected: "a message argument can't be removed",
+		},
+		{
+			name:   "cascade message replaced by a variable",
+			source: "foo ^self bar; baz",
+			replace: func(n ast.Node) ast.Node {
+				if send, ok := n.(*ast.MessageSendNode); ok && send.Selector == "baz" {
+					return &ast.VariableNode{Name: "x"}
+				}
+				return n
+			},
+			expected: "a cascade message can't be replaced by a *ast.VariableNode",
+		},
+		{
+			name:   "cascade message sent to another receiver",
+			source: "foo ^self bar; baz",
+			replace: func(n ast.Node) ast.Node {
+				if send, ok := n.(*ast.MessageSendNode); ok && send.Selector == "baz" {
+					return &ast.MessageSendNode{Receiver: &ast.SuperNode{}, Selector: "baz"}
+				}
+				return n
+			},
+			expected: "a cascade message must be sent to the receiver of the cascade",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := parseMethod(t, test.source)
+			_, err := ast.Rewrite(node, test.replace)
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("Expected error %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+// TestRewriteCascadeReceiver tests that the messages of a cascade share the
+// rewritten receiver
+func TestRewriteCascadeReceiver(t *testing.T) {
+	node := parseMethod(t, "foo ^x add: 1; add: 2; yourself")
+
+	rewritten, err := ast.Rewrite(node, func(n ast.Node) ast.Node {
+		if variable, ok := n.(*ast.VariableNode); ok && variable.Name == "x" {
+			return &ast.GlobalNode{Name: "Set"}
+		}
+		return n
+	})
+	if err != nil {
+		t.Fatalf("Error rewriting: %v", err)
+	}
+
+	var cascade *ast.CascadeNode
+	ast.Inspect(rewritten, func(n ast.Node) bool {
+		if c, ok := n.(*ast.CascadeNode); ok {
+			cascade = c
+		}
+		return true
+	})
+	if cascade == nil {
+		t.Fatalf("Expected a cascade")
+	}
+
+	if _, ok := cascade.Receiver.(*ast.GlobalNode); !ok {
+		t.Fatalf("Expected the receiver to be replaced, got %T", cascade.Receiver)
+	}
+	for _, message := range cascade.Messages {
+		if message.Receiver != cascade.Receiver {
+			t.Errorf("Expected %s to be sent to the shared receiver", message.Selector)
+		}
+	}
+}
@@ -16,24 +16,35 @@ const (
 	JUMP_IF_FALSE            byte = 10 // Jump if top of stack is false (followed by 4-byte target)
 	POP                      byte = 11 // Pop the top value from the stack
 	DUPLICATE                byte = 12 // Duplicate the top value on the stack
-	CREATE_BLOCK             byte = 13 // Create a block (followed by 4-byte bytecode size, 4-byte literal count, 4-byte temp var count)
+	CREATE_BLOCK             byte = 13 // Create a block closure from a compiled block literal (followed by 4-byte literal index)
 	EXECUTE_BLOCK            byte = 14 // Execute a block (followed by 4-byte arg count)
+	CREATE_ARRAY             byte = 15 // Create an array from the top stack values (followed by 4-byte element count)
+	PUSH_GLOBAL              byte = 16 // Push the value of a global binding from the literals array (followed by 4-byte index)
+
+	PUSH_OUTER_TEMPORARY_VARIABLE  byte = 17 // Push a temporary variable of an enclosing context (followed by 4-byte depth and 4-byte index)
+	STORE_OUTER_TEMPORARY_VARIABLE byte = 18 // Store a value into a temporary variable of an enclosing context (followed by 4-byte depth and 4-byte index)
+	BLOCK_RETURN                   byte = 19 // Return the top of the stack from the home method of the block
+	STORE_GLOBAL                   byte = 20 // Store a value into a global binding from the literals array (followed by 4-byte index)
 )
 
 // InstructionSize returns the size of the instruction in bytes (including the opcode)
 func InstructionSize(bytecode byte) int {
 	switch bytecode {
-	case PUSH_LITERAL, PUSH_INSTANCE_VARIABLE, PUSH_TEMPORARY_VARIABLE,
-		STORE_INSTANCE_VARIABLE, STORE_TEMPORARY_VARIABLE,
+	case PUSH_LITERAL, PUSH_INSTANCE_VARIABLE, PUSH_TEMPORARY_VARIABLE, PUSH_GLOBAL,
+		STORE_INSTANCE_VARIABLE, STORE_TEMPORARY_VARIABLE, STORE_GLOBAL,
 		JUMP, JUMP_IF_TRUE, JUMP_IF_FALSE:
 		return 5 // 1 byte opcode + 4 byte operand
 	case SEND_MESSAGE:
 		return 9 // 1 byte opcode + 4 byte selector index + 4 byte arg count
+	case PUSH_OUTER_TEMPORARY_VARIABLE, STORE_OUTER_TEMPORARY_VARIABLE:
+		return 9 // 1 byte opcode + 4 byte depth + 4 byte index
 	case CREATE_BLOCK:
-		return 13 // 1 byte opcode + 4 byte bytecode size + 4 byte literal count + 4 byte temp var count
+		return 5 // 1 byte opcode + 4 byte literal index
 	case EXECUTE_BLOCK:
 		return 5 // 1 byte opcode + 4 byte arg count
-	case PUSH_SELF, RETURN_STACK_TOP, POP, DUPLICATE:
+	case CREATE_ARRAY:
+		return 5 // 1 byte opcode + 4 byte element count
+	case PUSH_SELF, RETURN_STACK_TOP, BLOCK_RETURN, POP, DUPLICATE:
 		return 1 // 1 byte opcode
 	default:
 		return 1 // Default to 1 byte for unknown bytecodes
@@ -73,6 +84,18 @@ func BytecodeName(bytecode byte) string {
 		return "CREATE_BLOCK"
 	case EXECUTE_BLOCK:
 		return "EXECUTE_BLOCK"
+	case CREATE_ARRAY:
+		return "CREATE_ARRAY"
+	case PUSH_GLOBAL:
+		return "PUSH_GLOBAL"
+	case PUSH_OUTER_TEMPORARY_VARIABLE:
+		return "PUSH_OUTER_TEMPORARY_VARIABLE"
+	case STORE_OUTER_TEMPORARY_VARIABLE:
+		return "STORE_OUTER_TEMPORARY_VARIABLE"
+	case BLOCK_RETURN:
+		return "BLOCK_RETURN"
+	case STORE_GLOBAL:
+		return "STORE_GLOBAL"
 	default:
 		return "UNKNOWN"
 	}
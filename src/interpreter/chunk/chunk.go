@@ -0,0 +1,146 @@
+package chunk
+
+import (
+	"strings"
+
+	"smalltalklsp/interpreter/definition"
+)
+
+// Chunk is a piece of a chunk file terminated by a ! separator
+type Chunk struct {
+	// Text is the text of the chunk with !! unescaped to ! and
+	// surrounding whitespace removed
+	Text string
+
+	// Location is the location of the first non-whitespace character of the chunk
+	Location definition.Location
+
+	// Terminated is false for text after the last ! of the file
+	Terminated bool
+
+	// Start and End are the byte offsets of the text in the source, where
+	// an escaped ! still takes two bytes
+	Start int
+	End   int
+}
+
+// IsEmpty returns true if the chunk contains only whitespace. An empty chunk
+// ends a list of methods, so "! !" closes a methodsFor: section.
+func (c Chunk) IsEmpty() bool {
+	return c.Text == ""
+}
+
+// Split splits the source of a chunk file into chunks. Every ! ends a chunk,
+// except for !! which stands for a single ! in the chunk text. Trailing text
+// without a final ! becomes an unterminated chunk. A single ! inside a string,
+// a comment or a character literal such as $! is kept in the chunk text, so
+// files that don't escape those are still split at the right places.
+func Split(file string, source string) []Chunk {
+	var chunks []Chunk
+	var text strings.Builder
+
+	line := 1
+	startLine := 0
+
+	// Offsets of the first and just after the last non-whitespace character
+	start, end := -1, -1
+
+	// quote is the quote of the string or comment being scanned, or 0
+	var quote byte
+
+	for i := 0; i < len(source); i++ {
+		c := source[i]
+
+		// Remember the line of the first non-whitespace character
+		if startLine == 0 && !isWhitespace(c) {
+			startLine = line
+			start = i
+		}
+
+		if c == '!' {
+			// A doubled ! is an escaped ! inside the chunk
+			if i+1 < len(source) && source[i+1] == '!' {
+				text.WriteByte('!')
+				i++
+				end = i + 1
+				continue
+			}
+
+			// A single ! ends the chunk unless it is quoted
+			if quote == 0 {
+				chunks = append(chunks, newChunk(file, text.String(), startLine, line, true, start, end, i))
+				text.Reset()
+				startLine = 0
+				start, end = -1, -1
+				continue
+			}
+		}
+
+		if c == '\n' {
+			line++
+		}
+		text.WriteByte(c)
+
+		switch {
+		case quote != 0:
+			// A doubled quote ends and restarts the string, which needs no special case
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$' && i+1 < len(source):
+			// The character of a character literal is never a quote or separator,
+			// but $!! is still an escaped $!
+			i++
+			if source[i] == '!' && i+1 < len(source) && source[i+1] == '!' {
+				i++
+			}
+			if source[i] == '\n' {
+				line++
+			}
+			text.WriteByte(source[i])
+		}
+
+		if !isWhitespace(c) {
+			end = i + 1
+		}
+	}
+
+	// Keep any text after the last separator
+	if strings.TrimSpace(text.String()) != "" {
+		chunks = append(chunks, newChunk(file, text.String(), startLine, line, false, start, end, len(source)))
+	}
+
+	return chunks
+}
+
+// newChunk creates a chunk from its raw text, which runs from start to end in
+// the source. Empty chunks are located at the line and offset of the ! that
+// ends them.
+func newChunk(file string, text string, startLine int, endLine int, terminated bool, start int, end int, separator int) Chunk {
+	if startLine == 0 {
+		startLine = endLine
+	}
+	if end < 0 {
+		start, end = separator, separator
+	}
+
+	return Chunk{
+		Text:       strings.TrimSpace(text),
+		Location:   definition.Location{File: file, Line: startLine},
+		Terminated: terminated,
+		Start:      start,
+		End:        end,
+	}
+}
+
+// isWhitespace returns true if the character is whitespace
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// Escape doubles every ! in text so it can be written as a single chunk
+func Escape(text string) string {
+	return strings.ReplaceAll(text, "!", "!!")
+}
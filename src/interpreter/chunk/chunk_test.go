@@ -0,0 +1,283 @@
+package chunk
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSplit tests splitting a chunk file into chunks
+func TestSplit(t *testing.T) {
+	source := "first chunk!\n\n  second\n  chunk  !\n! !\ntrailing"
+
+	chunks := Split("test.st", source)
+
+	expected := []struct {
+		text       string
+		line       int
+		terminated bool
+	}{
+		{"first chunk", 1, true},
+		{"second\n  chunk", 3, true},
+		{"", 5, true},
+		{"", 5, true},
+		{"trailing", 6, false},
+	}
+
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d: %v", len(expected), len(chunks), chunks)
+	}
+
+	for i, e := range expected {
+		if chunks[i].Text != e.text {
+			t.Errorf("Chunk %d: expected text %q, got %q", i, e.text, chunks[i].Text)
+		}
+		if chunks[i].Location.Line != e.line {
+			t.Errorf("Chunk %d: expected line %d, got %d", i, e.line, chunks[i].Location.Line)
+		}
+		if chunks[i].Location.File != "test.st" {
+			t.Errorf("Chunk %d: expected file test.st, got %s", i, chunks[i].Location.File)
+		}
+		if chunks[i].Terminated != e.terminated {
+			t.Errorf("Chunk %d: expected terminated %v, got %v", i, e.terminated, chunks[i].Terminated)
+		}
+		if text := strings.TrimSpace(source[chunks[i].Start:chunks[i].End]); text != e.text {
+			t.Errorf("Chunk %d: expected offsets %d-%d to hold the text, got %q", i, chunks[i].Start, chunks[i].End, text)
+		}
+	}
+}
+
+// TestSplitEscapedBang tests that !! stands for a single ! inside a chunk
+func TestSplitEscapedBang(t *testing.T) {
+	chunks := Split("test.st", "shout\n  ^'hello!!'!")
+
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d: %v", len(chunks), chunks)
+	}
+	if chunks[0].Text != "shout\n  ^'hello!'" {
+		t.Errorf("Expected escaped ! to be unescaped, got %q", chunks[0].Text)
+	}
+
+	// A single ! in a string, comment or character literal doesn't end the chunk
+	chunks = Split("test.st", "shout \"Say it!\"\n  ^'hello!', $! asString, 'it''s'!next!")
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d: %v", len(chunks), chunks)
+	}
+	if chunks[0].Text != "shout \"Say it!\"\n  ^'hello!', $! asString, 'it''s'" {
+		t.Errorf("Expected quoted ! to be kept, got %q", chunks[0].Text)
+	}
+	if chunks[1].Text != "next" || chunks[1].Location.Line != 2 {
+		t.Errorf("Expected chunk next at line 2, got %q at line %d", chunks[1].Text, chunks[1].Location.Line)
+	}
+
+	// The offsets cover the escaped text
+	chunks = Split("test.st", "  a!!b  !")
+	if chunks[0].Start != 2 || chunks[0].End != 6 {
+		t.Errorf("Expected chunk at 2-6, got %d-%d", chunks[0].Start, chunks[0].End)
+	}
+
+	if Escape("^'hello!'") != "^'hello!!'" {
+		t.Errorf("Expected Escape to double the !, got %q", Escape("^'hello!'"))
+	}
+}
+
+// TestRead tests reading class and method definitions
+func TestRead(t *testing.T) {
+	source := `Object subclass: #Point
+    instanceVariableNames: 'x y'
+    classVariableNames: 'Origin'
+    package: 'Graphics'!
+
+Point comment: 'A point in the plane'!
+
+Point class instanceVariableNames: 'cache'!
+
+!Point class methodsFor: 'instance creation'!
+x: anX y: aY
+    ^self new setX: anX y: aY
+! !
+
+!Point methodsFor: 'accessing'!
+x
+    ^x
+!
+
++ aPoint
+    "Add two points, 'quoted!!'"
+    ^Point x: x + aPoint x y: y + aPoint y
+! !
+
+Transcript show: 'loaded'!
+`
+
+	definitions, err := Read("Point.st", source)
+	if err != nil {
+		t.Fatalf("Error reading chunks: %v", err)
+	}
+
+	// Check the class
+	if len(definitions.Classes) != 1 {
+		t.Fatalf("Expected 1 class, got %d", len(definitions.Classes))
+	}
+	class := definitions.Classes[0]
+	if class.Name != "Point" || class.Superclass != "Object" {
+		t.Errorf("Expected Point subclass of Object, got %s subclass of %s", class.Name, class.Superclass)
+	}
+	if strings.Join(class.InstanceVariableNames, " ") != "x y" {
+		t.Errorf("Expected instance variables x y, got %v", class.InstanceVariableNames)
+	}
+	if strings.Join(class.ClassVariableNames, " ") != "Origin" {
+		t.Errorf("Expected class variables Origin, got %v", class.ClassVariableNames)
+	}
+	if strings.Join(class.ClassInstanceVariableNames, " ") != "cache" {
+		t.Errorf("Expected class instance variables cache, got %v", class.ClassInstanceVariableNames)
+	}
+	if class.Package != "Graphics" {
+		t.Errorf("Expected package Graphics, got %s", class.Package)
+	}
+	if class.Comment != "A point in the plane" {
+		t.Errorf("Expected class comment, got %q", class.Comment)
+	}
+	if class.Location.String() != "Point.st:1" {
+		t.Errorf("Expected class location Point.st:1, got %s", class.Location)
+	}
+
+	// Check the methods
+	expected := []struct {
+		selector  string
+		classSide bool
+		category  string
+		line      int
+	}{
+		{"x:y:", true, "instance creation", 11},
+		{"x", false, "accessing", 16},
+		{"+", false, "accessing", 20},
+	}
+
+	if len(definitions.Methods) != len(expected) {
+		t.Fatalf("Expected %d methods, got %d", len(expected), len(definitions.Methods))
+	}
+	for i, e := range expected {
+		method := definitions.Methods[i]
+		if method.ClassName != "Point" {
+			t.Errorf("Method %d: expected class Point, got %s", i, method.ClassName)
+		}
+		if method.Selector != e.selector {
+			t.Errorf("Method %d: expected selector %s, got %s", i, e.selector, method.Selector)
+		}
+		if method.ClassSide != e.classSide {
+			t.Errorf("Method %d: expected class side %v, got %v", i, e.classSide, method.ClassSide)
+		}
+		if method.Category != e.category {
+			t.Errorf("Method %d: expected category %s, got %s", i, e.category, method.Category)
+		}
+		if method.Location.Line != e.line {
+			t.Errorf("Method %d: expected line %d, got %d", i, e.line, method.Location.Line)
+		}
+	}
+	if !strings.Contains(definitions.Methods[2].Source, "'quoted!'") {
+		t.Errorf("Expected !! to be unescaped in method source, got %q", definitions.Methods[2].Source)
+	}
+
+	// Check the expression
+	if len(definitions.Expressions) != 1 || definitions.Expressions[0].Source != "Transcript show: 'loaded'" {
+		t.Fatalf("Expected one expression, got %v", definitions.Expressions)
+	}
+	if definitions.Expressions[0].Location.Line != 25 {
+		t.Errorf("Expected expression at line 25, got %d", definitions.Expressions[0].Location.Line)
+	}
+}
+
+// TestMethodChunks tests finding the chunks that hold methods
+func TestMethodChunks(t *testing.T) {
+	source := "Object subclass: #Foo!\n!Foo methodsFor: 'a'!\nx\n  ^1!\ny ^2! !\nFoo new x!\n!Foo class methodsFor: 'b'!\nz ^3! !"
+
+	methods := MethodChunks(Split("test.st", source))
+
+	var texts []string
+	for _, method := range methods {
+		texts = append(texts, method.Text)
+	}
+	if strings.Join(texts, "|") != "x\n  ^1|y ^2|z ^3" {
+		t.Errorf("Expected the chunks of methods x, y and z, got %q", texts)
+	}
+}
+
+// TestReadErrors tests that errors point back to the file and line
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "invalid method pattern",
+			source:   "Object subclass: #Foo!\n\n!Foo methodsFor: 'broken'!\n\n  #foo\n  ^1\n! !",
+			expected: "Foo.st:5: expected identifier or special, got \"foo\"",
+		},
+		{
+			name:     "unknown class definition keyword",
+			source:   "\nObject subclass: #Foo colour: 'red'!",
+			expected: "Foo.st:2: unknown keyword colour: in definition of class Foo",
+		},
+		{
+			name:     "comment of undefined class",
+			source:   "Foo comment: 'missing'!",
+			expected: "Foo.st:1: class Foo is not defined",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Read("Foo.st", test.source)
+			if err == nil {
+				t.Fatalf("Expected error %q, got none", test.expected)
+			}
+			if err.Error() != test.expected {
+				t.Errorf("Expected error %q, got %q", test.expected, err.Error())
+			}
+		})
+	}
+}
+
+// TestReadSourceFiles tests reading the chunk files of the Smalltalk sources
+func TestReadSourceFiles(t *testing.T) {
+	files, err := filepath.Glob("../../*.st")
+	if err != nil {
+		t.Fatalf("Error listing source files: %v", err)
+	}
+	if len(files) == 0 {
+		t.Skip("No source files found")
+	}
+
+	for _, file := range files {
+		definitions, err := ReadFile(file)
+		if err != nil {
+			t.Errorf("Error reading %s: %v", file, err)
+			continue
+		}
+
+		// Every method must belong to a class defined in the same file
+		for _, method := range definitions.Methods {
+			if definitions.Class(method.ClassName) == nil {
+				t.Errorf("%s: method %s of undefined class %s", method.Location, method.Selector, method.ClassName)
+			}
+		}
+	}
+
+	// Spot check one file
+	definitions, err := ReadFile("../../Association.st")
+	if err != nil {
+		t.Fatalf("Error reading Association.st: %v", err)
+	}
+	if len(definitions.Classes) != 1 || definitions.Classes[0].Name != "Association" {
+		t.Fatalf("Expected class Association, got %v", definitions.Classes)
+	}
+	classMethods := definitions.MethodsOf("Association", true)
+	if len(classMethods) != 1 || classMethods[0].Selector != "key:value:" {
+		t.Errorf("Expected class method key:value:, got %v", classMethods)
+	}
+	if len(definitions.MethodsOf("Association", false)) != 8 {
+		t.Errorf("Expected 8 instance methods, got %d", len(definitions.MethodsOf("Association", false)))
+	}
+}
@@ -0,0 +1,311 @@
+package chunk
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"smalltalklsp/interpreter/definition"
+	"smalltalklsp/interpreter/parser"
+)
+
+// ReadFile reads the class, method and expression definitions of a chunk file
+func ReadFile(path string) (*definition.Definitions, error) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Read(path, string(source))
+}
+
+// Read reads the class, method and expression definitions of the source of
+// a chunk file. A chunk such as "Foo methodsFor: 'accessing'" starts a list of
+// methods that ends with an empty chunk, class definitions are messages such as
+// "Object subclass: #Foo instanceVariableNames: 'a b' ...", and any other chunk
+// is an expression to evaluate.
+func Read(file string, source string) (*definition.Definitions, error) {
+	reader := &reader{
+		chunks:      Split(file, source),
+		definitions: &definition.Definitions{},
+	}
+
+	err := reader.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.definitions, nil
+}
+
+// reader reads definitions from a list of chunks
+type reader struct {
+	// chunks are the chunks of the file
+	chunks []Chunk
+
+	// index is the index of the next chunk to read
+	index int
+
+	// definitions are the definitions read so far
+	definitions *definition.Definitions
+}
+
+// message is a keyword message sent to a class name, such as a class
+// definition or a methodsFor: declaration
+type message struct {
+	// receiver is the name of the receiving class
+	receiver string
+
+	// classSide is true if the message is sent to the metaclass, as in "Foo class"
+	classSide bool
+
+	// keywords are the keywords of the message
+	keywords []string
+
+	// arguments are the values of the symbol and string arguments
+	arguments []string
+}
+
+// selector returns the selector of the message
+func (m *message) selector() string {
+	return strings.Join(m.keywords, "")
+}
+
+// read reads all chunks
+func (r *reader) read() error {
+	for r.index < len(r.chunks) {
+		chunk := r.chunks[r.index]
+		r.index++
+
+		if chunk.IsEmpty() {
+			continue
+		}
+
+		err := r.readChunk(chunk)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readChunk reads a chunk outside of a list of methods
+func (r *reader) readChunk(chunk Chunk) error {
+	msg := parseMessage(chunk)
+
+	// Anything that isn't a message to a class is an expression
+	if msg == nil {
+		r.addExpression(chunk)
+		return nil
+	}
+
+	switch {
+	case msg.keywords[0] == "methodsFor:":
+		return r.readMethods(msg)
+	case isSubclassKeyword(msg.keywords[0]) && !msg.classSide:
+		return r.readClass(chunk, msg)
+	case msg.selector() == "instanceVariableNames:" && msg.classSide:
+		return r.readClassInstanceVariables(chunk, msg)
+	case msg.selector() == "comment:" && !msg.classSide:
+		return r.readComment(chunk, msg)
+	}
+
+	r.addExpression(chunk)
+	return nil
+}
+
+// addExpression adds a chunk as an expression to evaluate
+func (r *reader) addExpression(chunk Chunk) {
+	r.definitions.Expressions = append(r.definitions.Expressions, &definition.Expression{
+		Source:   chunk.Text,
+		Location: chunk.Location,
+	})
+}
+
+// readClass reads a class definition such as
+// "Object subclass: #Foo instanceVariableNames: 'a b' package: 'Bar'"
+func (r *reader) readClass(chunk Chunk, msg *message) error {
+	class := &definition.Class{
+		Name:       msg.arguments[0],
+		Superclass: msg.receiver,
+		Location:   chunk.Location,
+	}
+
+	// nil subclass: #Foo defines a root class
+	if class.Superclass == "nil" {
+		class.Superclass = ""
+	}
+
+	for i, keyword := range msg.keywords[1:] {
+		value := msg.arguments[i+1]
+
+		switch keyword {
+		case "instanceVariableNames:":
+			class.InstanceVariableNames = strings.Fields(value)
+		case "classVariableNames:":
+			class.ClassVariableNames = strings.Fields(value)
+		case "poolDictionaries:":
+			class.PoolDictionaries = strings.Fields(value)
+		case "package:", "category:":
+			class.Package = value
+		default:
+			return definition.Errorf(chunk.Location, "unknown keyword %s in definition of class %s", keyword, class.Name)
+		}
+	}
+
+	if r.definitions.Class(class.Name) != nil {
+		return definition.Errorf(chunk.Location, "class %s is defined twice", class.Name)
+	}
+
+	r.definitions.Classes = append(r.definitions.Classes, class)
+	return nil
+}
+
+// readClassInstanceVariables reads a chunk such as "Foo class instanceVariableNames: 'a b'"
+func (r *reader) readClassInstanceVariables(chunk Chunk, msg *message) error {
+	class := r.definitions.Class(msg.receiver)
+	if class == nil {
+		return definition.Errorf(chunk.Location, "class %s is not defined", msg.receiver)
+	}
+
+	class.ClassInstanceVariableNames = strings.Fields(msg.arguments[0])
+	return nil
+}
+
+// readComment reads a chunk such as "Foo comment: 'A comment'"
+func (r *reader) readComment(chunk Chunk, msg *message) error {
+	class := r.definitions.Class(msg.receiver)
+	if class == nil {
+		return definition.Errorf(chunk.Location, "class %s is not defined", msg.receiver)
+	}
+
+	class.Comment = msg.arguments[0]
+	return nil
+}
+
+// readMethods reads the methods following a methodsFor: chunk up to the next empty chunk
+func (r *reader) readMethods(msg *message) error {
+	for r.index < len(r.chunks) {
+		chunk := r.chunks[r.index]
+		r.index++
+
+		if chunk.IsEmpty() {
+			return nil
+		}
+
+		method, err := readMethod(chunk)
+		if err != nil {
+			return err
+		}
+
+		method.ClassName = msg.receiver
+		method.ClassSide = msg.classSide
+		method.Category = msg.arguments[0]
+		r.definitions.Methods = append(r.definitions.Methods, method)
+	}
+
+	return nil
+}
+
+// readMethod reads the definition of the method in a chunk
+func readMethod(chunk Chunk) (*definition.Method, error) {
+	p := parser.NewParser(chunk.Text, nil, nil)
+	selector, _, err := p.ParseMethodPattern()
+	if err != nil {
+		return nil, LocatedError(chunk, err)
+	}
+
+	return &definition.Method{
+		Selector: selector,
+		Source:   chunk.Text,
+		Location: chunk.Location,
+	}, nil
+}
+
+// MethodChunks returns the chunks that hold the source of a method, which are
+// the chunks after a methodsFor: chunk up to the next empty chunk
+func MethodChunks(chunks []Chunk) []Chunk {
+	var methods []Chunk
+	inMethods := false
+
+	for _, chunk := range chunks {
+		switch {
+		case chunk.IsEmpty():
+			inMethods = false
+		case inMethods:
+			methods = append(methods, chunk)
+		default:
+			msg := parseMessage(chunk)
+			inMethods = msg != nil && msg.keywords[0] == "methodsFor:"
+		}
+	}
+
+	return methods
+}
+
+// parseMessage parses a chunk of the form "Foo keyword: #symbol keyword: 'string' ..."
+// or "Foo class keyword: ...". It returns nil if the chunk has a different form.
+func parseMessage(chunk Chunk) *message {
+	p := parser.NewParser(chunk.Text, nil, nil)
+	tokens, err := p.Tokenize()
+	if err != nil {
+		// Leave errors in expressions to the compiler
+		return nil
+	}
+
+	if tokens[0].Type != parser.TOKEN_IDENTIFIER || isKeyword(tokens[0].Value) {
+		return nil
+	}
+
+	msg := &message{receiver: tokens[0].Value}
+	i := 1
+
+	if tokens[i].Type == parser.TOKEN_IDENTIFIER && tokens[i].Value == "class" {
+		msg.classSide = true
+		i++
+	}
+
+	for tokens[i].Type != parser.TOKEN_EOF {
+		keyword := tokens[i]
+		argument := tokens[i+1]
+		if keyword.Type != parser.TOKEN_IDENTIFIER || !isKeyword(keyword.Value) {
+			return nil
+		}
+		if argument.Type != parser.TOKEN_STRING && argument.Type != parser.TOKEN_SYMBOL {
+			return nil
+		}
+
+		msg.keywords = append(msg.keywords, keyword.Value)
+		msg.arguments = append(msg.arguments, argument.Value)
+		i += 2
+	}
+
+	if len(msg.keywords) == 0 {
+		return nil
+	}
+
+	return msg
+}
+
+// LocatedError converts a parse error in the text of a chunk into an error
+// located in the chunk file
+func LocatedError(chunk Chunk, err error) error {
+	var parseError *parser.ParseError
+	if errors.As(err, &parseError) {
+		return definition.Errorf(chunk.Location.Offset(parseError.Range.Start.Line), "%s", parseError.Message)
+	}
+
+	return definition.Errorf(chunk.Location, "%s", err)
+}
+
+// isKeyword returns true if the identifier is a keyword such as at:
+func isKeyword(identifier string) bool {
+	return strings.HasSuffix(identifier, ":")
+}
+
+// isSubclassKeyword returns true if the keyword defines a subclass, as
+// subclass:, variableSubclass: or variableByteSubclass: do
+func isSubclassKeyword(keyword string) bool {
+	return keyword == "subclass:" || (strings.HasPrefix(keyword, "variable") && strings.HasSuffix(keyword, "Subclass:"))
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -36,21 +35,6 @@ func parseCode(input string, methodMode bool) (ast.Node, error) {
 	}
 }
 
-// prettyJSON converts a JSON string to a formatted, indented JSON string
-func prettyJSON(input string) string {
-	var obj interface{}
-	if err := json.Unmarshal([]byte(input), &obj); err != nil {
-		return fmt.Sprintf("Error formatting JSON: %v\nOriginal JSON: %s", err, input)
-	}
-
-	pretty, err := json.MarshalIndent(obj, "", "  ")
-	if err != nil {
-		return fmt.Sprintf("Error indenting JSON: %v\nOriginal JSON: %s", err, input)
-	}
-
-	return string(pretty)
-}
-
 func main() {
 	// Check if we have the right number of arguments
 	if len(os.Args) < 2 {
@@ -66,6 +50,7 @@ func main() {
 		fmt.Println("  parser_tester \"^self factorial\"") 
 		fmt.Println("  parser_tester -f mycode.st")
 		fmt.Println("  parser_tester \"yourself ^self\" --method")
+		fmt.Println("  parser_tester \"1 + 2\" --ranges")
 		fmt.Println("\nNote: The parser is still under development and doesn't yet support:")
 		fmt.Println("  - Assignment expressions (x := 5)")
 		fmt.Println("  - Block literals [...]")
@@ -73,12 +58,15 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Determine if we're parsing a method or an expression
+	// Determine if we're parsing a method or an expression, and whether to show source ranges
 	methodMode := false
+	showRanges := false
 	for _, arg := range os.Args {
 		if arg == "--method" {
 			methodMode = true
-			break
+		}
+		if arg == "--ranges" {
+			showRanges = true
 		}
 	}
 
@@ -97,8 +85,9 @@ func main() {
 		code = string(fileContent)
 	} else {
 		code = strings.Join(os.Args[1:], " ")
-		// Remove --method flag if present
+		// Remove the flags if present
 		code = strings.ReplaceAll(code, " --method", "")
+		code = strings.ReplaceAll(code, " --ranges", "")
 	}
 
 	// Parse the code
@@ -115,10 +104,12 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Convert the AST to JSON
-	visitor := &JSONVisitor{}
-	jsonResult := node.Accept(visitor).(string)
+	// Convert the AST to indented JSON
+	jsonResult, err := ast.EncodeJSON(node, ast.JSONOptions{OmitRanges: !showRanges, Indent: "  "})
+	if err != nil {
+		fmt.Printf("Error converting the AST to JSON: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Print the pretty JSON
-	fmt.Println(prettyJSON(jsonResult))
+	fmt.Println(string(jsonResult))
 }
\ No newline at end of file
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"smalltalklsp/interpreter/formatter"
+	"smalltalklsp/interpreter/vm"
+)
+
+// formatFile formats the methods of a chunk file, rewriting it unless check
+// is set. It returns true if the file was not formatted.
+func formatFile(file string, f *formatter.Formatter, check bool) (bool, error) {
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+
+	formatted, err := f.FormatChunks(file, string(source))
+	if err != nil {
+		return false, err
+	}
+
+	if formatted == string(source) {
+		return false, nil
+	}
+	if check {
+		return true, nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return true, err
+	}
+	return true, ioutil.WriteFile(file, []byte(formatted), info.Mode())
+}
+
+func main() {
+	defaults := formatter.DefaultOptions()
+	check := flag.Bool("check", false, "list the files that need formatting instead of rewriting them")
+	tabs := flag.Bool("tabs", false, "indent with tabs")
+	indent := flag.Int("indent", len(defaults.Indent), "number of spaces of one indentation level")
+	width := flag.Int("width", defaults.LineWidth, "line width")
+	cascades := flag.Bool("cascades", defaults.CascadeOnSeparateLines, "put every message of a cascade on its own line")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: stfmt [flags] file.st ...")
+		fmt.Fprintln(os.Stderr, "\nFormats the methods of chunk files in place.")
+		fmt.Fprintln(os.Stderr, "With -check, lists the files that need formatting and exits with status 1.")
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	options := formatter.Options{
+		Indent:                 strings.Repeat(" ", *indent),
+		LineWidth:              *width,
+		CascadeOnSeparateLines: *cascades,
+	}
+	if *tabs {
+		options.Indent = "\t"
+	}
+
+	// All the files are parsed with the literals of the one VM
+	f := formatter.NewFormatter(options, vm.DefaultVM)
+
+	status := 0
+	for _, file := range flag.Args() {
+		changed, err := formatFile(file, f, *check)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		if changed && *check {
+			fmt.Println(file)
+			status = 1
+		}
+	}
+
+	os.Exit(status)
+}
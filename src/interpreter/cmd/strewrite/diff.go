@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around changes
+const context = 3
+
+// operation is a line of an edit script: kept, removed or added
+type operation struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns the changes between two versions of a file in the
+// unified diff format, or an empty string if they are the same
+func unifiedDiff(file string, before string, after string) string {
+	if before == after {
+		return ""
+	}
+
+	operations := diffLines(splitLines(before), splitLines(after))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", file, file)
+
+	// Every hunk spans the changes closer than twice the context to each other
+	for start := 0; start < len(operations); {
+		if operations[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		end := start
+		for i := start; i < len(operations) && i-end <= 2*context; i++ {
+			if operations[i].kind != ' ' {
+				end = i + 1
+			}
+		}
+
+		first := max(start-context, 0)
+		last := min(end+context, len(operations))
+		writeHunk(&out, operations, first, last)
+		start = last
+	}
+
+	return out.String()
+}
+
+// writeHunk writes the operations from first up to last as a hunk
+func writeHunk(out *strings.Builder, operations []operation, first int, last int) {
+	// Line numbers start after the lines of the operations before the hunk
+	beforeLine, afterLine := 1, 1
+	for _, op := range operations[:first] {
+		if op.kind != '+' {
+			beforeLine++
+		}
+		if op.kind != '-' {
+			afterLine++
+		}
+	}
+
+	beforeCount, afterCount := 0, 0
+	for _, op := range operations[first:last] {
+		if op.kind != '+' {
+			beforeCount++
+		}
+		if op.kind != '-' {
+			afterCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(beforeLine, beforeCount), hunkRange(afterLine, afterCount))
+	for _, op := range operations[first:last] {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		out.WriteByte('\n')
+	}
+}
+
+// hunkRange returns the start and length of a hunk in one of the files.
+// An empty range starts at the line before it.
+func hunkRange(line int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// splitLines splits a file into lines without their line ends
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script turning the lines a into the
+// lines b, using the Myers algorithm on the lines between the common start
+// and end
+func diffLines(a []string, b []string) []operation {
+	var prefix, suffix []operation
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, operation{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append([]operation{{' ', a[len(a)-1]}}, suffix...)
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	// Find the furthest reaching path for every number of edits d
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			x := v[offset+k-1] + 1
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the paths back from the end
+	var middle []operation
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		previous := k - 1
+		if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+			previous = k + 1
+		}
+		previousX := v[offset+previous]
+		previousY := previousX - previous
+
+		for x > previousX && y > previousY {
+			middle = append(middle, operation{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if d > 0 {
+			if x == previousX {
+				middle = append(middle, operation{'+', b[y-1]})
+			} else {
+				middle = append(middle, operation{'-', a[x-1]})
+			}
+		}
+		x, y = previousX, previousY
+	}
+
+	for i, j := 0, len(middle)-1; i < j; i, j = i+1, j-1 {
+		middle[i], middle[j] = middle[j], middle[i]
+	}
+
+	result := append(prefix, middle...)
+	return append(result, suffix...)
+}
+
+// max returns the larger of two integers
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// min returns the smaller of two integers
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
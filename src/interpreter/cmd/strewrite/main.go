@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"smalltalklsp/interpreter/formatter"
+	"smalltalklsp/interpreter/pattern"
+	"smalltalklsp/interpreter/vm"
+)
+
+// sourceFiles returns the chunk files given on the command line, looking
+// for .st files in the directories
+func sourceFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && filepath.Ext(file) == ".st" {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// rewriteFile applies the rules to a chunk file and prints the diff,
+// rewriting the file if write is set. It returns the number of replacements.
+func rewriteFile(file string, rules []*pattern.Rule, f *formatter.Formatter, write bool) (int, error) {
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten, count, err := pattern.RewriteChunks(file, string(source), rules, f)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	fmt.Print(unifiedDiff(file, string(source), rewritten))
+	if !write {
+		return count, nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return count, err
+	}
+	return count, ioutil.WriteFile(file, []byte(rewritten), info.Mode())
+}
+
+func main() {
+	rulesFile := flag.String("rules", "", "file with the rewrite rules")
+	write := flag.Bool("w", false, "write the rewritten files instead of only printing the diff")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: strewrite -rules rules.st [-w] file.st|directory ...")
+		fmt.Fprintln(os.Stderr, "\nApplies rewrite rules to the methods of chunk files and prints the diff.")
+		fmt.Fprintln(os.Stderr, "The rule file holds pairs of chunks: a search pattern and its replacement.")
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *rulesFile == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	source, err := ioutil.ReadFile(*rulesFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	rules, err := pattern.ParseRules(*rulesFile, string(source))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	files, err := sourceFiles(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// All the files are parsed with the literals of the one VM
+	f := formatter.NewFormatter(formatter.DefaultOptions(), vm.DefaultVM)
+
+	status := 0
+	total := 0
+	for _, file := range files {
+		count, err := rewriteFile(file, rules, f, *write)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+		total += count
+	}
+
+	fmt.Fprintf(os.Stderr, "%d replacements\n", total)
+	os.Exit(status)
+}
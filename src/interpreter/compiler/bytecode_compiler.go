@@ -25,6 +25,65 @@ type BytecodeCompiler struct {
 
 	// Class is the class the method belongs to
 	Class *pile.Object
+
+	// Globals provides the bindings of the globals the method refers to
+	Globals GlobalAccess
+
+	// outer is the compiler of the enclosing method or block when compiling a block
+	outer *BytecodeCompiler
+
+	// argCount is the number of temporary variables that are arguments
+	argCount int
+
+	// temporaries are the declared temporaries, checked for being read at the end
+	temporaries []declaration
+
+	// read records the temporary variables that are read
+	read map[string]bool
+
+	// nonLocalReturn is set when a block being compiled contains a ^
+	nonLocalReturn bool
+
+	// diagnostics are the problems found while compiling
+	diagnostics []ast.Diagnostic
+}
+
+// declaration is a temporary variable and the node that declares it
+type declaration struct {
+	name string
+	node ast.Node
+}
+
+// Diagnostic codes of compile errors and warnings
+const (
+	CodeUndeclaredVariable   = "undeclared-variable"    // Name that is not a temporary, instance variable or global
+	CodeAssignmentToArgument = "assignment-to-argument" // Assignment to a method or block argument
+	CodeUnusedTemporary      = "unused-temporary"       // Temporary that is never read
+	CodeShadowedVariable     = "shadowed-variable"      // Argument or temporary hiding an instance variable or outer temporary
+	CodeDuplicateVariable    = "duplicate-variable"     // Argument or temporary declared twice in the same scope
+	CodeSyntaxError          = "syntax-error"           // Code the parser recovered from
+	CodeUnreachableCode      = "unreachable-code"       // Statements after a return
+	CodeInvalidPragma        = "invalid-pragma"         // Malformed primitive pragma
+	CodeUnsupported          = "unsupported"            // Code the bytecode set can't express yet
+	CodeInternal             = "internal-error"         // Failure of the compiler itself
+)
+
+// CompileError is returned when a method has errors, which are also among
+// the diagnostics Compile returns
+type CompileError struct {
+	// Errors are the error diagnostics, at least one
+	Errors []ast.Diagnostic
+}
+
+// Error implements the error interface with the position and message of the
+// first error
+func (e *CompileError) Error() string {
+	first := e.Errors[0]
+	message := fmt.Sprintf("line %d, column %d: %s", first.Range.Start.Line, first.Range.Start.Column, first.Message)
+	if len(e.Errors) > 1 {
+		message += fmt.Sprintf(" (and %d more errors)", len(e.Errors)-1)
+	}
+	return message
 }
 
 // NewBytecodeCompiler creates a new bytecode compiler
@@ -35,11 +94,13 @@ func NewBytecodeCompiler(class *pile.Object) *BytecodeCompiler {
 		Bytecodes:    []byte{},
 		TempVarNames: []string{},
 		Class:        class,
+		read:         map[string]bool{},
 	}
 }
 
-// Compile compiles an AST node to bytecode
-func (c *BytecodeCompiler) Compile(node ast.Node) *pile.Method {
+// Compile compiles an AST node to bytecode. It returns the warnings and errors
+// found, and if there are errors no method but a *CompileError.
+func (c *BytecodeCompiler) Compile(node ast.Node) (method *pile.Method, diagnostics []ast.Diagnostic, err error) {
 	// Create a new method
 	c.Method = &pile.Method{
 		Object: pile.Object{
@@ -50,8 +111,21 @@ func (c *BytecodeCompiler) Compile(node ast.Node) *pile.Method {
 		TempVarNames: []string{},
 	}
 
+	// A failure of the compiler itself is reported as an error of the node
+	defer func() {
+		if r := recover(); r != nil {
+			c.addDiagnostic(node, ast.SeverityError, CodeInternal, fmt.Sprint(r))
+			method, diagnostics, err = nil, c.diagnostics, &CompileError{Errors: c.errors()}
+		}
+	}()
+
 	// Visit the node
 	node.Accept(c)
+	c.checkUnusedTemporaries()
+
+	if errors := c.errors(); len(errors) > 0 {
+		return nil, c.diagnostics, &CompileError{Errors: errors}
+	}
 
 	// Set the method bytecodes and literals
 	c.Method.Bytecodes = c.Bytecodes
@@ -61,7 +135,60 @@ func (c *BytecodeCompiler) Compile(node ast.Node) *pile.Method {
 	// Set the method class
 	c.Method.SetMethodClass(pile.ObjectToClass(c.Class))
 
-	return c.Method
+	return c.Method, c.diagnostics, nil
+}
+
+// addDiagnostic records a problem with a node
+func (c *BytecodeCompiler) addDiagnostic(node ast.Node, severity ast.Severity, code string, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, ast.Diagnostic{
+		Range:    node.Range(),
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// errors returns the error diagnostics
+func (c *BytecodeCompiler) errors() []ast.Diagnostic {
+	var errors []ast.Diagnostic
+	for _, diagnostic := range c.diagnostics {
+		if diagnostic.Severity == ast.SeverityError {
+			errors = append(errors, diagnostic)
+		}
+	}
+	return errors
+}
+
+// declare adds arguments or temporaries declared by a node, warning about
+// names that hide an instance variable or a variable of an enclosing scope.
+// A name already declared in the same scope is an error.
+func (c *BytecodeCompiler) declare(node ast.Node, names []string, temporaries bool) {
+	for _, name := range names {
+		if c.hasTempVar(name) {
+			c.addDiagnostic(node, ast.SeverityError, CodeDuplicateVariable, "%s is already declared", name)
+			continue
+		}
+
+		if c.outer != nil && c.outer.lookupTempVar(name) != nil {
+			c.addDiagnostic(node, ast.SeverityWarning, CodeShadowedVariable, "%s shadows a variable of an enclosing scope", name)
+		} else if c.instanceVarIndex(name) >= 0 {
+			c.addDiagnostic(node, ast.SeverityWarning, CodeShadowedVariable, "%s shadows an instance variable", name)
+		}
+
+		if temporaries {
+			c.temporaries = append(c.temporaries, declaration{name: name, node: node})
+		}
+		c.TempVarNames = append(c.TempVarNames, name)
+	}
+}
+
+// checkUnusedTemporaries warns about the declared temporaries that are never read
+func (c *BytecodeCompiler) checkUnusedTemporaries() {
+	for _, temporary := range c.temporaries {
+		if !c.read[temporary.name] {
+			c.addDiagnostic(temporary.node, ast.SeverityWarning, CodeUnusedTemporary, "temporary %s is never read", temporary.name)
+		}
+	}
 }
 
 // VisitMethodNode visits a method node
@@ -69,22 +196,148 @@ func (c *BytecodeCompiler) VisitMethodNode(node *ast.MethodNode) interface{} {
 	// Set the method selector
 	c.Method.SetSelector(pile.NewSymbol(node.Selector))
 
-	// Set the temporary variable names
-	c.TempVarNames = append(c.TempVarNames, node.Parameters...)
-	c.TempVarNames = append(c.TempVarNames, node.Temporaries...)
+	// Set the temporary variable names, the parameters first
+	c.declare(node, node.Parameters, false)
+	c.argCount = len(node.Parameters)
+	c.declare(node, node.Temporaries, true)
 	c.Method.TempVarNames = c.TempVarNames
 
+	// Record the pragmas, which may declare the primitive of the method
+	for _, pragma := range node.Pragmas {
+		pragma.Accept(c)
+	}
+
 	// Compile the method body
 	node.Body.Accept(c)
 
+	// A method that falls off the end returns self
+	if !endsWithReturn(node.Body) {
+		c.Bytecodes = append(c.Bytecodes, bytecode.POP)
+		c.Bytecodes = append(c.Bytecodes, bytecode.PUSH_SELF)
+		c.Bytecodes = append(c.Bytecodes, bytecode.RETURN_STACK_TOP)
+	}
+
+	return nil
+}
+
+// endsWithReturn returns true if the last statement of the body is a return
+func endsWithReturn(body ast.Node) bool {
+	if sequence, ok := body.(*ast.SequenceNode); ok {
+		if len(sequence.Statements) == 0 {
+			return false
+		}
+		body = sequence.Statements[len(sequence.Statements)-1]
+	}
+
+	_, ok := body.(*ast.ReturnNode)
+	return ok
+}
+
+// VisitSequenceNode visits a sequence node
+func (c *BytecodeCompiler) VisitSequenceNode(node *ast.SequenceNode) interface{} {
+	// Declare the temporaries that the method or block did not already declare
+	var temporaries []string
+	for _, name := range node.Temporaries {
+		if !c.hasTempVar(name) {
+			temporaries = append(temporaries, name)
+		}
+	}
+	c.declare(node, temporaries, true)
+
+	// An empty sequence evaluates to nil
+	if len(node.Statements) == 0 {
+		c.VisitLiteralNode(&ast.LiteralNode{Value: pile.MakeNilImmediate()})
+		return nil
+	}
+
+	for i, statement := range node.Statements {
+		// Discard the value of the previous statement
+		if i > 0 {
+			c.Bytecodes = append(c.Bytecodes, bytecode.POP)
+		}
+
+		// The statements after a return are still compiled, to find their problems
+		if _, ok := statement.(*ast.ReturnNode); ok && i < len(node.Statements)-1 {
+			unreachable := &ast.SequenceNode{Statements: node.Statements[i+1:]}
+			unreachable.Source = ast.SourceRange{
+				Start: node.Statements[i+1].Range().Start,
+				End:   node.Statements[len(node.Statements)-1].Range().End,
+			}
+			c.addDiagnostic(unreachable, ast.SeverityWarning, CodeUnreachableCode, "statements after a return are never run")
+		}
+
+		// Compile the statement
+		statement.Accept(c)
+	}
+
 	return nil
 }
 
+// hasTempVar returns true if the name is already a temporary variable
+func (c *BytecodeCompiler) hasTempVar(name string) bool {
+	return c.tempVarIndex(name) >= 0
+}
+
+// tempVarIndex returns the index of a temporary variable of the method or
+// block being compiled, or -1 if there is none with that name
+func (c *BytecodeCompiler) tempVarIndex(name string) int {
+	for i, tempVarName := range c.TempVarNames {
+		if tempVarName == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// lookupTempVar returns the compiler of the innermost method or block with a
+// temporary variable of that name, or nil if none has one
+func (c *BytecodeCompiler) lookupTempVar(name string) *BytecodeCompiler {
+	for scope := c; scope != nil; scope = scope.outer {
+		if scope.hasTempVar(name) {
+			return scope
+		}
+	}
+	return nil
+}
+
+// outerTempVar returns the compiler of the enclosing method or block that
+// declares a temporary variable, how many scopes out it is and its index
+// there, or a nil compiler if no enclosing scope has one of that name
+func (c *BytecodeCompiler) outerTempVar(name string) (scope *BytecodeCompiler, depth int, index int) {
+	depth = 1
+	for scope = c.outer; scope != nil; scope = scope.outer {
+		if i := scope.tempVarIndex(name); i >= 0 {
+			return scope, depth, i
+		}
+		depth++
+	}
+	return nil, 0, -1
+}
+
+// emitOuterTempVar adds a push or store bytecode for a temporary variable of
+// an enclosing scope, followed by its depth and index (4 bytes each)
+func (c *BytecodeCompiler) emitOuterTempVar(opcode byte, depth int, index int) {
+	c.Bytecodes = append(c.Bytecodes, opcode)
+
+	operandBytes := make([]byte, 8)
+	binary.BigEndian.PutUint32(operandBytes, uint32(depth))
+	binary.BigEndian.PutUint32(operandBytes[4:], uint32(index))
+	c.Bytecodes = append(c.Bytecodes, operandBytes...)
+}
+
 // VisitReturnNode visits a return node
 func (c *BytecodeCompiler) VisitReturnNode(node *ast.ReturnNode) interface{} {
 	// Compile the expression
 	node.Expression.Accept(c)
 
+	// A ^ in a block returns from the method the block is written in
+	if c.outer != nil {
+		c.nonLocalReturn = true
+		c.Bytecodes = append(c.Bytecodes, bytecode.BLOCK_RETURN)
+
+		return nil
+	}
+
 	// Add the return bytecode
 	c.Bytecodes = append(c.Bytecodes, bytecode.RETURN_STACK_TOP)
 
@@ -99,10 +352,85 @@ func (c *BytecodeCompiler) VisitSelfNode(node *ast.SelfNode) interface{} {
 	return nil
 }
 
+// VisitSuperNode visits a super node. The bytecode set has no super send yet,
+// and compiling super as self would silently look up the wrong method.
+func (c *BytecodeCompiler) VisitSuperNode(node *ast.SuperNode) interface{} {
+	c.addDiagnostic(node, ast.SeverityError, CodeUnsupported, "super is not supported by the bytecode compiler")
+	c.VisitLiteralNode(&ast.LiteralNode{Value: pile.MakeNilImmediate()})
+
+	return nil
+}
+
+// VisitThisContextNode visits a thisContext node. The bytecode set has no way
+// to push the current context yet.
+func (c *BytecodeCompiler) VisitThisContextNode(node *ast.ThisContextNode) interface{} {
+	c.addDiagnostic(node, ast.SeverityError, CodeUnsupported, "thisContext is not supported by the bytecode compiler")
+	c.VisitLiteralNode(&ast.LiteralNode{Value: pile.MakeNilImmediate()})
+
+	return nil
+}
+
+// VisitErrorNode visits an error node. Error nodes only appear in ASTs the
+// parser recovered from errors, which must not be run, so the parse error is
+// reported as a compile error. The node compiles to nil to keep compiling.
+func (c *BytecodeCompiler) VisitErrorNode(node *ast.ErrorNode) interface{} {
+	c.addDiagnostic(node, ast.SeverityError, CodeSyntaxError, "%s", node.Message)
+	c.VisitLiteralNode(&ast.LiteralNode{Value: pile.MakeNilImmediate()})
+
+	return nil
+}
+
+// VisitPragmaNode visits a pragma node. The pragma is kept on the method, and
+// <primitive: N> or <primitive: N error: ec> makes the method a primitive whose
+// body runs when the primitive fails. The error code variable becomes a temporary.
+func (c *BytecodeCompiler) VisitPragmaNode(node *ast.PragmaNode) interface{} {
+	pragma := &pile.Pragma{Selector: node.Selector}
+	for _, argument := range node.Arguments {
+		switch argument := argument.(type) {
+		case *ast.LiteralNode:
+			pragma.Arguments = append(pragma.Arguments, argument.Value)
+		case *ast.VariableNode:
+			pragma.Arguments = append(pragma.Arguments, pile.NewSymbol(argument.Name))
+		default:
+			c.addDiagnostic(argument, ast.SeverityError, CodeInvalidPragma, "invalid pragma argument %T", argument)
+			return nil
+		}
+	}
+	c.Method.AddPragma(pragma)
+
+	if node.Selector != "primitive:" && node.Selector != "primitive:error:" {
+		return nil
+	}
+
+	// The primitive index must be an integer
+	index, ok := node.Arguments[0].(*ast.LiteralNode)
+	if !ok || !pile.IsIntegerImmediate(index.Value) {
+		c.addDiagnostic(node, ast.SeverityError, CodeInvalidPragma, "primitive index must be an integer")
+		return nil
+	}
+	c.Method.SetPrimitive(true)
+	c.Method.SetPrimitiveIndex(int(pile.GetIntegerImmediate(index.Value)))
+
+	// Declare the error code variable
+	if node.Selector == "primitive:error:" {
+		variable, ok := node.Arguments[1].(*ast.VariableNode)
+		if !ok {
+			c.addDiagnostic(node, ast.SeverityError, CodeInvalidPragma, "primitive error code must be a variable name")
+			return nil
+		}
+		if !c.hasTempVar(variable.Name) {
+			c.declare(node, []string{variable.Name}, false)
+			c.Method.TempVarNames = c.TempVarNames
+		}
+	}
+
+	return nil
+}
+
 // VisitLiteralNode visits a literal node
 func (c *BytecodeCompiler) VisitLiteralNode(node *ast.LiteralNode) interface{} {
 	// Add the literal to the literals array
-	literalIndex := c.addLiteral(node.Value)
+	literalIndex := c.addLiteralValue(node)
 
 	// Add the push literal bytecode
 	c.Bytecodes = append(c.Bytecodes, bytecode.PUSH_LITERAL)
@@ -118,25 +446,80 @@ func (c *BytecodeCompiler) VisitLiteralNode(node *ast.LiteralNode) interface{} {
 // VisitVariableNode visits a variable node
 func (c *BytecodeCompiler) VisitVariableNode(node *ast.VariableNode) interface{} {
 	// Check if the variable is a temporary variable
-	for i, name := range c.TempVarNames {
-		if name == node.Name {
-			// Add the push temporary variable bytecode
-			c.Bytecodes = append(c.Bytecodes, bytecode.PUSH_TEMPORARY_VARIABLE)
+	if i := c.tempVarIndex(node.Name); i >= 0 {
+		c.read[node.Name] = true
 
-			// Add the temporary variable index (4 bytes)
-			indexBytes := make([]byte, 4)
-			binary.BigEndian.PutUint32(indexBytes, uint32(i))
-			c.Bytecodes = append(c.Bytecodes, indexBytes...)
+		// Add the push temporary variable bytecode
+		c.Bytecodes = append(c.Bytecodes, bytecode.PUSH_TEMPORARY_VARIABLE)
 
-			return nil
-		}
+		// Add the temporary variable index (4 bytes)
+		indexBytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(indexBytes, uint32(i))
+		c.Bytecodes = append(c.Bytecodes, indexBytes...)
+
+		return nil
+	}
+
+	// Check if the variable is a temporary variable of an enclosing scope
+	if scope, depth, i := c.outerTempVar(node.Name); scope != nil {
+		scope.read[node.Name] = true
+		c.emitOuterTempVar(bytecode.PUSH_OUTER_TEMPORARY_VARIABLE, depth, i)
+
+		return nil
 	}
 
 	// Check if the variable is an instance variable
-	// TODO: Implement instance variable lookup
+	if index := c.instanceVarIndex(node.Name); index >= 0 {
+		// Add the push instance variable bytecode
+		c.Bytecodes = append(c.Bytecodes, bytecode.PUSH_INSTANCE_VARIABLE)
+
+		// Add the instance variable index (4 bytes)
+		indexBytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(indexBytes, uint32(index))
+		c.Bytecodes = append(c.Bytecodes, indexBytes...)
+
+		return nil
+	}
+
+	// If we get here, the variable is not declared
+	c.addDiagnostic(node, ast.SeverityError, CodeUndeclaredVariable, "undeclared variable %s", node.Name)
+	c.VisitLiteralNode(&ast.LiteralNode{Value: pile.MakeNilImmediate()})
+
+	return nil
+}
+
+// instanceVarIndex returns the slot of an instance variable of the class the
+// method is compiled for, counting the instance variables of the superclasses,
+// or -1 if there is no such instance variable
+func (c *BytecodeCompiler) instanceVarIndex(name string) int {
+	if c.Class == nil {
+		return -1
+	}
+	return pile.GetClassInstanceVarIndex(pile.ObjectToClass(c.Class), name)
+}
+
+// VisitGlobalNode visits a global variable node. The binding of the global
+// becomes a literal, so the method sees the value the global has when it runs.
+func (c *BytecodeCompiler) VisitGlobalNode(node *ast.GlobalNode) interface{} {
+	if c.Globals == nil {
+		c.addDiagnostic(node, ast.SeverityError, CodeUndeclaredVariable, "global %s can't be compiled without global access", node.Name)
+		c.VisitLiteralNode(&ast.LiteralNode{Value: pile.MakeNilImmediate()})
+
+		return nil
+	}
 
-	// If we get here, the variable is not found
-	panic(fmt.Sprintf("Variable not found: %s", node.Name))
+	// Add the binding to the literals array
+	bindingIndex := c.addLiteral(c.Globals.GlobalBinding(node.Name))
+
+	// Add the push global bytecode
+	c.Bytecodes = append(c.Bytecodes, bytecode.PUSH_GLOBAL)
+
+	// Add the binding index (4 bytes)
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, uint32(bindingIndex))
+	c.Bytecodes = append(c.Bytecodes, indexBytes...)
+
+	return nil
 }
 
 // VisitAssignmentNode visits an assignment node
@@ -144,26 +527,71 @@ func (c *BytecodeCompiler) VisitAssignmentNode(node *ast.AssignmentNode) interfa
 	// Compile the expression
 	node.Expression.Accept(c)
 
-	// Check if the variable is a temporary variable
-	for i, name := range c.TempVarNames {
-		if name == node.Variable {
-			// Add the store temporary variable bytecode
-			c.Bytecodes = append(c.Bytecodes, bytecode.STORE_TEMPORARY_VARIABLE)
+	// Check if the variable is a temporary variable, arguments can't be assigned
+	if i := c.tempVarIndex(node.Variable); i >= 0 {
+		if i < c.argCount {
+			c.addDiagnostic(node, ast.SeverityError, CodeAssignmentToArgument, "cannot assign to argument %s", node.Variable)
+		}
 
-			// Add the temporary variable index (4 bytes)
-			indexBytes := make([]byte, 4)
-			binary.BigEndian.PutUint32(indexBytes, uint32(i))
-			c.Bytecodes = append(c.Bytecodes, indexBytes...)
+		// Add the store temporary variable bytecode
+		c.Bytecodes = append(c.Bytecodes, bytecode.STORE_TEMPORARY_VARIABLE)
 
-			return nil
+		// Add the temporary variable index (4 bytes)
+		indexBytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(indexBytes, uint32(i))
+		c.Bytecodes = append(c.Bytecodes, indexBytes...)
+
+		return nil
+	}
+
+	// Check if the variable is a temporary variable of an enclosing scope
+	if scope, depth, i := c.outerTempVar(node.Variable); scope != nil {
+		if i < scope.argCount {
+			c.addDiagnostic(node, ast.SeverityError, CodeAssignmentToArgument, "cannot assign to argument %s", node.Variable)
 		}
+		c.emitOuterTempVar(bytecode.STORE_OUTER_TEMPORARY_VARIABLE, depth, i)
+
+		return nil
 	}
 
 	// Check if the variable is an instance variable
-	// TODO: Implement instance variable lookup
+	if index := c.instanceVarIndex(node.Variable); index >= 0 {
+		// Add the store instance variable bytecode
+		c.Bytecodes = append(c.Bytecodes, bytecode.STORE_INSTANCE_VARIABLE)
+
+		// Add the instance variable index (4 bytes)
+		indexBytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(indexBytes, uint32(index))
+		c.Bytecodes = append(c.Bytecodes, indexBytes...)
+
+		return nil
+	}
+
+	// Check if the variable is a global, which is stored through its binding
+	if ast.IsGlobalName(node.Variable) {
+		if c.Globals == nil {
+			c.addDiagnostic(node, ast.SeverityError, CodeUndeclaredVariable, "global %s can't be compiled without global access", node.Variable)
+			return nil
+		}
+
+		// Add the binding to the literals array
+		bindingIndex := c.addLiteral(c.Globals.GlobalBinding(node.Variable))
+
+		// Add the store global bytecode
+		c.Bytecodes = append(c.Bytecodes, bytecode.STORE_GLOBAL)
 
-	// If we get here, the variable is not found
-	panic(fmt.Sprintf("Variable not found: %s", node.Variable))
+		// Add the binding index (4 bytes)
+		indexBytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(indexBytes, uint32(bindingIndex))
+		c.Bytecodes = append(c.Bytecodes, indexBytes...)
+
+		return nil
+	}
+
+	// If we get here, the variable is not declared
+	c.addDiagnostic(node, ast.SeverityError, CodeUndeclaredVariable, "undeclared variable %s", node.Variable)
+
+	return nil
 }
 
 // VisitMessageSendNode visits a message send node
@@ -176,8 +604,16 @@ func (c *BytecodeCompiler) VisitMessageSendNode(node *ast.MessageSendNode) inter
 		arg.Accept(c)
 	}
 
+	// Send the message
+	c.emitSend(node.Selector, len(node.Arguments))
+
+	return nil
+}
+
+// emitSend adds a SEND_MESSAGE bytecode for the given selector and argument count
+func (c *BytecodeCompiler) emitSend(selector string, argCount int) {
 	// Create a symbol and add it to the literals array
-	symbol := pile.NewSymbol(node.Selector)
+	symbol := pile.NewSymbol(selector)
 	selectorIndex := c.addLiteral(symbol)
 
 	// Add the send message bytecode
@@ -190,8 +626,70 @@ func (c *BytecodeCompiler) VisitMessageSendNode(node *ast.MessageSendNode) inter
 
 	// Add the argument count (4 bytes)
 	argCountBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(argCountBytes, uint32(len(node.Arguments)))
+	binary.BigEndian.PutUint32(argCountBytes, uint32(argCount))
 	c.Bytecodes = append(c.Bytecodes, argCountBytes...)
+}
+
+// VisitCascadeNode visits a cascade node
+func (c *BytecodeCompiler) VisitCascadeNode(node *ast.CascadeNode) interface{} {
+	// Compile the receiver once
+	node.Receiver.Accept(c)
+
+	for i, message := range node.Messages {
+		isLast := i == len(node.Messages)-1
+
+		// Keep a copy of the receiver for the next message
+		if !isLast {
+			c.Bytecodes = append(c.Bytecodes, bytecode.DUPLICATE)
+		}
+
+		// Send the message to the receiver already on the stack
+		c.compileCascadeMessage(node, message)
+
+		// Discard the result of every message but the last
+		if !isLast {
+			c.Bytecodes = append(c.Bytecodes, bytecode.POP)
+		}
+	}
+
+	return nil
+}
+
+// compileCascadeMessage compiles one cascade part assuming the cascade receiver
+// is already on the stack. Messages chained onto the part (e.g. "; foo bar")
+// are compiled innermost first until the shared receiver is reached.
+func (c *BytecodeCompiler) compileCascadeMessage(cascade *ast.CascadeNode, message *ast.MessageSendNode) {
+	// Compile the inner part of the chain first
+	if message.Receiver != cascade.Receiver {
+		inner, ok := message.Receiver.(*ast.MessageSendNode)
+		if !ok {
+			panic(fmt.Sprintf("Invalid cascade message receiver: %T", message.Receiver))
+		}
+		c.compileCascadeMessage(cascade, inner)
+	}
+
+	// Compile the arguments
+	for _, arg := range message.Arguments {
+		arg.Accept(c)
+	}
+
+	c.emitSend(message.Selector, len(message.Arguments))
+}
+
+// VisitDynamicArrayNode visits a dynamic array node
+func (c *BytecodeCompiler) VisitDynamicArrayNode(node *ast.DynamicArrayNode) interface{} {
+	// Compile the elements in order
+	for _, element := range node.Elements {
+		element.Accept(c)
+	}
+
+	// Add the create array bytecode
+	c.Bytecodes = append(c.Bytecodes, bytecode.CREATE_ARRAY)
+
+	// Add the element count (4 bytes)
+	sizeBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(sizeBytes, uint32(len(node.Elements)))
+	c.Bytecodes = append(c.Bytecodes, sizeBytes...)
 
 	return nil
 }
@@ -200,44 +698,56 @@ func (c *BytecodeCompiler) VisitMessageSendNode(node *ast.MessageSendNode) inter
 func (c *BytecodeCompiler) VisitBlockNode(node *ast.BlockNode) interface{} {
 	// Create a new bytecode compiler for the block
 	blockCompiler := NewBytecodeCompiler(c.Class)
+	blockCompiler.Globals = c.Globals
+	blockCompiler.outer = c
 
-	// Set the temporary variable names
-	blockCompiler.TempVarNames = append(blockCompiler.TempVarNames, node.Parameters...)
-	blockCompiler.TempVarNames = append(blockCompiler.TempVarNames, node.Temporaries...)
+	// Set the temporary variable names, the parameters first
+	blockCompiler.declare(node, node.Parameters, false)
+	blockCompiler.argCount = len(node.Parameters)
+	blockCompiler.declare(node, node.Temporaries, true)
 
-	// Compile the block body
+	// Compile the block body, keeping the problems found in it
 	node.Body.Accept(blockCompiler)
+	blockCompiler.checkUnusedTemporaries()
+	c.diagnostics = append(c.diagnostics, blockCompiler.diagnostics...)
 
-	// Add the create block bytecode
-	c.Bytecodes = append(c.Bytecodes, bytecode.CREATE_BLOCK)
+	// A block answers the value of its last statement
+	if !endsWithReturn(node.Body) {
+		blockCompiler.Bytecodes = append(blockCompiler.Bytecodes, bytecode.RETURN_STACK_TOP)
+	}
 
-	// Add the bytecode size (4 bytes)
-	bytecodeSize := len(blockCompiler.Bytecodes)
-	bytecodeSizeBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(bytecodeSizeBytes, uint32(bytecodeSize))
-	c.Bytecodes = append(c.Bytecodes, bytecodeSizeBytes...)
+	// The compiled block is a literal with its own bytecodes and literals
+	compiledBlock := pile.NewCompiledBlock(blockCompiler.Bytecodes, blockCompiler.Literals, blockCompiler.TempVarNames, len(node.Parameters))
+	compiledBlock.NonLocalReturn = blockCompiler.nonLocalReturn
+	blockIndex := c.addLiteral(pile.CompiledBlockToObject(compiledBlock))
 
-	// Add the literal count (4 bytes)
-	literalCount := len(blockCompiler.Literals)
-	literalCountBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(literalCountBytes, uint32(literalCount))
-	c.Bytecodes = append(c.Bytecodes, literalCountBytes...)
+	// Add the create block bytecode
+	c.Bytecodes = append(c.Bytecodes, bytecode.CREATE_BLOCK)
 
-	// Add the temporary variable count (4 bytes)
-	tempVarCount := len(blockCompiler.TempVarNames)
-	tempVarCountBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(tempVarCountBytes, uint32(tempVarCount))
-	c.Bytecodes = append(c.Bytecodes, tempVarCountBytes...)
+	// Add the compiled block index (4 bytes)
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, uint32(blockIndex))
+	c.Bytecodes = append(c.Bytecodes, indexBytes...)
 
-	// Add the block bytecodes
-	c.Bytecodes = append(c.Bytecodes, blockCompiler.Bytecodes...)
+	return nil
+}
 
-	// Add the block literals to the method literals
-	for _, literal := range blockCompiler.Literals {
-		c.addLiteral(literal)
+// addLiteralValue adds the value of a literal node to the literals array and
+// returns its index. The value may be a tagged immediate, which must not be
+// live in a Go stack frame when the stack is copied, so it is compared and
+// stored straight from the node after making room for it.
+func (c *BytecodeCompiler) addLiteralValue(node *ast.LiteralNode) int {
+	// Check if the literal already exists
+	for i := range c.Literals {
+		if c.Literals[i] == node.Value {
+			return i
+		}
 	}
 
-	return nil
+	// Add the literal
+	c.Literals = append(c.Literals, nil)
+	c.Literals[len(c.Literals)-1] = node.Value
+	return len(c.Literals) - 1
 }
 
 // addLiteral adds a literal to the literals array and returns its index
@@ -8,6 +8,15 @@ import (
 	"smalltalklsp/interpreter/pile"
 )
 
+// integerLiteral returns a literal node for an integer. The tagged immediate
+// is stored after allocating the node, so that it is never live in a Go stack
+// frame while the stack may be copied.
+func integerLiteral(value int64) *ast.LiteralNode {
+	node := &ast.LiteralNode{}
+	node.Value = pile.MakeIntegerImmediate(value)
+	return node
+}
+
 // TestCompileYourself tests compiling the method Object>>yourself ^self
 func TestCompileYourself(t *testing.T) {
 	// Create a class
@@ -28,7 +37,10 @@ func TestCompileYourself(t *testing.T) {
 	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
 
 	// Compile the method
-	method := compiler.Compile(methodNode)
+	method, _, err := compiler.Compile(methodNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
 
 	// Check the method selector
 	if method.GetSelector() == nil {
@@ -101,7 +113,10 @@ func TestCompileAdd(t *testing.T) {
 	compiler := NewBytecodeCompiler(pile.ClassToObject(integerClass))
 
 	// Compile the method
-	method := compiler.Compile(methodNode)
+	method, _, err := compiler.Compile(methodNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
 
 	// Check the method selector
 	if method.GetSelector() == nil {
@@ -163,4 +178,656 @@ func TestCompileAdd(t *testing.T) {
 	if method.GetMethodClass() != integerClass {
 		t.Errorf("Expected method class to be %v, got %v", integerClass, method.GetMethodClass())
 	}
-}
\ No newline at end of file
+}
+
+// TestCompileCascade tests compiling the cascade 3 + 4; * 10
+func TestCompileCascade(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for 3 + 4; * 10
+	receiver := integerLiteral(3)
+	cascadeNode := &ast.CascadeNode{
+		Receiver: receiver,
+		Messages: []*ast.MessageSendNode{
+			{
+				Receiver:  receiver,
+				Selector:  "+",
+				Arguments: []ast.Node{integerLiteral(4)},
+			},
+			{
+				Receiver:  receiver,
+				Selector:  "*",
+				Arguments: []ast.Node{integerLiteral(10)},
+			},
+		},
+	}
+
+	// Compile the cascade
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(cascadeNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	// The receiver is evaluated once and duplicated for every message but the last
+	expectedBytecodes := []byte{
+		bytecode.PUSH_LITERAL, 0, 0, 0, 0, // Push 3
+		bytecode.DUPLICATE,                // Keep the receiver for the next message
+		bytecode.PUSH_LITERAL, 0, 0, 0, 1, // Push 4
+		bytecode.SEND_MESSAGE, 0, 0, 0, 2, 0, 0, 0, 1, // Send +
+		bytecode.POP,                      // Discard the result of +
+		bytecode.PUSH_LITERAL, 0, 0, 0, 3, // Push 10
+		bytecode.SEND_MESSAGE, 0, 0, 0, 4, 0, 0, 0, 1, // Send *
+	}
+
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+}
+
+// TestCompileCascadeChainedPart tests compiling a cascade part that chains further messages
+func TestCompileCascadeChainedPart(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for x foo; bar baz
+	receiver := &ast.SelfNode{}
+	cascadeNode := &ast.CascadeNode{
+		Receiver: receiver,
+		Messages: []*ast.MessageSendNode{
+			{Receiver: receiver, Selector: "foo", Arguments: []ast.Node{}},
+			{
+				Receiver:  &ast.MessageSendNode{Receiver: receiver, Selector: "bar", Arguments: []ast.Node{}},
+				Selector:  "baz",
+				Arguments: []ast.Node{},
+			},
+		},
+	}
+
+	// Compile the cascade
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(cascadeNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_SELF,
+		bytecode.DUPLICATE,
+		bytecode.SEND_MESSAGE, 0, 0, 0, 0, 0, 0, 0, 0, // Send foo
+		bytecode.POP,
+		bytecode.SEND_MESSAGE, 0, 0, 0, 1, 0, 0, 0, 0, // Send bar
+		bytecode.SEND_MESSAGE, 0, 0, 0, 2, 0, 0, 0, 0, // Send baz
+	}
+
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+}
+
+// TestCompileSequence tests that every statement of a sequence is compiled
+// and that the value of each statement but the last is discarded
+func TestCompileSequence(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for | a | a := 3. a + 4
+	sequenceNode := &ast.SequenceNode{
+		Temporaries: []string{"a"},
+		Statements: []ast.Node{
+			&ast.AssignmentNode{
+				Variable:   "a",
+				Expression: integerLiteral(3),
+			},
+			&ast.MessageSendNode{
+				Receiver:  &ast.VariableNode{Name: "a"},
+				Selector:  "+",
+				Arguments: []ast.Node{integerLiteral(4)},
+			},
+		},
+	}
+
+	// Compile the sequence
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(sequenceNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_LITERAL, 0, 0, 0, 0, // Push 3
+		bytecode.STORE_TEMPORARY_VARIABLE, 0, 0, 0, 0, // Store into a
+		bytecode.POP,                                 // Discard the value of the first statement
+		bytecode.PUSH_TEMPORARY_VARIABLE, 0, 0, 0, 0, // Push a
+		bytecode.PUSH_LITERAL, 0, 0, 0, 1, // Push 4
+		bytecode.SEND_MESSAGE, 0, 0, 0, 2, 0, 0, 0, 1, // Send +
+	}
+
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+
+	// The sequence temporaries become method temporaries
+	if len(method.TempVarNames) != 1 || method.TempVarNames[0] != "a" {
+		t.Errorf("Expected temporary variables [a], got %v", method.TempVarNames)
+	}
+}
+
+// TestCompileDynamicArray tests compiling a brace array
+func TestCompileDynamicArray(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for {1. 2 + 3}
+	arrayNode := &ast.DynamicArrayNode{
+		Elements: []ast.Node{
+			integerLiteral(1),
+			&ast.MessageSendNode{
+				Receiver:  integerLiteral(2),
+				Selector:  "+",
+				Arguments: []ast.Node{integerLiteral(3)},
+			},
+		},
+	}
+
+	// Compile the array
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(arrayNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_LITERAL, 0, 0, 0, 0, // Push 1
+		bytecode.PUSH_LITERAL, 0, 0, 0, 1, // Push 2
+		bytecode.PUSH_LITERAL, 0, 0, 0, 2, // Push 3
+		bytecode.SEND_MESSAGE, 0, 0, 0, 3, 0, 0, 0, 1, // Send +
+		bytecode.CREATE_ARRAY, 0, 0, 0, 2, // Create the two element array
+	}
+
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+}
+
+// TestCompileMethodImplicitReturn tests that a method without a final return answers self
+func TestCompileMethodImplicitReturn(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for Object>>touch 3
+	methodNode := &ast.MethodNode{
+		Selector:    "touch",
+		Parameters:  []string{},
+		Temporaries: []string{},
+		Body: &ast.SequenceNode{
+			Temporaries: []string{},
+			Statements: []ast.Node{
+				integerLiteral(3),
+			},
+		},
+		Class: pile.ClassToObject(objectClass),
+	}
+
+	// Compile the method
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(methodNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_LITERAL, 0, 0, 0, 0, // Push 3
+		bytecode.POP,              // Discard the value of the last statement
+		bytecode.PUSH_SELF,        // Push self
+		bytecode.RETURN_STACK_TOP, // Return self
+	}
+
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+}
+
+// TestCompilePrimitivePragma tests that <primitive: N error: ec> makes a
+// primitive method and keeps its pragmas
+func TestCompilePrimitivePragma(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for Object>>at: index <primitive: 60 error: ec> <category: #accessing> ^ec
+	methodNode := &ast.MethodNode{
+		Selector:    "at:",
+		Parameters:  []string{"index"},
+		Temporaries: []string{},
+		Pragmas: []*ast.PragmaNode{
+			{
+				Selector: "primitive:error:",
+				Arguments: []ast.Node{
+					integerLiteral(60),
+					&ast.VariableNode{Name: "ec"},
+				},
+			},
+			{
+				Selector:  "category:",
+				Arguments: []ast.Node{&ast.LiteralNode{Value: pile.NewSymbol("accessing")}},
+			},
+		},
+		Body: &ast.ReturnNode{
+			Expression: &ast.VariableNode{Name: "ec"},
+		},
+		Class: pile.ClassToObject(objectClass),
+	}
+
+	// Compile the method
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(methodNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	if !method.IsPrimitiveMethod() || method.GetPrimitiveIndex() != 60 {
+		t.Errorf("Expected primitive 60, got primitive %v with index %d", method.IsPrimitiveMethod(), method.GetPrimitiveIndex())
+	}
+
+	// The error code is a temporary after the parameters
+	if len(method.TempVarNames) != 2 || method.TempVarNames[1] != "ec" {
+		t.Errorf("Expected temporaries [index ec], got %v", method.TempVarNames)
+	}
+
+	// The fallback code returns the error code
+	expectedBytecodes := []byte{
+		bytecode.PUSH_TEMPORARY_VARIABLE, 0, 0, 0, 1, // Push ec
+		bytecode.RETURN_STACK_TOP, // Return it
+	}
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+
+	// The pragmas are kept for reflection
+	if len(method.GetPragmas()) != 2 {
+		t.Fatalf("Expected 2 pragmas, got %d", len(method.GetPragmas()))
+	}
+	category := method.GetPragma("category:")
+	if category == nil || pile.ObjectToSymbol(category.Arguments[0]).GetValue() != "accessing" {
+		t.Errorf("Expected category: #accessing pragma, got %v", category)
+	}
+	primitive := method.GetPragma("primitive:error:")
+	if primitive == nil || pile.ObjectToSymbol(primitive.Arguments[1]).GetValue() != "ec" {
+		t.Errorf("Expected the error code to be kept as the symbol #ec, got %v", primitive)
+	}
+}
+
+// testGlobals provides bindings for the compiler tests, one per name
+type testGlobals map[string]*pile.Object
+
+// GlobalBinding implements GlobalAccess
+func (g testGlobals) GlobalBinding(name string) *pile.Object {
+	if _, ok := g[name]; !ok {
+		g[name] = pile.AssociationToObject(pile.NewAssociationInternal(pile.NewSymbol(name), pile.MakeNilImmediate()))
+	}
+	return g[name]
+}
+
+// TestCompileGlobal tests that globals compile to pushes of their shared binding
+func TestCompileGlobal(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for {Foo. Bar. Foo}
+	arrayNode := &ast.DynamicArrayNode{
+		Elements: []ast.Node{
+			&ast.GlobalNode{Name: "Foo"},
+			&ast.GlobalNode{Name: "Bar"},
+			&ast.GlobalNode{Name: "Foo"},
+		},
+	}
+
+	// Compile the array
+	globals := testGlobals{}
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	compiler.Globals = globals
+	method, _, err := compiler.Compile(arrayNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_GLOBAL, 0, 0, 0, 0, // Push Foo
+		bytecode.PUSH_GLOBAL, 0, 0, 0, 1, // Push Bar
+		bytecode.PUSH_GLOBAL, 0, 0, 0, 0, // Push Foo from the same binding
+		bytecode.CREATE_ARRAY, 0, 0, 0, 3, // Create the three element array
+	}
+
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+
+	// The bindings are the literals
+	if len(method.Literals) != 2 || method.Literals[0] != globals["Foo"] || method.Literals[1] != globals["Bar"] {
+		t.Errorf("Expected the bindings of Foo and Bar as literals, got %v", method.Literals)
+	}
+}
+
+// TestCompileGlobalAssignment tests that assignments to globals compile to
+// stores into their shared binding
+func TestCompileGlobalAssignment(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for {Foo := Bar. Foo}
+	arrayNode := &ast.DynamicArrayNode{
+		Elements: []ast.Node{
+			&ast.AssignmentNode{Variable: "Foo", Expression: &ast.GlobalNode{Name: "Bar"}},
+			&ast.GlobalNode{Name: "Foo"},
+		},
+	}
+
+	// Compile the expression
+	globals := testGlobals{}
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	compiler.Globals = globals
+	method, _, err := compiler.Compile(arrayNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_GLOBAL, 0, 0, 0, 0, // Push Bar
+		bytecode.STORE_GLOBAL, 0, 0, 0, 1, // Store into Foo
+		bytecode.PUSH_GLOBAL, 0, 0, 0, 1, // Push Foo from the same binding
+		bytecode.CREATE_ARRAY, 0, 0, 0, 2, // Create the two element array
+	}
+
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+
+	// The bindings are the literals
+	if len(method.Literals) != 2 || method.Literals[0] != globals["Bar"] || method.Literals[1] != globals["Foo"] {
+		t.Errorf("Expected the bindings of Bar and Foo as literals, got %v", method.Literals)
+	}
+}
+
+// TestCompileInstanceVariables tests that instance variables, including the
+// inherited ones, compile to pushes and stores of their slot
+func TestCompileInstanceVariables(t *testing.T) {
+	// Create the classes, Pair declaring key and Triple adding value
+	objectClass := pile.NewClass("Object", nil)
+	pairClass := pile.NewClass("Pair", objectClass)
+	pile.AddClassInstanceVarName(pairClass, "key")
+	tripleClass := pile.NewClass("Triple", pairClass)
+	pile.AddClassInstanceVarName(tripleClass, "value")
+
+	// Create the AST for Triple>>swap | t | t := key. key := value. value := t
+	methodNode := &ast.MethodNode{
+		Selector:    "swap",
+		Temporaries: []string{"t"},
+		Body: &ast.SequenceNode{
+			Statements: []ast.Node{
+				&ast.AssignmentNode{Variable: "t", Expression: &ast.VariableNode{Name: "key"}},
+				&ast.AssignmentNode{Variable: "key", Expression: &ast.VariableNode{Name: "value"}},
+				&ast.AssignmentNode{Variable: "value", Expression: &ast.VariableNode{Name: "t"}},
+			},
+		},
+	}
+
+	// Compile the method
+	compiler := NewBytecodeCompiler(pile.ClassToObject(tripleClass))
+	method, _, err := compiler.Compile(methodNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_INSTANCE_VARIABLE, 0, 0, 0, 0, // Push key, inherited from Pair
+		bytecode.STORE_TEMPORARY_VARIABLE, 0, 0, 0, 0, // Store into t
+		bytecode.POP,
+		bytecode.PUSH_INSTANCE_VARIABLE, 0, 0, 0, 1, // Push value, after the inherited slot
+		bytecode.STORE_INSTANCE_VARIABLE, 0, 0, 0, 0, // Store into key
+		bytecode.POP,
+		bytecode.PUSH_TEMPORARY_VARIABLE, 0, 0, 0, 0, // Push t
+		bytecode.STORE_INSTANCE_VARIABLE, 0, 0, 0, 1, // Store into value
+		bytecode.POP, // Return self
+		bytecode.PUSH_SELF,
+		bytecode.RETURN_STACK_TOP,
+	}
+
+	if len(method.Bytecodes) != len(expectedBytecodes) {
+		t.Fatalf("Expected bytecode length to be %d, got %d", len(expectedBytecodes), len(method.Bytecodes))
+	}
+	for i, b := range expectedBytecodes {
+		if method.Bytecodes[i] != b {
+			t.Errorf("Expected bytecode at index %d to be %d, got %d", i, b, method.Bytecodes[i])
+		}
+	}
+}
+
+// TestCompileBlock tests that a block compiles to a compiled block literal
+// with its own bytecodes and literals
+func TestCompileBlock(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for 10. [:x | x + 20]
+	sequenceNode := &ast.SequenceNode{
+		Statements: []ast.Node{
+			integerLiteral(10),
+			&ast.BlockNode{
+				Parameters: []string{"x"},
+				Body: &ast.SequenceNode{
+					Statements: []ast.Node{
+						&ast.MessageSendNode{
+							Receiver:  &ast.VariableNode{Name: "x"},
+							Selector:  "+",
+							Arguments: []ast.Node{integerLiteral(20)},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Compile the sequence
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(sequenceNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_LITERAL, 0, 0, 0, 0, // Push 10
+		bytecode.POP,
+		bytecode.CREATE_BLOCK, 0, 0, 0, 1, // Create the block from literal 1
+	}
+	if string(method.Bytecodes) != string(expectedBytecodes) {
+		t.Errorf("Expected bytecodes %v, got %v", expectedBytecodes, method.Bytecodes)
+	}
+
+	// The block's literals are not in the method
+	if len(method.Literals) != 2 {
+		t.Fatalf("Expected 2 literals, got %v", method.Literals)
+	}
+	block := pile.ObjectToCompiledBlock(method.Literals[1])
+	if block == nil {
+		t.Fatalf("Expected a compiled block, got %v", method.Literals[1])
+	}
+
+	expectedBlockBytecodes := []byte{
+		bytecode.PUSH_TEMPORARY_VARIABLE, 0, 0, 0, 0, // Push x
+		bytecode.PUSH_LITERAL, 0, 0, 0, 0, // Push 20, the first literal of the block
+		bytecode.SEND_MESSAGE, 0, 0, 0, 1, 0, 0, 0, 1, // Send +
+		bytecode.RETURN_STACK_TOP, // Return the value of the last statement
+	}
+	if string(block.Bytecodes) != string(expectedBlockBytecodes) {
+		t.Errorf("Expected block bytecodes %v, got %v", expectedBlockBytecodes, block.Bytecodes)
+	}
+	if len(block.Literals) != 2 || pile.GetIntegerImmediate(block.Literals[0]) != 20 {
+		t.Errorf("Expected the block literals 20 and #+, got %v", block.Literals)
+	}
+	if block.NumArgs != 1 || block.NumTemps() != 1 {
+		t.Errorf("Expected 1 argument and 1 temporary, got %d and %d", block.NumArgs, block.NumTemps())
+	}
+}
+
+// TestCompileOuterTemporaries tests that blocks address the temporaries of
+// enclosing scopes by how many scopes out they are and their index there
+func TestCompileOuterTemporaries(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for foo | a | ^[:x | [a := x]]
+	methodNode := &ast.MethodNode{
+		Selector:    "foo",
+		Parameters:  []string{},
+		Temporaries: []string{"a"},
+		Body: &ast.ReturnNode{
+			Expression: &ast.BlockNode{
+				Parameters: []string{"x"},
+				Body: &ast.SequenceNode{
+					Statements: []ast.Node{
+						&ast.BlockNode{
+							Body: &ast.SequenceNode{
+								Statements: []ast.Node{
+									&ast.AssignmentNode{
+										Variable:   "a",
+										Expression: &ast.VariableNode{Name: "x"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Class: pile.ClassToObject(objectClass),
+	}
+
+	// Compile the method
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(methodNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	outerBlock := pile.ObjectToCompiledBlock(method.Literals[0])
+	if outerBlock == nil {
+		t.Fatalf("Expected a compiled block, got %v", method.Literals[0])
+	}
+	innerBlock := pile.ObjectToCompiledBlock(outerBlock.Literals[0])
+	if innerBlock == nil {
+		t.Fatalf("Expected a compiled block, got %v", outerBlock.Literals[0])
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_OUTER_TEMPORARY_VARIABLE, 0, 0, 0, 1, 0, 0, 0, 0, // Push x, one scope out
+		bytecode.STORE_OUTER_TEMPORARY_VARIABLE, 0, 0, 0, 2, 0, 0, 0, 0, // Store into a, two scopes out
+		bytecode.RETURN_STACK_TOP,
+	}
+	if string(innerBlock.Bytecodes) != string(expectedBytecodes) {
+		t.Errorf("Expected inner block bytecodes %v, got %v", expectedBytecodes, innerBlock.Bytecodes)
+	}
+	if innerBlock.NumTemps() != 0 {
+		t.Errorf("Expected the inner block to have no temporaries, got %v", innerBlock.TempVarNames)
+	}
+}
+
+// TestCompileNonLocalReturn tests that a ^ in a block compiles to a return
+// from the home method and marks the block
+func TestCompileNonLocalReturn(t *testing.T) {
+	// Create a class
+	objectClass := pile.NewClass("Object", nil)
+
+	// Create the AST for foo ^[:x | [^x]]
+	methodNode := &ast.MethodNode{
+		Selector:    "foo",
+		Parameters:  []string{},
+		Temporaries: []string{},
+		Body: &ast.ReturnNode{
+			Expression: &ast.BlockNode{
+				Parameters: []string{"x"},
+				Body: &ast.SequenceNode{
+					Statements: []ast.Node{
+						&ast.BlockNode{
+							Body: &ast.SequenceNode{
+								Statements: []ast.Node{
+									&ast.ReturnNode{Expression: &ast.VariableNode{Name: "x"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Class: pile.ClassToObject(objectClass),
+	}
+
+	// Compile the method
+	compiler := NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	method, _, err := compiler.Compile(methodNode)
+	if err != nil {
+		t.Fatalf("Error compiling: %v", err)
+	}
+
+	// The method itself returns normally
+	if method.Bytecodes[len(method.Bytecodes)-1] != bytecode.RETURN_STACK_TOP {
+		t.Errorf("Expected the method to end with RETURN_STACK_TOP, got %v", method.Bytecodes)
+	}
+
+	outerBlock := pile.ObjectToCompiledBlock(method.Literals[0])
+	innerBlock := pile.ObjectToCompiledBlock(outerBlock.Literals[0])
+	if outerBlock.NonLocalReturn {
+		t.Errorf("Expected the outer block not to be marked, got %v", outerBlock)
+	}
+	if !innerBlock.NonLocalReturn {
+		t.Errorf("Expected the inner block to be marked, got %v", innerBlock)
+	}
+
+	expectedBytecodes := []byte{
+		bytecode.PUSH_OUTER_TEMPORARY_VARIABLE, 0, 0, 0, 1, 0, 0, 0, 0, // Push x
+		bytecode.BLOCK_RETURN, // Return x from foo
+	}
+	if string(innerBlock.Bytecodes) != string(expectedBytecodes) {
+		t.Errorf("Expected inner block bytecodes %v, got %v", expectedBytecodes, innerBlock.Bytecodes)
+	}
+}
@@ -0,0 +1,244 @@
+package compiler_test
+
+import (
+	"strings"
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/compiler"
+	"smalltalklsp/interpreter/parser"
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestCompileDiagnostics tests the errors and warnings found while compiling
+// methods of a class with the instance variables key and value
+func TestCompileDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []string
+		failed   bool
+	}{
+		{
+			name:   "clean method",
+			source: "key: aKey | old | old := key. key := aKey. ^old",
+		},
+		{
+			name:     "undeclared variable",
+			source:   "foo ^missing + 1",
+			expected: []string{"1:6: error: undeclared variable missing [undeclared-variable]"},
+			failed:   true,
+		},
+		{
+			name:     "undeclared assignment",
+			source:   "foo missing := 1",
+			expected: []string{"1:5: error: undeclared variable missing [undeclared-variable]"},
+			failed:   true,
+		},
+		{
+			name:   "global assignment",
+			source: "foo Missing := key. ^Missing",
+		},
+		{
+			name:     "assignment to argument",
+			source:   "foo: x x := 1. ^x",
+			expected: []string{"1:8: error: cannot assign to argument x [assignment-to-argument]"},
+			failed:   true,
+		},
+		{
+			name:     "assignment to block argument",
+			source:   "foo ^[:x | x := 1]",
+			expected: []string{"1:12: error: cannot assign to argument x [assignment-to-argument]"},
+			failed:   true,
+		},
+		{
+			name:     "assignment to argument of an enclosing scope",
+			source:   "foo: x ^[x := 1]",
+			expected: []string{"1:10: error: cannot assign to argument x [assignment-to-argument]"},
+			failed:   true,
+		},
+		{
+			name:   "temporaries of enclosing scopes",
+			source: "foo | a | ^[:x | [a := x + key]. a]",
+		},
+		{
+			name:     "unused temporary",
+			source:   "foo | a b | a := 1. ^key",
+			expected: []string{"1:1: warning: temporary a is never read [unused-temporary]", "1:1: warning: temporary b is never read [unused-temporary]"},
+		},
+		{
+			name:     "unused block temporary",
+			source:   "foo ^[| t | 1]",
+			expected: []string{"1:6: warning: temporary t is never read [unused-temporary]"},
+		},
+		{
+			name:     "temporary shadowing an instance variable",
+			source:   "foo | key | key := 1. ^key",
+			expected: []string{"1:1: warning: key shadows an instance variable [shadowed-variable]"},
+		},
+		{
+			name:     "argument shadowing an instance variable",
+			source:   "value: value ^value",
+			expected: []string{"1:1: warning: value shadows an instance variable [shadowed-variable]"},
+		},
+		{
+			name:     "block argument shadowing a temporary",
+			source:   "foo: x ^[:x | x]",
+			expected: []string{"1:9: warning: x shadows a variable of an enclosing scope [shadowed-variable]"},
+		},
+		{
+			name:     "duplicate temporary",
+			source:   "foo | a a | a := 1. ^a",
+			expected: []string{"1:1: error: a is already declared [duplicate-variable]"},
+			failed:   true,
+		},
+		{
+			name:     "temporary named like an argument",
+			source:   "foo: x | x | ^x",
+			expected: []string{"1:1: error: x is already declared [duplicate-variable]"},
+			failed:   true,
+		},
+		{
+			name:     "duplicate block temporary",
+			source:   "foo ^[:x | | x | x]",
+			expected: []string{"1:6: error: x is already declared [duplicate-variable]"},
+			failed:   true,
+		},
+		{
+			name:     "unreachable statements",
+			source:   "foo ^1. key := 2. value := 3",
+			expected: []string{"1:9: warning: statements after a return are never run [unreachable-code]"},
+		},
+		{
+			name:   "several problems",
+			source: "foo: x | t | x := y. ^1. 2",
+			expected: []string{
+				"1:19: error: undeclared variable y [undeclared-variable]",
+				"1:14: error: cannot assign to argument x [assignment-to-argument]",
+				"1:26: warning: statements after a return are never run [unreachable-code]",
+				"1:1: warning: temporary t is never read [unused-temporary]",
+			},
+			failed: true,
+		},
+	}
+
+	virtualMachine := vm.NewVM()
+	class := virtualMachine.NewClass("Pair", pile.ObjectToClass(virtualMachine.Globals["Object"]))
+	pile.AddClassInstanceVarName(class, "key")
+	pile.AddClassInstanceVarName(class, "value")
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node, err := parser.NewParser(test.source, pile.ClassToObject(class), virtualMachine).Parse()
+			if err != nil {
+				t.Fatalf("Error parsing %q: %v", test.source, err)
+			}
+
+			bytecodeCompiler := compiler.NewBytecodeCompiler(pile.ClassToObject(class))
+			bytecodeCompiler.Globals = virtualMachine
+			method, diagnostics, err := bytecodeCompiler.Compile(node)
+
+			var actual []string
+			for _, diagnostic := range diagnostics {
+				actual = append(actual, diagnostic.String())
+			}
+			if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("Expected diagnostics\n%s\ngot\n%s", strings.Join(test.expected, "\n"), strings.Join(actual, "\n"))
+			}
+
+			if test.failed {
+				if method != nil || err == nil {
+					t.Errorf("Expected no method and an error, got %v and %v", method, err)
+				}
+			} else if method == nil || err != nil {
+				t.Errorf("Expected a method and no error, got %v and %v", method, err)
+			}
+		})
+	}
+}
+
+// TestCompileRecoveredAST tests that the error nodes of an AST the parser
+// recovered from are compile errors, so that no half-parsed method is installed
+func TestCompileRecoveredAST(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"foo ^3 + . ^4", "1:5: error: expected primary expression, got \".\" [syntax-error]"},
+		{"foo ^(3 + ]", "1:6: error: expected primary expression, got \"]\" [syntax-error]"},
+	}
+
+	virtualMachine := vm.NewVM()
+	class := pile.ObjectToClass(virtualMachine.Globals["Object"])
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			node, parseDiagnostics := parser.NewParser(test.source, pile.ClassToObject(class), virtualMachine).ParseWithDiagnostics()
+			if len(parseDiagnostics) == 0 {
+				t.Fatalf("Expected %q not to parse", test.source)
+			}
+
+			method, diagnostics, err := compiler.NewBytecodeCompiler(pile.ClassToObject(class)).Compile(node)
+			if method != nil {
+				t.Errorf("Expected no method, got %v", method)
+			}
+			if _, ok := err.(*compiler.CompileError); !ok {
+				t.Fatalf("Expected a *CompileError, got %T %v", err, err)
+			}
+
+			found := false
+			for _, diagnostic := range diagnostics {
+				found = found || diagnostic.String() == test.expected
+			}
+			if !found {
+				t.Errorf("Expected the diagnostic %s, got %v", test.expected, diagnostics)
+			}
+		})
+	}
+}
+
+// TestCompileError tests the error returned for a method with errors
+func TestCompileError(t *testing.T) {
+	virtualMachine := vm.NewVM()
+	class := pile.ObjectToClass(virtualMachine.Globals["Object"])
+
+	node, err := parser.NewParser("foo\n\t^a + b", pile.ClassToObject(class), virtualMachine).Parse()
+	if err != nil {
+		t.Fatalf("Error parsing: %v", err)
+	}
+
+	_, _, err = compiler.NewBytecodeCompiler(pile.ClassToObject(class)).Compile(node)
+	compileError, ok := err.(*compiler.CompileError)
+	if !ok {
+		t.Fatalf("Expected a *CompileError, got %v", err)
+	}
+	if len(compileError.Errors) != 2 || compileError.Errors[0].Severity != ast.SeverityError {
+		t.Errorf("Expected 2 errors, got %v", compileError.Errors)
+	}
+
+	expected := "line 2, column 3: undeclared variable a (and 1 more errors)"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+// TestCompileUnsupported tests that code the bytecode set can't express is
+// reported instead of stopping the compiler
+func TestCompileUnsupported(t *testing.T) {
+	virtualMachine := vm.NewVM()
+	class := pile.ObjectToClass(virtualMachine.Globals["Object"])
+
+	node, err := parser.NewParser("foo ^super foo , thisContext", pile.ClassToObject(class), virtualMachine).Parse()
+	if err != nil {
+		t.Fatalf("Error parsing: %v", err)
+	}
+
+	_, diagnostics, err := compiler.NewBytecodeCompiler(pile.ClassToObject(class)).Compile(node)
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+	if len(diagnostics) != 2 || diagnostics[0].Code != compiler.CodeUnsupported || diagnostics[1].Code != compiler.CodeUnsupported {
+		t.Errorf("Expected super and thisContext to be unsupported, got %v", diagnostics)
+	}
+}
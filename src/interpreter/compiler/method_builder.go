@@ -85,6 +85,14 @@ func (mb *MethodBuilder) PushTemporaryVariable(offset int) *MethodBuilder {
 	return mb.addUint32(uint32(offset))
 }
 
+// PushOuterTemporaryVariable adds a PUSH_OUTER_TEMPORARY_VARIABLE bytecode for
+// the temporary at the given offset of the context depth levels out
+func (mb *MethodBuilder) PushOuterTemporaryVariable(depth, offset int) *MethodBuilder {
+	mb.bytecodes = append(mb.bytecodes, bytecode.PUSH_OUTER_TEMPORARY_VARIABLE)
+	mb.addUint32(uint32(depth))
+	return mb.addUint32(uint32(offset))
+}
+
 // PushSelf adds a PUSH_SELF bytecode
 func (mb *MethodBuilder) PushSelf() *MethodBuilder {
 	mb.bytecodes = append(mb.bytecodes, bytecode.PUSH_SELF)
@@ -103,6 +111,20 @@ func (mb *MethodBuilder) StoreTemporaryVariable(offset int) *MethodBuilder {
 	return mb.addUint32(uint32(offset))
 }
 
+// StoreOuterTemporaryVariable adds a STORE_OUTER_TEMPORARY_VARIABLE bytecode for
+// the temporary at the given offset of the context depth levels out
+func (mb *MethodBuilder) StoreOuterTemporaryVariable(depth, offset int) *MethodBuilder {
+	mb.bytecodes = append(mb.bytecodes, bytecode.STORE_OUTER_TEMPORARY_VARIABLE)
+	mb.addUint32(uint32(depth))
+	return mb.addUint32(uint32(offset))
+}
+
+// StoreGlobal adds a STORE_GLOBAL bytecode with the given binding literal index
+func (mb *MethodBuilder) StoreGlobal(index int) *MethodBuilder {
+	mb.bytecodes = append(mb.bytecodes, bytecode.STORE_GLOBAL)
+	return mb.addUint32(uint32(index))
+}
+
 // SendMessage adds a SEND_MESSAGE bytecode with the given selector index and argument count
 func (mb *MethodBuilder) SendMessage(selectorIndex, argCount int) *MethodBuilder {
 	mb.bytecodes = append(mb.bytecodes, bytecode.SEND_MESSAGE)
@@ -116,6 +138,12 @@ func (mb *MethodBuilder) ReturnStackTop() *MethodBuilder {
 	return mb
 }
 
+// BlockReturn adds a BLOCK_RETURN bytecode
+func (mb *MethodBuilder) BlockReturn() *MethodBuilder {
+	mb.bytecodes = append(mb.bytecodes, bytecode.BLOCK_RETURN)
+	return mb
+}
+
 // Jump adds a JUMP bytecode with the given target offset
 func (mb *MethodBuilder) Jump(target int) *MethodBuilder {
 	mb.bytecodes = append(mb.bytecodes, bytecode.JUMP)
@@ -146,6 +174,12 @@ func (mb *MethodBuilder) Duplicate() *MethodBuilder {
 	return mb
 }
 
+// CreateArray adds a CREATE_ARRAY bytecode with the given element count
+func (mb *MethodBuilder) CreateArray(size int) *MethodBuilder {
+	mb.bytecodes = append(mb.bytecodes, bytecode.CREATE_ARRAY)
+	return mb.addUint32(uint32(size))
+}
+
 // Go finalizes the method creation and adds it to the class's method dictionary
 // It takes the selector name as a parameter to eliminate the need for a separate Selector call
 func (mb *MethodBuilder) Go(selectorName string) *pile.Object {
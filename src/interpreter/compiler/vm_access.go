@@ -10,6 +10,12 @@ type VMAccess interface {
 	NewMethod(selector *pile.Object, class *pile.Class) *pile.Object
 }
 
+// GlobalAccess provides the bindings of global variables, the associations
+// compiled methods read the current value of a global from
+type GlobalAccess interface {
+	GlobalBinding(name string) *pile.Object
+}
+
 // DefaultVMAccess is the global VM access instance
 // This should be set by the VM during initialization
 var DefaultVMAccess VMAccess
@@ -0,0 +1,142 @@
+package definition
+
+import "fmt"
+
+// Location is a place in a source file
+type Location struct {
+	// File is the name of the file
+	File string
+
+	// Line is the line number, starting at 1
+	Line int
+}
+
+// String returns the location as file:line
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// Offset returns the location of a line inside a piece of text that
+// starts at this location, where line 1 is the first line of the text
+func (l Location) Offset(line int) Location {
+	return Location{File: l.File, Line: l.Line + line - 1}
+}
+
+// Error is an error in a source file
+type Error struct {
+	// Location is where the error was found
+	Location Location
+
+	// Message describes the error
+	Message string
+}
+
+// Error implements the error interface, prefixing the message with the location
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Location, e.Message)
+}
+
+// Errorf creates an error at a location
+func Errorf(location Location, format string, args ...interface{}) error {
+	return &Error{Location: location, Message: fmt.Sprintf(format, args...)}
+}
+
+// Class is the definition of a class
+type Class struct {
+	// Name is the name of the class
+	Name string
+
+	// Superclass is the name of the superclass, empty for a root class
+	Superclass string
+
+	// InstanceVariableNames are the names of the instance variables
+	InstanceVariableNames []string
+
+	// ClassVariableNames are the names of the class variables
+	ClassVariableNames []string
+
+	// ClassInstanceVariableNames are the names of the instance variables of the metaclass
+	ClassInstanceVariableNames []string
+
+	// PoolDictionaries are the names of the shared pools
+	PoolDictionaries []string
+
+	// Package is the package or category the class belongs to
+	Package string
+
+	// Comment is the class comment
+	Comment string
+
+	// Location is where the class is defined
+	Location Location
+}
+
+// Method is the definition of a method
+type Method struct {
+	// ClassName is the name of the class the method belongs to
+	ClassName string
+
+	// ClassSide is true for methods of the metaclass
+	ClassSide bool
+
+	// Category is the protocol the method is filed under
+	Category string
+
+	// Selector is the selector of the method
+	Selector string
+
+	// Source is the source code of the method, starting with its pattern
+	Source string
+
+	// Location is where the source code of the method starts
+	Location Location
+}
+
+// Expression is a top-level expression to evaluate, such as a doIt in a chunk file
+type Expression struct {
+	// Source is the source code of the expression
+	Source string
+
+	// Location is where the source code of the expression starts
+	Location Location
+}
+
+// Definitions are the classes, methods and expressions read from source files
+type Definitions struct {
+	// Classes are the class definitions in the order they were read
+	Classes []*Class
+
+	// Methods are the method definitions in the order they were read
+	Methods []*Method
+
+	// Expressions are the top-level expressions in the order they were read
+	Expressions []*Expression
+}
+
+// Class returns the definition of the class with the given name, or nil
+func (d *Definitions) Class(name string) *Class {
+	for _, class := range d.Classes {
+		if class.Name == name {
+			return class
+		}
+	}
+	return nil
+}
+
+// MethodsOf returns the methods of one side of a class in the order they were read
+func (d *Definitions) MethodsOf(className string, classSide bool) []*Method {
+	var methods []*Method
+	for _, method := range d.Methods {
+		if method.ClassName == className && method.ClassSide == classSide {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// Add appends the classes, methods and expressions of other
+func (d *Definitions) Add(other *Definitions) {
+	d.Classes = append(d.Classes, other.Classes...)
+	d.Methods = append(d.Methods, other.Methods...)
+	d.Expressions = append(d.Expressions, other.Expressions...)
+}
@@ -0,0 +1,135 @@
+// Package formatter prints ASTs back as Smalltalk source code in a
+// consistent layout, keeping the comments attached to the nodes
+package formatter
+
+import (
+	"strings"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/chunk"
+	"smalltalklsp/interpreter/parser"
+	"smalltalklsp/interpreter/vm"
+)
+
+// Options configures the layout of formatted source code
+type Options struct {
+	// Indent is the indentation of one level, such as a tab or four spaces
+	Indent string
+
+	// LineWidth is the number of columns lines are kept within by breaking
+	// keyword messages, cascades, blocks and brace arrays over several lines
+	LineWidth int
+
+	// CascadeOnSeparateLines puts every message of a cascade on its own
+	// line, even when the cascade would fit on one line
+	CascadeOnSeparateLines bool
+}
+
+// DefaultOptions returns the options matching the style of the Smalltalk sources
+func DefaultOptions() Options {
+	return Options{
+		Indent:                 "    ",
+		LineWidth:              80,
+		CascadeOnSeparateLines: false,
+	}
+}
+
+// Format returns the source code of an AST. The source the AST was parsed
+// from is used to keep the spelling of literals, such as 16r1F, and blank
+// lines between statements. It may be empty for an AST built in code.
+func Format(node ast.Node, source string, options Options) string {
+	p := &printer{options: options, source: source}
+
+	// Statements of a doIt go on separate lines with their comments
+	if _, ok := node.(*ast.MethodNode); !ok {
+		if _, ok := node.(*ast.SequenceNode); !ok {
+			node = &ast.SequenceNode{Statements: []ast.Node{node}, Source: node.Range()}
+		}
+	}
+
+	node.Accept(p)
+	return p.out.String()
+}
+
+// Formatter parses and formats source code with a set of options. All the
+// source code it formats is parsed with the same literal factory, so
+// formatting many methods doesn't boot a VM for every one of them.
+type Formatter struct {
+	// Options configures the layout of the formatted source code
+	Options Options
+
+	// Literals creates the literals of the parsed source code
+	Literals ast.LiteralFactory
+}
+
+// NewFormatter creates a formatter parsing with the literal factory
+func NewFormatter(options Options, literals ast.LiteralFactory) *Formatter {
+	return &Formatter{Options: options, Literals: literals}
+}
+
+// FormatMethod parses the source code of a method and formats it
+func (f *Formatter) FormatMethod(source string) (string, error) {
+	p := parser.NewParser(source, nil, f.Literals)
+	node, err := p.Parse()
+	if err != nil {
+		return "", err
+	}
+
+	return Format(node, source, f.Options), nil
+}
+
+// FormatExpression parses the source code of a doIt and formats it
+func (f *Formatter) FormatExpression(source string) (string, error) {
+	p := parser.NewParser(source, nil, f.Literals)
+	node, err := p.ParseExpression()
+	if err != nil {
+		return "", err
+	}
+
+	return Format(node, source, f.Options), nil
+}
+
+// FormatMethod parses the source code of a method and formats it with the
+// literals of the shared VM
+func FormatMethod(source string, options Options) (string, error) {
+	return NewFormatter(options, vm.DefaultVM).FormatMethod(source)
+}
+
+// FormatExpression parses the source code of a doIt and formats it with the
+// literals of the shared VM
+func FormatExpression(source string, options Options) (string, error) {
+	return NewFormatter(options, vm.DefaultVM).FormatExpression(source)
+}
+
+// FormatChunks formats the methods of a chunk file with the literals of the
+// shared VM. Class definitions, expressions and the text between chunks are
+// left as they are.
+func FormatChunks(file string, source string, options Options) (string, error) {
+	return NewFormatter(options, vm.DefaultVM).FormatChunks(file, source)
+}
+
+// FormatChunks formats the methods of a chunk file. Class definitions,
+// expressions and the text between chunks are left as they are.
+func (f *Formatter) FormatChunks(file string, source string) (string, error) {
+	var out strings.Builder
+	position := 0
+
+	for _, method := range chunk.MethodChunks(chunk.Split(file, source)) {
+		formatted, err := f.FormatMethod(method.Text)
+		if err != nil {
+			return "", chunk.LocatedError(method, err)
+		}
+
+		// Files that don't escape the ! in strings and comments are kept that way
+		if source[method.Start:method.End] != method.Text {
+			formatted = chunk.Escape(formatted)
+		}
+
+		out.WriteString(source[position:method.Start])
+		out.WriteString(formatted)
+		position = method.End
+	}
+	out.WriteString(source[position:])
+
+	return out.String(), nil
+}
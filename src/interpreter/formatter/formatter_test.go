@@ -0,0 +1,214 @@
+package formatter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/chunk"
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestFormatMethod tests the layout of formatted methods
+func TestFormatMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "comments and temporaries",
+			source:   "foo: x   \"Answer x\"  |a|  a := x+1 .  \"trailing\"\n\n\n  ^a",
+			expected: "foo: x\n    \"Answer x\"\n    | a |\n    a := x + 1. \"trailing\"\n\n    ^a",
+		},
+		{
+			name:     "literals keep their spelling",
+			source:   "foo ^#(1 $a #bar baz: 'it''s') , 16r1F printString , 1.5e3",
+			expected: "foo\n    ^#(1 $a #bar baz: 'it''s') , 16r1F printString , 1.5e3",
+		},
+		{
+			name:     "parentheses only where needed",
+			source:   "foo ^((a foo) + (b bar: 1)) baz: (c + (d * 2))",
+			expected: "foo\n    ^a foo + (b bar: 1) baz: c + (d * 2)",
+		},
+		{
+			name:     "long keyword message",
+			source:   "foo self at: aVeryLongIndexName + anotherLongName put: (someValue bitShift: -16) ifAbsent: nil",
+			expected: "foo\n    self\n        at: aVeryLongIndexName + anotherLongName\n        put: (someValue bitShift: -16)\n        ifAbsent: nil",
+		},
+		{
+			name:     "short block stays on one line",
+			source:   "foo (x > 0) ifTrue: [ ^x ]",
+			expected: "foo\n    x > 0 ifTrue: [^x]",
+		},
+		{
+			name:     "block with several statements",
+			source:   "foo 1 to: 3 do: [:i | |t| t := i * 2. self add: t]",
+			expected: "foo\n    1 to: 3 do: [:i |\n        | t |\n        t := i * 2.\n        self add: t]",
+		},
+		{
+			name:     "cascade",
+			source:   "foo Transcript show: 'a'; show: 'b' , 'c'; cr",
+			expected: "foo\n    Transcript show: 'a'; show: 'b' , 'c'; cr",
+		},
+		{
+			name:     "pragma and dynamic array",
+			source:   "foo <primitive: 60> ^{1. self bar}",
+			expected: "foo\n    <primitive: 60>\n    ^{1. self bar}",
+		},
+		{
+			name:     "block comments",
+			source:   "foo ^[\"nothing\"] value: [:x | \"first\"\n x]",
+			expected: "foo\n    ^[\"nothing\"] value: [:x |\n        \"first\"\n        x]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := FormatMethod(test.source, DefaultOptions())
+			if err != nil {
+				t.Fatalf("Error formatting %q: %v", test.source, err)
+			}
+			if formatted != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, formatted)
+			}
+		})
+	}
+}
+
+// TestFormatOptions tests formatting with options other than the defaults
+func TestFormatOptions(t *testing.T) {
+	options := Options{Indent: "\t", LineWidth: 30, CascadeOnSeparateLines: true}
+
+	formatted, err := FormatMethod("foo | a | a := OrderedCollection new add: 1; yourself. ^a", options)
+	if err != nil {
+		t.Fatalf("Error formatting method: %v", err)
+	}
+
+	expected := "foo\n\t| a |\n\ta := OrderedCollection new\n\t\tadd: 1;\n\t\tyourself.\n\t^a"
+	if formatted != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
+
+// TestFormatExpression tests formatting a doIt
+func TestFormatExpression(t *testing.T) {
+	formatted, err := FormatExpression("| x | \"start\" x := 3 .   x printString", DefaultOptions())
+	if err != nil {
+		t.Fatalf("Error formatting expression: %v", err)
+	}
+
+	expected := "| x |\n\"start\"\nx := 3.\nx printString"
+	if formatted != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
+
+// TestFormatBuiltAST tests formatting an AST that was not parsed from source
+func TestFormatBuiltAST(t *testing.T) {
+	node := &ast.MessageSendNode{
+		Receiver: &ast.LiteralNode{Value: pile.MakeIntegerImmediate(3)},
+		Selector: "+",
+		Arguments: []ast.Node{
+			&ast.MessageSendNode{
+				Receiver:  &ast.VariableNode{Name: "x"},
+				Selector:  "max:",
+				Arguments: []ast.Node{&ast.LiteralNode{Value: pile.MakeCharacterImmediate('a')}},
+			},
+		},
+	}
+
+	formatted := Format(node, "", DefaultOptions())
+	if formatted != "3 + (x max: $a)" {
+		t.Errorf("Expected 3 + (x max: $a), got %q", formatted)
+	}
+}
+
+// TestFormatChunks tests that only the methods of a chunk file are formatted
+func TestFormatChunks(t *testing.T) {
+	source := "Object subclass: #Foo\n    instanceVariableNames: 'a'!\n\n!Foo methodsFor: 'accessing'!\na   ^a!\n\nshout ^'hi!!'! !\n\nFoo  new  a!\n"
+
+	formatted, err := FormatChunks("Foo.st", source, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Error formatting chunks: %v", err)
+	}
+
+	expected := "Object subclass: #Foo\n    instanceVariableNames: 'a'!\n\n!Foo methodsFor: 'accessing'!\na\n    ^a!\n\nshout\n    ^'hi!!'! !\n\nFoo  new  a!\n"
+	if formatted != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, formatted)
+	}
+
+	_, err = FormatChunks("Foo.st", "!Foo methodsFor: 'a'!\nfoo\n  ^(1!\n! !", DefaultOptions())
+	expectedError := "Foo.st:3: expected closing parenthesis, got end of input"
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+}
+
+// countingLiterals is a literal factory counting the strings it creates
+type countingLiterals struct {
+	*vm.VM
+	strings int
+}
+
+// NewString implements ast.LiteralFactory
+func (l *countingLiterals) NewString(value string) *pile.Object {
+	l.strings++
+	return l.VM.NewString(value)
+}
+
+// TestFormatterLiterals tests that a formatter parses all the methods of
+// chunk files with its literal factory
+func TestFormatterLiterals(t *testing.T) {
+	literals := &countingLiterals{VM: vm.DefaultVM}
+	f := NewFormatter(DefaultOptions(), literals)
+
+	source := "!Foo methodsFor: 'a'!\na ^'a'! !\n!Foo methodsFor: 'b'!\nb ^'b'! !\n"
+	for _, file := range []string{"A.st", "B.st"} {
+		if _, err := f.FormatChunks(file, source); err != nil {
+			t.Fatalf("Error formatting chunks: %v", err)
+		}
+	}
+
+	if literals.strings != 4 {
+		t.Errorf("Expected the formatter to create 4 strings, got %d", literals.strings)
+	}
+}
+
+// TestFormatSourceFiles tests that the methods of the Smalltalk sources
+// still parse after formatting and that formatting again changes nothing
+func TestFormatSourceFiles(t *testing.T) {
+	files, err := filepath.Glob("../../*.st")
+	if err != nil {
+		t.Fatalf("Error listing source files: %v", err)
+	}
+	if len(files) == 0 {
+		t.Skip("No source files found")
+	}
+
+	for _, file := range files {
+		source, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Error reading %s: %v", file, err)
+		}
+
+		for _, method := range chunk.MethodChunks(chunk.Split(file, string(source))) {
+			formatted, err := FormatMethod(method.Text, DefaultOptions())
+			if err != nil {
+				// Some of the sources have methods that don't parse yet
+				continue
+			}
+
+			again, err := FormatMethod(formatted, DefaultOptions())
+			if err != nil {
+				t.Errorf("%s:%d: formatted method doesn't parse: %v\n%s", file, method.Location.Line, err, formatted)
+				continue
+			}
+			if again != formatted {
+				t.Errorf("%s:%d: formatting again changed the method:\n%s\nto:\n%s", file, method.Location.Line, formatted, again)
+			}
+		}
+	}
+}
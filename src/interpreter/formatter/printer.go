@@ -0,0 +1,821 @@
+package formatter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/pile"
+)
+
+// Precedence of expressions, an operand needs parentheses when its
+// precedence is higher than its position allows
+const (
+	precedencePrimary = iota
+	precedenceUnary
+	precedenceBinary
+	precedenceKeyword
+	precedenceStatement // Cascades, assignments and returns
+)
+
+// printer prints an AST as source code, it implements the ast.Visitor interface
+type printer struct {
+	// options are the layout options
+	options Options
+
+	// source is the source code the AST was parsed from, or empty
+	source string
+
+	// out is the source code printed so far
+	out strings.Builder
+
+	// level is the indentation level of new lines
+	level int
+
+	// flat is true when everything is printed on one line, which is used
+	// to measure whether a node fits on the current line
+	flat bool
+
+	// broken is set when a node printed flat needs several lines, because
+	// of a block with several statements or a comment with line breaks
+	broken bool
+}
+
+// write appends text to the output
+func (p *printer) write(text string) {
+	p.out.WriteString(text)
+}
+
+// newline starts a new line at the current indentation level
+func (p *printer) newline() {
+	p.write("\n")
+	for i := 0; i < p.level; i++ {
+		p.write(p.options.Indent)
+	}
+}
+
+// startLine starts a new line unless nothing has been printed yet
+func (p *printer) startLine() {
+	if p.out.Len() > 0 {
+		p.newline()
+	}
+}
+
+// column returns the column at the end of the output, counting tabs as four columns
+func (p *printer) column() int {
+	text := p.out.String()
+	line := text[strings.LastIndex(text, "\n")+1:]
+	return width(line)
+}
+
+// width returns the number of columns of a line, counting characters rather
+// than bytes and tabs as four columns
+func width(line string) int {
+	return utf8.RuneCountInString(line) + 3*strings.Count(line, "\t")
+}
+
+// measure prints something on one line with a new printer, and returns the
+// text and whether it fits on the current line
+func (p *printer) measure(print func(flat *printer)) (string, bool) {
+	flat := &printer{options: p.options, source: p.source, flat: true}
+	print(flat)
+
+	text := flat.out.String()
+	return text, !flat.broken && p.column()+width(text) <= p.options.LineWidth
+}
+
+// comment prints a comment
+func (p *printer) comment(comment ast.Comment) {
+	if p.flat && strings.Contains(comment.Text, "\n") {
+		p.broken = true
+	}
+	p.write("\"" + comment.Text + "\"")
+}
+
+// print prints a node with the comments attached to it
+func (p *printer) print(node ast.Node) {
+	before, after := comments(node)
+	for _, comment := range before {
+		p.comment(comment)
+		p.write(" ")
+	}
+
+	node.Accept(p)
+
+	for _, comment := range after {
+		p.write(" ")
+		p.comment(comment)
+	}
+}
+
+// comments returns the comments attached to a node that come before it and
+// those that come after it or inside it. Blocks, brace arrays, sequences and
+// methods print the comments inside them themselves, so those are left out.
+func comments(node ast.Node) (before []ast.Comment, after []ast.Comment) {
+	commented, ok := node.(ast.Commented)
+	if !ok {
+		return nil, nil
+	}
+
+	source := node.Range()
+	for _, comment := range commented.NodeComments() {
+		inside := comment.Source.Start.Offset >= source.Start.Offset && comment.Source.End.Offset <= source.End.Offset
+		switch {
+		case comment.Source.End.Offset <= source.Start.Offset:
+			before = append(before, comment)
+		case inside && printsInnerComments(node):
+		default:
+			after = append(after, comment)
+		}
+	}
+
+	return before, after
+}
+
+// innerComments returns the comments attached to a node that are inside it
+func innerComments(node ast.Node) []ast.Comment {
+	var result []ast.Comment
+
+	source := node.Range()
+	for _, comment := range node.(ast.Commented).NodeComments() {
+		if comment.Source.Start.Offset >= source.Start.Offset && comment.Source.End.Offset <= source.End.Offset {
+			result = append(result, comment)
+		}
+	}
+
+	return result
+}
+
+// printsInnerComments returns true if the node prints the comments inside it
+func printsInnerComments(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.BlockNode, *ast.DynamicArrayNode, *ast.SequenceNode:
+		return true
+	}
+	return false
+}
+
+// statements prints statements on separate lines, each followed by a period
+// except the last one, keeping single blank lines of the source between them
+func (p *printer) statements(statements []ast.Node) {
+	previousEnd := -1
+
+	for i, statement := range statements {
+		before, after := comments(statement)
+
+		start := statement.Range().Start.Offset
+		if len(before) > 0 {
+			start = before[0].Source.Start.Offset
+		}
+		if p.blankLineBetween(previousEnd, start) {
+			p.write("\n")
+		}
+
+		p.startLine()
+		for _, comment := range before {
+			p.comment(comment)
+			p.newline()
+		}
+
+		statement.Accept(p)
+		if i < len(statements)-1 {
+			p.write(".")
+		}
+
+		previousEnd = statement.Range().End.Offset
+		for _, comment := range after {
+			p.write(" ")
+			p.comment(comment)
+			previousEnd = comment.Source.End.Offset
+		}
+	}
+}
+
+// flatStatements prints statements on one line separated by periods. A
+// comment before a statement is kept on a line of its own.
+func (p *printer) flatStatements(statements []ast.Node) {
+	for i, statement := range statements {
+		if before, _ := comments(statement); len(before) > 0 {
+			p.broken = true
+		}
+		if i > 0 {
+			p.write(". ")
+		}
+		p.print(statement)
+	}
+}
+
+// blankLineBetween returns true if there is a blank line between two offsets of the source
+func (p *printer) blankLineBetween(start int, end int) bool {
+	if p.source == "" || start < 0 || end > len(p.source) || start >= end {
+		return false
+	}
+
+	lines := strings.Split(p.source[start:end], "\n")
+	if len(lines) < 3 {
+		return false
+	}
+	for _, line := range lines[1 : len(lines)-1] {
+		if strings.TrimSpace(line) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// temporaries prints a declaration of temporaries such as | a b |
+func (p *printer) temporaries(names []string) {
+	p.write("| " + strings.Join(names, " ") + " |")
+}
+
+// VisitMethodNode prints the pattern of a method followed by its comments,
+// pragmas, temporaries and statements on separate lines
+func (p *printer) VisitMethodNode(node *ast.MethodNode) interface{} {
+	p.write(pattern(node.Selector, node.Parameters))
+
+	p.level++
+	for _, comment := range node.Comments.Comments {
+		p.newline()
+		p.comment(comment)
+	}
+	for _, pragma := range node.Pragmas {
+		p.newline()
+		p.print(pragma)
+	}
+	if len(node.Temporaries) > 0 {
+		p.newline()
+		p.temporaries(node.Temporaries)
+	}
+	if body, ok := node.Body.(*ast.SequenceNode); ok {
+		p.statements(body.Statements)
+		for _, comment := range body.Comments.Comments {
+			p.newline()
+			p.comment(comment)
+		}
+	}
+	p.level--
+
+	return nil
+}
+
+// pattern returns the pattern of a method, such as "at: index put: value"
+func pattern(selector string, parameters []string) string {
+	if len(parameters) == 0 {
+		return selector
+	}
+
+	if !strings.HasSuffix(selector, ":") {
+		return selector + " " + parameters[0]
+	}
+
+	var parts []string
+	for i, keyword := range keywords(selector) {
+		if i < len(parameters) {
+			parts = append(parts, keyword+" "+parameters[i])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// VisitPragmaNode prints a pragma such as <primitive: 60>
+func (p *printer) VisitPragmaNode(node *ast.PragmaNode) interface{} {
+	p.write("<")
+	if len(node.Arguments) == 0 {
+		p.write(node.Selector)
+	}
+	for i, keyword := range keywords(node.Selector) {
+		if i >= len(node.Arguments) {
+			break
+		}
+		if i > 0 {
+			p.write(" ")
+		}
+		p.write(keyword + " ")
+		p.print(node.Arguments[i])
+	}
+	p.write(">")
+
+	return nil
+}
+
+// VisitSequenceNode prints the temporaries and statements of a doIt
+func (p *printer) VisitSequenceNode(node *ast.SequenceNode) interface{} {
+	if p.flat {
+		if len(node.Temporaries) > 0 {
+			p.temporaries(node.Temporaries)
+			p.write(" ")
+		}
+		p.flatStatements(node.Statements)
+		return nil
+	}
+
+	if len(node.Temporaries) > 0 {
+		p.startLine()
+		p.temporaries(node.Temporaries)
+	}
+
+	p.statements(node.Statements)
+	for _, comment := range innerComments(node) {
+		p.startLine()
+		p.comment(comment)
+	}
+
+	return nil
+}
+
+// VisitReturnNode prints a return such as ^x
+func (p *printer) VisitReturnNode(node *ast.ReturnNode) interface{} {
+	p.write("^")
+	if node.Expression != nil {
+		p.print(node.Expression)
+	}
+	return nil
+}
+
+// VisitAssignmentNode prints an assignment such as x := 3
+func (p *printer) VisitAssignmentNode(node *ast.AssignmentNode) interface{} {
+	p.write(node.Variable + " := ")
+	p.print(node.Expression)
+	return nil
+}
+
+// VisitSelfNode prints self
+func (p *printer) VisitSelfNode(node *ast.SelfNode) interface{} {
+	p.write("self")
+	return nil
+}
+
+// VisitSuperNode prints super
+func (p *printer) VisitSuperNode(node *ast.SuperNode) interface{} {
+	p.write("super")
+	return nil
+}
+
+// VisitThisContextNode prints thisContext
+func (p *printer) VisitThisContextNode(node *ast.ThisContextNode) interface{} {
+	p.write("thisContext")
+	return nil
+}
+
+// VisitVariableNode prints the name of a variable
+func (p *printer) VisitVariableNode(node *ast.VariableNode) interface{} {
+	p.write(node.Name)
+	return nil
+}
+
+// VisitGlobalNode prints the name of a global
+func (p *printer) VisitGlobalNode(node *ast.GlobalNode) interface{} {
+	p.write(node.Name)
+	return nil
+}
+
+// VisitErrorNode prints the source code that could not be parsed as it is
+func (p *printer) VisitErrorNode(node *ast.ErrorNode) interface{} {
+	p.write(p.sourceOf(node))
+	return nil
+}
+
+// VisitLiteralNode prints a literal as it is spelled in the source, or
+// from its value for an AST built in code
+func (p *printer) VisitLiteralNode(node *ast.LiteralNode) interface{} {
+	if text := p.sourceOf(node); text != "" {
+		p.write(text)
+		return nil
+	}
+
+	p.write(literal(node.Value, false))
+	return nil
+}
+
+// sourceOf returns the source code of a node, or an empty string if unknown
+func (p *printer) sourceOf(node ast.Node) string {
+	source := node.Range()
+	if source.Start.Offset >= source.End.Offset || source.End.Offset > len(p.source) {
+		return ""
+	}
+	return p.source[source.Start.Offset:source.End.Offset]
+}
+
+// literal returns the source code of a literal value. Inside a literal
+// array the elements of nested arrays are written without the #.
+func literal(value *pile.Object, inArray bool) string {
+	switch {
+	case value == nil || pile.IsNilImmediate(value):
+		return "nil"
+	case pile.IsTrueImmediate(value):
+		return "true"
+	case pile.IsFalseImmediate(value):
+		return "false"
+	case pile.IsIntegerImmediate(value):
+		return strconv.FormatInt(pile.GetIntegerImmediate(value), 10)
+	case pile.IsFloatImmediate(value):
+		text := strconv.FormatFloat(pile.GetFloatImmediate(value), 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eIN") {
+			text += ".0"
+		}
+		return text
+	case pile.IsCharacterImmediate(value):
+		return "$" + string(pile.GetCharacterImmediate(value))
+	}
+
+	switch value.Type() {
+	case pile.OBJ_STRING:
+		return quote(pile.ObjectToString(value).GetValue())
+	case pile.OBJ_SYMBOL:
+		return symbol(pile.ObjectToSymbol(value).GetValue())
+	case pile.OBJ_SCALED_DECIMAL:
+		return pile.ObjectToScaledDecimal(value).String()
+	case pile.OBJ_BYTE_ARRAY:
+		bytes := pile.ObjectToByteArray(value)
+		elements := make([]string, bytes.Size())
+		for i := range elements {
+			elements[i] = strconv.Itoa(int(bytes.At(i)))
+		}
+		return "#[" + strings.Join(elements, " ") + "]"
+	case pile.OBJ_ARRAY:
+		array := pile.ObjectToArray(value)
+		elements := make([]string, array.Size())
+		for i := range elements {
+			elements[i] = literal(array.At(i), true)
+		}
+		if inArray {
+			return "(" + strings.Join(elements, " ") + ")"
+		}
+		return "#(" + strings.Join(elements, " ") + ")"
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// quote returns a string literal, doubling the quotes inside it
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// symbol returns a symbol literal, quoting it unless it is a valid selector
+func symbol(value string) string {
+	if isSelector(value) {
+		return "#" + value
+	}
+	return "#" + quote(value)
+}
+
+// isSelector returns true if the value is a unary, binary or keyword selector
+func isSelector(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	if strings.Trim(value, "!%&*+,-/<=>?@\\~|") == "" {
+		return true
+	}
+
+	for i, c := range value {
+		letter := unicode.IsLetter(c) || c == '_'
+		digit := c >= '0' && c <= '9'
+		if !letter && !(i > 0 && (digit || c == ':')) {
+			return false
+		}
+	}
+	return true
+}
+
+// VisitMessageSendNode prints a message send. Keyword messages that don't
+// fit on the line put every keyword on its own line, unless a block at the
+// end would still need several lines, in which case it starts on the line.
+func (p *printer) VisitMessageSendNode(node *ast.MessageSendNode) interface{} {
+	kind := selectorKind(node.Selector)
+	parts := keywords(node.Selector)
+	if kind != precedenceKeyword || p.flat || len(parts) != len(node.Arguments) {
+		p.operand(node.Receiver, kind, true)
+		p.write(" ")
+		p.messageTail(node)
+		return nil
+	}
+
+	if text, fits := p.measure(func(flat *printer) { node.Accept(flat) }); fits {
+		p.write(text)
+		return nil
+	}
+
+	last := len(node.Arguments) - 1
+	block, endsWithBlock := node.Arguments[last].(*ast.BlockNode)
+	for _, argument := range node.Arguments[:last] {
+		if _, isBlock := argument.(*ast.BlockNode); isBlock {
+			endsWithBlock = false
+		}
+	}
+
+	if endsWithBlock && p.needsLines(block, parts[last]) {
+		head, fits := p.measure(func(flat *printer) {
+			flat.operand(node.Receiver, kind, true)
+			for i, keyword := range parts[:last] {
+				flat.write(" " + keyword + " ")
+				flat.operand(node.Arguments[i], kind, false)
+			}
+			flat.write(" " + parts[last] + " [")
+		})
+		if fits {
+			p.write(strings.TrimSuffix(head, "["))
+			p.print(block)
+			return nil
+		}
+	}
+
+	// A single keyword with an argument other than a block gains nothing
+	// from a line of its own
+	if len(parts) == 1 && !endsWithBlock {
+		p.operand(node.Receiver, kind, true)
+		p.write(" ")
+		p.messageTail(node)
+		return nil
+	}
+
+	p.operand(node.Receiver, kind, true)
+	p.level++
+	for i, keyword := range parts {
+		if i >= len(node.Arguments) {
+			break
+		}
+		p.newline()
+		p.write(keyword + " ")
+		p.operand(node.Arguments[i], kind, false)
+	}
+	p.level--
+
+	return nil
+}
+
+// needsLines returns true if a block argument would need several lines even
+// when its keyword starts a new line
+func (p *printer) needsLines(block *ast.BlockNode, keyword string) bool {
+	p.level++
+	defer func() { p.level-- }()
+
+	indented := &printer{options: p.options, level: p.level}
+	indented.newline()
+	indented.write(keyword + " ")
+	_, fits := indented.measure(func(flat *printer) { flat.print(block) })
+	return !fits
+}
+
+// messageTail prints the selector and arguments of a message on one line
+func (p *printer) messageTail(node *ast.MessageSendNode) {
+	kind := selectorKind(node.Selector)
+	switch kind {
+	case precedenceUnary:
+		p.write(node.Selector)
+	case precedenceBinary:
+		p.write(node.Selector + " ")
+		if len(node.Arguments) > 0 {
+			p.operand(node.Arguments[0], kind, false)
+		}
+	default:
+		for i, keyword := range keywords(node.Selector) {
+			if i >= len(node.Arguments) {
+				break
+			}
+			if i > 0 {
+				p.write(" ")
+			}
+			p.write(keyword + " ")
+			p.operand(node.Arguments[i], kind, false)
+		}
+	}
+}
+
+// operand prints the receiver or an argument of a message of the given
+// precedence, with parentheses if the operand binds less tightly
+func (p *printer) operand(node ast.Node, kind int, receiver bool) {
+	allowed := kind
+	switch {
+	case kind == precedenceKeyword:
+		allowed = precedenceBinary
+	case kind == precedenceBinary && !receiver:
+		allowed = precedenceUnary
+	}
+
+	if precedence(node) <= allowed {
+		p.print(node)
+		return
+	}
+
+	p.write("(")
+	p.print(node)
+	p.write(")")
+}
+
+// precedence returns the precedence of an expression
+func precedence(node ast.Node) int {
+	switch n := node.(type) {
+	case *ast.MessageSendNode:
+		return selectorKind(n.Selector)
+	case *ast.CascadeNode, *ast.AssignmentNode, *ast.ReturnNode:
+		return precedenceStatement
+	}
+	return precedencePrimary
+}
+
+// selectorKind returns the precedence of the messages with a selector
+func selectorKind(selector string) int {
+	switch {
+	case strings.HasSuffix(selector, ":"):
+		return precedenceKeyword
+	case isSelector(selector) && strings.Trim(selector, "!%&*+,-/<=>?@\\~|") != "":
+		return precedenceUnary
+	}
+	return precedenceBinary
+}
+
+// keywords splits a keyword selector such as at:put: into its keywords
+func keywords(selector string) []string {
+	var result []string
+	for _, part := range strings.SplitAfter(selector, ":") {
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// VisitCascadeNode prints a cascade, with every message on its own line
+// if the options ask for it or the cascade doesn't fit on the line
+func (p *printer) VisitCascadeNode(node *ast.CascadeNode) interface{} {
+	if !p.flat {
+		if !p.options.CascadeOnSeparateLines {
+			if text, fits := p.measure(func(flat *printer) { node.Accept(flat) }); fits {
+				p.write(text)
+				return nil
+			}
+		}
+
+		p.cascadeReceiver(node)
+		p.level++
+		for i, message := range node.Messages {
+			p.newline()
+			p.cascadePart(node, message)
+			if i < len(node.Messages)-1 {
+				p.write(";")
+			}
+		}
+		p.level--
+		return nil
+	}
+
+	p.cascadeReceiver(node)
+	for i, message := range node.Messages {
+		if i > 0 {
+			p.write(";")
+		}
+		p.write(" ")
+		p.cascadePart(node, message)
+	}
+	return nil
+}
+
+// cascadeReceiver prints the receiver of a cascade. The first message is sent
+// to it without a semicolon, so a binary receiver needs parentheses when the
+// first message is unary.
+func (p *printer) cascadeReceiver(node *ast.CascadeNode) {
+	kind := precedenceKeyword
+	if len(node.Messages) > 0 && selectorKind(firstMessage(node, node.Messages[0]).Selector) == precedenceUnary {
+		kind = precedenceUnary
+	}
+	p.operand(node.Receiver, kind, true)
+}
+
+// firstMessage returns the message of a cascade part that is sent to the
+// receiver of the cascade
+func firstMessage(cascade *ast.CascadeNode, message *ast.MessageSendNode) *ast.MessageSendNode {
+	for message.Receiver != cascade.Receiver {
+		inner, ok := message.Receiver.(*ast.MessageSendNode)
+		if !ok {
+			break
+		}
+		message = inner
+	}
+	return message
+}
+
+// cascadePart prints the messages of a cascade part without the receiver
+// they are sent to
+func (p *printer) cascadePart(cascade *ast.CascadeNode, message *ast.MessageSendNode) {
+	if message.Receiver != cascade.Receiver {
+		if inner, ok := message.Receiver.(*ast.MessageSendNode); ok {
+			p.cascadePart(cascade, inner)
+			p.write(" ")
+		}
+	}
+
+	p.messageTail(message)
+	for _, comment := range message.Comments.Comments {
+		p.write(" ")
+		p.comment(comment)
+	}
+}
+
+// VisitBlockNode prints a block on one line if it fits and has at most one
+// statement, and otherwise with its statements on separate lines
+func (p *printer) VisitBlockNode(node *ast.BlockNode) interface{} {
+	var statements []ast.Node
+	if body, ok := node.Body.(*ast.SequenceNode); ok {
+		statements = body.Statements
+	}
+
+	if p.flat {
+		if len(statements) > 1 {
+			p.broken = true
+		}
+
+		p.write("[")
+		p.blockParameters(node)
+		if len(node.Parameters) > 0 {
+			p.write(" ")
+		}
+		if len(node.Temporaries) > 0 {
+			p.temporaries(node.Temporaries)
+			p.write(" ")
+		}
+		for _, comment := range innerComments(node) {
+			p.comment(comment)
+			if len(statements) > 0 {
+				p.write(" ")
+			}
+		}
+		p.flatStatements(statements)
+		p.write("]")
+		return nil
+	}
+
+	if text, fits := p.measure(func(flat *printer) { node.Accept(flat) }); fits {
+		p.write(text)
+		return nil
+	}
+
+	p.write("[")
+	p.blockParameters(node)
+	p.level++
+	if len(node.Temporaries) > 0 {
+		p.newline()
+		p.temporaries(node.Temporaries)
+	}
+	for _, comment := range innerComments(node) {
+		p.newline()
+		p.comment(comment)
+	}
+	p.statements(statements)
+	p.level--
+	p.write("]")
+
+	return nil
+}
+
+// blockParameters prints the parameters of a block, such as ":x :y |"
+func (p *printer) blockParameters(node *ast.BlockNode) {
+	for i, parameter := range node.Parameters {
+		if i > 0 {
+			p.write(" ")
+		}
+		p.write(":" + parameter)
+	}
+	if len(node.Parameters) > 0 {
+		p.write(" |")
+	}
+}
+
+// VisitDynamicArrayNode prints a brace array such as {a. b + 1}, with
+// its elements on separate lines if it doesn't fit on the line
+func (p *printer) VisitDynamicArrayNode(node *ast.DynamicArrayNode) interface{} {
+	if p.flat {
+		p.write("{")
+		for _, comment := range innerComments(node) {
+			p.comment(comment)
+			if len(node.Elements) > 0 {
+				p.write(" ")
+			}
+		}
+		p.flatStatements(node.Elements)
+		p.write("}")
+		return nil
+	}
+
+	if text, fits := p.measure(func(flat *printer) { node.Accept(flat) }); fits {
+		p.write(text)
+		return nil
+	}
+
+	p.write("{")
+	p.level++
+	for _, comment := range innerComments(node) {
+		p.newline()
+		p.comment(comment)
+	}
+	p.statements(node.Elements)
+	p.level--
+	p.write("}")
+
+	return nil
+}
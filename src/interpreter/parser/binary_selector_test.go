@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/vm"
+)
+
+// tokenTypeAndValue is a token without its source range
+type tokenTypeAndValue struct {
+	Type  TokenType
+	Value string
+}
+
+// TestTokenizeBinarySelectors tests that runs of operator characters become one binary selector token
+func TestTokenizeBinarySelectors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []tokenTypeAndValue
+	}{
+		{"a <= b", []tokenTypeAndValue{{TOKEN_IDENTIFIER, "a"}, {TOKEN_BINARY, "<="}, {TOKEN_IDENTIFIER, "b"}}},
+		{"a->b", []tokenTypeAndValue{{TOKEN_IDENTIFIER, "a"}, {TOKEN_BINARY, "->"}, {TOKEN_IDENTIFIER, "b"}}},
+		{"a ~= b", []tokenTypeAndValue{{TOKEN_IDENTIFIER, "a"}, {TOKEN_BINARY, "~="}, {TOKEN_IDENTIFIER, "b"}}},
+		{"a == b", []tokenTypeAndValue{{TOKEN_IDENTIFIER, "a"}, {TOKEN_BINARY, "=="}, {TOKEN_IDENTIFIER, "b"}}},
+		{"a \\\\ b", []tokenTypeAndValue{{TOKEN_IDENTIFIER, "a"}, {TOKEN_BINARY, "\\\\"}, {TOKEN_IDENTIFIER, "b"}}},
+		{"a // b", []tokenTypeAndValue{{TOKEN_IDENTIFIER, "a"}, {TOKEN_BINARY, "//"}, {TOKEN_IDENTIFIER, "b"}}},
+		{"a @ b % c & d", []tokenTypeAndValue{
+			{TOKEN_IDENTIFIER, "a"}, {TOKEN_BINARY, "@"}, {TOKEN_IDENTIFIER, "b"},
+			{TOKEN_BINARY, "%"}, {TOKEN_IDENTIFIER, "c"}, {TOKEN_BINARY, "&"}, {TOKEN_IDENTIFIER, "d"}}},
+		{"a ,, b", []tokenTypeAndValue{{TOKEN_IDENTIFIER, "a"}, {TOKEN_BINARY, ",,"}, {TOKEN_IDENTIFIER, "b"}}},
+		{"3+-4", []tokenTypeAndValue{{TOKEN_NUMBER, "3"}, {TOKEN_BINARY, "+"}, {TOKEN_NUMBER, "-4"}}},
+		{"a | b", []tokenTypeAndValue{{TOKEN_IDENTIFIER, "a"}, {TOKEN_SPECIAL, "|"}, {TOKEN_IDENTIFIER, "b"}}},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(tt.input, nil, nil)
+		if err := p.tokenize(); err != nil {
+			t.Fatalf("Error tokenizing '%s': %v", tt.input, err)
+		}
+
+		// The last token is always EOF
+		tokens := p.Tokens[:len(p.Tokens)-1]
+		if len(tokens) != len(tt.expected) {
+			t.Errorf("Expected %d tokens for '%s', got %d", len(tt.expected), tt.input, len(tokens))
+			continue
+		}
+		for i, token := range tokens {
+			if token.Type != tt.expected[i].Type || token.Value != tt.expected[i].Value {
+				t.Errorf("Expected token %d of '%s' to be %v, got %v", i, tt.input, tt.expected[i], token)
+			}
+		}
+	}
+}
+
+// TestParseBinarySelectors tests that multi-character binary selectors become message sends
+func TestParseBinarySelectors(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	for _, selector := range []string{"<=", ">=", "~=", "==", "->", "@", "%", "&", "\\\\", "//", ",", "|"} {
+		input := "a " + selector + " b " + selector + " c"
+		p := NewParser(input, nil, vmInstance)
+
+		node, err := p.ParseExpression()
+		if err != nil {
+			t.Errorf("Error parsing '%s': %v", input, err)
+			continue
+		}
+
+		// Binary messages are parsed left to right
+		outer, ok := node.(*ast.MessageSendNode)
+		if !ok || outer.Selector != selector {
+			t.Errorf("Expected outer %s message for '%s', got %#v", selector, input, node)
+			continue
+		}
+		inner, ok := outer.Receiver.(*ast.MessageSendNode)
+		if !ok || inner.Selector != selector {
+			t.Errorf("Expected inner %s message for '%s', got %#v", selector, input, outer.Receiver)
+		}
+	}
+}
+
+// TestParseBinaryMethodSelector tests parsing a method with a multi-character binary selector
+func TestParseBinaryMethodSelector(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("<= aNumber ^(self > aNumber) not", nil, vmInstance)
+	node, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing method: %v", err)
+	}
+
+	methodNode, ok := node.(*ast.MethodNode)
+	if !ok {
+		t.Fatalf("Expected MethodNode, got %T", node)
+	}
+	if methodNode.Selector != "<=" {
+		t.Errorf("Expected selector '<=', got '%s'", methodNode.Selector)
+	}
+	if len(methodNode.Parameters) != 1 || methodNode.Parameters[0] != "aNumber" {
+		t.Errorf("Expected parameters [aNumber], got %v", methodNode.Parameters)
+	}
+}
@@ -47,9 +47,13 @@ func TestDirectParseBlockValue(t *testing.T) {
 		return
 	}
 
-	// The block body can be a literal node directly or a message send node
-	// depending on how the parser is implemented
-	switch body := blockNode.Body.(type) {
+	// The block body is a sequence holding a single statement
+	sequenceNode, ok := blockNode.Body.(*ast.SequenceNode)
+	if !ok || len(sequenceNode.Statements) != 1 {
+		t.Fatalf("Expected SequenceNode with 1 statement as block body, got %#v", blockNode.Body)
+	}
+
+	switch body := sequenceNode.Statements[0].(type) {
 	case *ast.LiteralNode:
 		// Verify it's an integer with value 5
 		if !pile.IsIntegerImmediate(body.Value) {
@@ -61,6 +65,6 @@ func TestDirectParseBlockValue(t *testing.T) {
 			t.Errorf("Expected value 5, got %d", value)
 		}
 	default:
-		t.Fatalf("Expected LiteralNode as block body, got %T", blockNode.Body)
+		t.Fatalf("Expected LiteralNode as block statement, got %T", sequenceNode.Statements[0])
 	}
 }
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"testing"
+	"unsafe"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestParseCascade tests parsing cascades on keyword, binary and unary messages
+func TestParseCascade(t *testing.T) {
+	// Create a class for context
+	objectClass := pile.NewClass("Object", nil)
+	objectClass.ClassField = objectClass
+	classObj := (*pile.Object)(unsafe.Pointer(objectClass))
+
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	// Create a parser with the test input
+	p := NewParser("stream show: 'Loading file: ', filename; cr; + 1; foo bar", classObj, vmInstance)
+
+	// Parse the expression
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	// Check if it's a cascade node
+	cascadeNode, ok := node.(*ast.CascadeNode)
+	if !ok {
+		t.Fatalf("Expected CascadeNode, got %T", node)
+	}
+
+	// Check the receiver
+	receiver, ok := cascadeNode.Receiver.(*ast.VariableNode)
+	if !ok || receiver.Name != "stream" {
+		t.Fatalf("Expected receiver to be variable 'stream', got %#v", cascadeNode.Receiver)
+	}
+
+	// Check the messages
+	if len(cascadeNode.Messages) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(cascadeNode.Messages))
+	}
+
+	expectedSelectors := []string{"show:", "cr", "+", "bar"}
+	for i, message := range cascadeNode.Messages {
+		if message.Selector != expectedSelectors[i] {
+			t.Errorf("Expected message %d selector '%s', got '%s'", i, expectedSelectors[i], message.Selector)
+		}
+	}
+
+	// The first three messages are sent directly to the shared receiver
+	for i := 0; i < 3; i++ {
+		if cascadeNode.Messages[i].Receiver != cascadeNode.Receiver {
+			t.Errorf("Expected message %d to be sent to the cascade receiver", i)
+		}
+	}
+
+	// The keyword argument is a binary expression
+	argument, ok := cascadeNode.Messages[0].Arguments[0].(*ast.MessageSendNode)
+	if !ok || argument.Selector != "," {
+		t.Errorf("Expected show: argument to be a , message, got %#v", cascadeNode.Messages[0].Arguments[0])
+	}
+
+	// The last part chains bar onto foo sent to the receiver
+	inner, ok := cascadeNode.Messages[3].Receiver.(*ast.MessageSendNode)
+	if !ok || inner.Selector != "foo" || inner.Receiver != cascadeNode.Receiver {
+		t.Errorf("Expected bar to be sent to (stream foo), got %#v", cascadeNode.Messages[3].Receiver)
+	}
+}
+
+// TestParseCascadeOnMessageChain tests that the cascade receiver is the receiver of the last message
+func TestParseCascadeOnMessageChain(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	// Create a parser with the test input
+	p := NewParser("x foo bar; baz", nil, vmInstance)
+
+	// Parse the expression
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	cascadeNode, ok := node.(*ast.CascadeNode)
+	if !ok {
+		t.Fatalf("Expected CascadeNode, got %T", node)
+	}
+
+	// The receiver should be "x foo"
+	receiver, ok := cascadeNode.Receiver.(*ast.MessageSendNode)
+	if !ok || receiver.Selector != "foo" {
+		t.Fatalf("Expected receiver to be (x foo), got %#v", cascadeNode.Receiver)
+	}
+
+	if len(cascadeNode.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(cascadeNode.Messages))
+	}
+	if cascadeNode.Messages[0].Selector != "bar" || cascadeNode.Messages[1].Selector != "baz" {
+		t.Errorf("Expected messages bar and baz, got %s and %s",
+			cascadeNode.Messages[0].Selector, cascadeNode.Messages[1].Selector)
+	}
+}
+
+// TestParseCascadeErrors tests that malformed cascades are rejected
+func TestParseCascadeErrors(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	inputs := []string{
+		"x; foo", // no message before the semicolon
+		"x foo;", // no message after the semicolon
+	}
+
+	for _, input := range inputs {
+		p := NewParser(input, nil, vmInstance)
+		if _, err := p.ParseExpression(); err == nil {
+			t.Errorf("Expected error parsing '%s'", input)
+		}
+	}
+}
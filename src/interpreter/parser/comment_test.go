@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/vm"
+)
+
+// commentTexts returns the texts of the comments attached to a node
+func commentTexts(node ast.Node) []string {
+	var texts []string
+	for _, comment := range node.(ast.Commented).NodeComments() {
+		texts = append(texts, comment.Text)
+	}
+	return texts
+}
+
+// expectComments checks the texts of the comments attached to a node
+func expectComments(t *testing.T, what string, node ast.Node, expected ...string) {
+	t.Helper()
+	texts := commentTexts(node)
+	if len(texts) != len(expected) {
+		t.Errorf("Expected %d comments on %s, got %q", len(expected), what, texts)
+		return
+	}
+	for i := range expected {
+		if texts[i] != expected[i] {
+			t.Errorf("Expected comment %q on %s, got %q", expected[i], what, texts[i])
+		}
+	}
+}
+
+// TestMethodComments tests attaching method, leading and trailing comments
+func TestMethodComments(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	input := "foo: x\n" +
+		"\t\"Answer x plus one\"\n" +
+		"\t| y |\n" +
+		"\ty := x + 1. \"trailing\"\n" +
+		"\t\"leading\"\n" +
+		"\t^ y"
+
+	p := NewParser(input, nil, vmInstance)
+	node, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing method: %v", err)
+	}
+
+	method := node.(*ast.MethodNode)
+	expectComments(t, "the method", method, "Answer x plus one")
+
+	body := method.Body.(*ast.SequenceNode)
+	expectComments(t, "the assignment", body.Statements[0], "trailing")
+	expectComments(t, "the return", body.Statements[1], "leading")
+
+	// The range includes the double quotes
+	comment := method.Comments.Comments[0]
+	if comment.Source.Start.Offset != 8 || comment.Source.End.Offset != 27 {
+		t.Errorf("Expected method comment at 8-27, got %s", comment.Source)
+	}
+	if comment.Source.Start.Line != 2 || comment.Source.Start.Column != 2 {
+		t.Errorf("Expected method comment at line 2 column 2, got %s", comment.Source)
+	}
+}
+
+// TestNestedComments tests attaching comments inside blocks, messages and doIts
+func TestNestedComments(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	input := "\"first\" x foo: 1 \"between\" bar: [:a | \"in block\" a]. \"last\""
+
+	p := NewParser(input, nil, vmInstance)
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	send := node.(*ast.MessageSendNode)
+	expectComments(t, "the message", send, "first", "between", "last")
+
+	block := send.Arguments[1].(*ast.BlockNode)
+	statement := block.Body.(*ast.SequenceNode).Statements[0]
+	expectComments(t, "the block statement", statement, "in block")
+}
+
+// TestCommentsWithoutStatements tests that comments in empty blocks and
+// methods are attached to the block or method
+func TestCommentsWithoutStatements(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("foo \"nothing yet\" ^ [\"empty\"]", nil, vmInstance)
+	node, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing method: %v", err)
+	}
+
+	method := node.(*ast.MethodNode)
+	expectComments(t, "the method", method, "nothing yet")
+
+	ret := method.Body.(*ast.SequenceNode).Statements[0].(*ast.ReturnNode)
+	expectComments(t, "the block", ret.Expression, "empty")
+	expectComments(t, "the return", ret)
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"smalltalklsp/interpreter/ast"
+)
+
+// attachComments attaches the comments found while tokenizing to the nodes
+// of the AST. A comment before the first statement of a method is the method
+// comment and goes to the MethodNode. Any other comment goes to the nearest
+// node: the innermost node it appears in, the statement it trails on the same
+// line, or else the statement that follows it.
+func (p *Parser) attachComments(root ast.Node) {
+	if root == nil {
+		return
+	}
+
+	for _, comment := range p.Comments {
+		attachComment(root, comment)
+	}
+}
+
+// attachComment attaches a comment to the node it appears in or to the
+// innermost child of that node that contains it
+func attachComment(node ast.Node, comment ast.Comment) {
+	switch n := node.(type) {
+	case *ast.MethodNode:
+		for _, pragma := range n.Pragmas {
+			if contains(pragma, comment) {
+				attachComment(pragma, comment)
+				return
+			}
+		}
+
+		body, _ := n.Body.(*ast.SequenceNode)
+		if body == nil || len(body.Statements) == 0 ||
+			comment.Source.End.Offset <= body.Statements[0].Range().Start.Offset {
+			n.AttachComment(comment)
+			return
+		}
+		attachToStatements(body, body.Statements, comment)
+		return
+
+	case *ast.BlockNode:
+		if body, ok := n.Body.(*ast.SequenceNode); ok {
+			attachToStatements(n, body.Statements, comment)
+			return
+		}
+
+	case *ast.SequenceNode:
+		attachToStatements(n, n.Statements, comment)
+		return
+
+	case *ast.DynamicArrayNode:
+		attachToStatements(n, n.Elements, comment)
+		return
+	}
+
+	for _, child := range ast.Children(node) {
+		if contains(child, comment) {
+			attachComment(child, comment)
+			return
+		}
+	}
+
+	// The comment is between the parts of the node, such as the keywords of a message
+	if commented, ok := node.(ast.Commented); ok {
+		commented.AttachComment(comment)
+	}
+}
+
+// attachToStatements attaches a comment to one of the statements of a method,
+// block or doIt, or to the elements of a dynamic array. A comment that is not
+// inside a statement trails the statement before it on the same line, or else
+// leads the statement after it. Without statements it goes to the owner.
+func attachToStatements(owner ast.Commented, statements []ast.Node, comment ast.Comment) {
+	var previous, next ast.Node
+	for _, statement := range statements {
+		if statement == nil {
+			continue
+		}
+		if contains(statement, comment) {
+			attachComment(statement, comment)
+			return
+		}
+		if statement.Range().End.Offset <= comment.Source.Start.Offset {
+			previous = statement
+		} else if next == nil {
+			next = statement
+		}
+	}
+
+	var nearest ast.Node
+	switch {
+	case previous != nil && previous.Range().End.Line == comment.Source.Start.Line:
+		nearest = previous
+	case next != nil:
+		nearest = next
+	case previous != nil:
+		nearest = previous
+	}
+
+	if commented, ok := nearest.(ast.Commented); ok {
+		commented.AttachComment(comment)
+		return
+	}
+	owner.AttachComment(comment)
+}
+
+// contains returns true if the comment is inside the range of the node
+func contains(node ast.Node, comment ast.Comment) bool {
+	source := node.Range()
+	return source.Start.Offset <= comment.Source.Start.Offset && comment.Source.End.Offset <= source.End.Offset
+}
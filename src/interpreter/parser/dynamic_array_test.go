@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestParseDynamicArray tests parsing brace arrays
+func TestParseDynamicArray(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input    string
+		elements int
+	}{
+		{"{}", 0},
+		{"{1}", 1},
+		{"{1. 2 + 3. x foo}", 3},
+		{"{1. 2.}", 2},
+		{"{x := 3. {4}}", 2},
+	}
+
+	for _, test := range tests {
+		p := NewParser(test.input, nil, vmInstance)
+		node, err := p.ParseExpression()
+		if err != nil {
+			t.Fatalf("Error parsing '%s': %v", test.input, err)
+		}
+
+		arrayNode, ok := node.(*ast.DynamicArrayNode)
+		if !ok {
+			t.Fatalf("Expected DynamicArrayNode for '%s', got %T", test.input, node)
+		}
+		if len(arrayNode.Elements) != test.elements {
+			t.Errorf("Expected %d elements for '%s', got %d", test.elements, test.input, len(arrayNode.Elements))
+		}
+	}
+}
+
+// TestParseDynamicArrayElements tests the element expressions of a brace array
+func TestParseDynamicArrayElements(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("{x. y := 2. 3 + 4}", nil, vmInstance)
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+	arrayNode := node.(*ast.DynamicArrayNode)
+
+	if _, ok := arrayNode.Elements[0].(*ast.VariableNode); !ok {
+		t.Errorf("Expected first element to be a VariableNode, got %T", arrayNode.Elements[0])
+	}
+	if _, ok := arrayNode.Elements[1].(*ast.AssignmentNode); !ok {
+		t.Errorf("Expected second element to be an AssignmentNode, got %T", arrayNode.Elements[1])
+	}
+	if send, ok := arrayNode.Elements[2].(*ast.MessageSendNode); !ok || send.Selector != "+" {
+		t.Errorf("Expected third element to be a + message, got %#v", arrayNode.Elements[2])
+	}
+}
+
+// TestParseDynamicArrayErrors tests that malformed brace arrays are rejected
+func TestParseDynamicArrayErrors(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	inputs := []string{
+		"{1. 2", // missing closing brace
+		"{1 2}", // missing period between elements
+		"{. 1}", // empty element
+		"{^ 1}", // return is not an expression
+	}
+
+	for _, input := range inputs {
+		p := NewParser(input, nil, vmInstance)
+		if _, err := p.ParseExpression(); err == nil {
+			t.Errorf("Expected error parsing '%s'", input)
+		}
+	}
+}
@@ -39,203 +39,31 @@ func parseSmalltalkExpression(expression string, isMethod bool) (string, error)
 		return "", err
 	}
 
-	// Convert the node to JSON using our visitor
-	visitor := &jsonVisitor{}
-	jsonResult := visitor.visitNode(node)
-
-	// Return the JSON string
-	return jsonResult, nil
-}
-
-// jsonVisitor is a simplified visitor that converts AST nodes to JSON
-type jsonVisitor struct{}
-
-func (v *jsonVisitor) visitNode(node ast.Node) string {
-	switch n := node.(type) {
-	case *ast.MethodNode:
-		return v.visitMethodNode(n)
-	case *ast.ReturnNode:
-		return v.visitReturnNode(n)
-	case *ast.SelfNode:
-		return v.visitSelfNode(n)
-	case *ast.LiteralNode:
-		return v.visitLiteralNode(n)
-	case *ast.VariableNode:
-		return v.visitVariableNode(n)
-	case *ast.AssignmentNode:
-		return v.visitAssignmentNode(n)
-	case *ast.MessageSendNode:
-		return v.visitMessageSendNode(n)
-	case *ast.BlockNode:
-		return v.visitBlockNode(n)
-	default:
-		return fmt.Sprintf(`{"type": "Unknown", "value": "%T"}`, n)
-	}
-}
-
-func (v *jsonVisitor) visitMethodNode(node *ast.MethodNode) string {
-	// Convert body to JSON
-	bodyJSON := "null"
-	if node.Body != nil {
-		bodyJSON = v.visitNode(node.Body)
-	}
-
-	// Convert parameters to JSON array
-	paramsJSON := fmt.Sprintf(`["%s"]`, strings.Join(node.Parameters, `", "`))
-	if len(node.Parameters) == 0 {
-		paramsJSON = "[]"
-	}
-
-	// Convert temporaries to JSON array
-	tempsJSON := fmt.Sprintf(`["%s"]`, strings.Join(node.Temporaries, `", "`))
-	if len(node.Temporaries) == 0 {
-		tempsJSON = "[]"
-	}
-
-	return fmt.Sprintf(`{"type":"MethodNode","selector":"%s","parameters":%s,"temporaries":%s,"body":%s}`,
-		node.Selector, paramsJSON, tempsJSON, bodyJSON)
-}
-
-func (v *jsonVisitor) visitReturnNode(node *ast.ReturnNode) string {
-	// Convert expression to JSON
-	exprJSON := "null"
-	if node.Expression != nil {
-		exprJSON = v.visitNode(node.Expression)
-	}
-
-	return fmt.Sprintf(`{"type":"ReturnNode","expression":%s}`, exprJSON)
-}
-
-func (v *jsonVisitor) visitSelfNode(node *ast.SelfNode) string {
-	return `{"type":"SelfNode"}`
-}
-
-func (v *jsonVisitor) visitLiteralNode(node *ast.LiteralNode) string {
-	literalJSON := "null"
-	if node.Value != nil {
-		// Try to convert the literal value based on its type
-		if pile.IsIntegerImmediate(node.Value) {
-			literalJSON = fmt.Sprintf(`{"type":"Integer","value":%d}`,
-				pile.GetIntegerImmediate(node.Value))
-		} else if pile.IsTrueImmediate(node.Value) {
-			literalJSON = `{"type":"Boolean","value":true}`
-		} else if pile.IsFalseImmediate(node.Value) {
-			literalJSON = `{"type":"Boolean","value":false}`
-		} else if pile.IsNilImmediate(node.Value) {
-			literalJSON = `{"type":"Nil"}`
-		} else if pile.IsFloatImmediate(node.Value) {
-			literalJSON = fmt.Sprintf(`{"type":"Float","value":%f}`,
-				pile.GetFloatImmediate(node.Value))
-		} else if node.Value.Type() == pile.OBJ_STRING {
-			str := pile.ObjectToString(node.Value)
-			literalJSON = fmt.Sprintf(`{"type":"String","value":"%s"}`, escapeString(str.GetValue()))
-		} else if node.Value.Type() == pile.OBJ_SYMBOL {
-			sym := pile.ObjectToSymbol(node.Value)
-			literalJSON = fmt.Sprintf(`{"type":"Symbol","value":"%s"}`, escapeString(sym.GetValue()))
-		} else if node.Value.Type() == pile.OBJ_ARRAY {
-			array := pile.ObjectToArray(node.Value)
-			elements := make([]string, array.Size())
-			for i := 0; i < array.Size(); i++ {
-				elem := array.At(i)
-				if pile.IsIntegerImmediate(elem) {
-					elements[i] = fmt.Sprintf(`{"type":"Integer","value":%d}`,
-						pile.GetIntegerImmediate(elem))
-				} else if pile.IsTrueImmediate(elem) {
-					elements[i] = `{"type":"Boolean","value":true}`
-				} else if pile.IsFalseImmediate(elem) {
-					elements[i] = `{"type":"Boolean","value":false}`
-				} else if pile.IsNilImmediate(elem) {
-					elements[i] = `{"type":"Nil"}`
-				} else if elem.Type() == pile.OBJ_STRING {
-					str := pile.ObjectToString(elem)
-					escapedStr := escapeString(str.GetValue())
-					elements[i] = fmt.Sprintf(`{"type":"String","value":"%s"}`, escapedStr)
-				} else {
-					elements[i] = "null"
-				}
-			}
-			literalJSON = fmt.Sprintf(`{"type":"Array","elements":[%s]}`, strings.Join(elements, ","))
-		} else {
-			// For unknown types, just use a generic description
-			literalJSON = fmt.Sprintf(`{"type":"Object","objectType":%d}`, node.Value.Type())
-		}
-	}
-
-	return fmt.Sprintf(`{"type":"LiteralNode","value":%s}`, literalJSON)
-}
-
-func (v *jsonVisitor) visitVariableNode(node *ast.VariableNode) string {
-	return fmt.Sprintf(`{"type":"VariableNode","name":"%s"}`, node.Name)
-}
-
-func (v *jsonVisitor) visitAssignmentNode(node *ast.AssignmentNode) string {
-	// Convert expression to JSON
-	exprJSON := "null"
-	if node.Expression != nil {
-		exprJSON = v.visitNode(node.Expression)
-	}
-
-	return fmt.Sprintf(`{"type":"AssignmentNode","variable":"%s","expression":%s}`,
-		node.Variable, exprJSON)
-}
-
-func (v *jsonVisitor) visitMessageSendNode(node *ast.MessageSendNode) string {
-	// Convert receiver to JSON
-	receiverJSON := "null"
-	if node.Receiver != nil {
-		receiverJSON = v.visitNode(node.Receiver)
-	}
-
-	// Convert arguments to JSON array
-	argJSONs := make([]string, 0, len(node.Arguments))
-	for _, arg := range node.Arguments {
-		if arg != nil {
-			argJSONs = append(argJSONs, v.visitNode(arg))
-		} else {
-			argJSONs = append(argJSONs, "null")
-		}
-	}
-
-	argsJSON := "[]"
-	if len(argJSONs) > 0 {
-		argsJSON = fmt.Sprintf(`[%s]`, strings.Join(argJSONs, ","))
+	// Convert the node to JSON without the source ranges and comments
+	jsonResult, err := ast.EncodeJSON(node, goldenJSONOptions)
+	if err != nil {
+		return "", err
 	}
 
-	return fmt.Sprintf(`{"type":"MessageSendNode","receiver":%s,"selector":"%s","arguments":%s}`,
-		receiverJSON, node.Selector, argsJSON)
+	return string(jsonResult), nil
 }
 
-func (v *jsonVisitor) visitBlockNode(node *ast.BlockNode) string {
-	// Convert body to JSON
-	bodyJSON := "null"
-	if node.Body != nil {
-		bodyJSON = v.visitNode(node.Body)
-	}
+// goldenJSONOptions leave out what the expected JSON of the test files doesn't have
+var goldenJSONOptions = ast.JSONOptions{OmitRanges: true, OmitComments: true}
 
-	// Convert parameters to JSON array
-	paramsJSON := fmt.Sprintf(`["%s"]`, strings.Join(node.Parameters, `", "`))
-	if len(node.Parameters) == 0 {
-		paramsJSON = "[]"
+// decodeAndEncode decodes expected JSON into an AST and encodes it again,
+// which gives the same structure if the decoder reads everything the encoder writes
+func decodeAndEncode(expectedJSON string) (string, error) {
+	node, err := ast.DecodeJSON([]byte(expectedJSON), vm.NewVM())
+	if err != nil {
+		return "", err
 	}
 
-	// Convert temporaries to JSON array
-	tempsJSON := fmt.Sprintf(`["%s"]`, strings.Join(node.Temporaries, `", "`))
-	if len(node.Temporaries) == 0 {
-		tempsJSON = "[]"
+	jsonResult, err := ast.EncodeJSON(node, goldenJSONOptions)
+	if err != nil {
+		return "", err
 	}
-
-	return fmt.Sprintf(`{"type":"BlockNode","parameters":%s,"temporaries":%s,"body":%s}`,
-		paramsJSON, tempsJSON, bodyJSON)
-}
-
-// escapeString escapes special characters in a string for JSON
-func escapeString(s string) string {
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "\"", "\\\"")
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	s = strings.ReplaceAll(s, "\r", "\\r")
-	s = strings.ReplaceAll(s, "\t", "\\t")
-	return s
+	return string(jsonResult), nil
 }
 
 // areJSONEqual compares two JSON strings by parsing them into Go objects
@@ -359,7 +187,16 @@ func runFileBasedTests(t *testing.T, testFilePath string) {
 			}
 
 			if !equal {
-				t.Errorf("Unexpected JSON result for expression '%s'", expression)
+				t.Errorf("Unexpected JSON result for expression '%s':\n%s", expression, actualJSON)
+			}
+
+			// The expected AST must survive a round trip through the decoder
+			decodedJSON, err := decodeAndEncode(expectedJSON)
+			if err != nil {
+				t.Fatalf("Error decoding the expected JSON: %v", err)
+			}
+			if equal, _ := areJSONEqual(decodedJSON, expectedJSON); !equal {
+				t.Errorf("Decoding the expected JSON of '%s' gave:\n%s", expression, decodedJSON)
 			}
 		})
 	}
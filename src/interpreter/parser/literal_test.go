@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestParseSymbolLiterals tests parsing unary, keyword, binary and string symbols
+func TestParseSymbolLiterals(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"#foo", "foo"},
+		{"#foo:", "foo:"},
+		{"#at:put:", "at:put:"},
+		{"#+", "+"},
+		{"#->", "->"},
+		{"#|", "|"},
+		{"#'hello world'", "hello world"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(tt.input, nil, vmInstance)
+
+		node, err := p.ParseExpression()
+		if err != nil {
+			t.Errorf("Error parsing '%s': %v", tt.input, err)
+			continue
+		}
+
+		literalNode, ok := node.(*ast.LiteralNode)
+		if !ok || literalNode.Value.Type() != pile.OBJ_SYMBOL {
+			t.Errorf("Expected symbol literal for '%s', got %#v", tt.input, node)
+			continue
+		}
+		if got := pile.ObjectToSymbol(literalNode.Value).GetValue(); got != tt.want {
+			t.Errorf("Expected symbol %s for '%s', got %s", tt.want, tt.input, got)
+		}
+		if vmInstance.GetClass(literalNode.Value).Name != "Symbol" {
+			t.Errorf("Expected '%s' to be a Symbol", tt.input)
+		}
+	}
+}
+
+// TestParseSymbolArgument tests that symbols can be used as message arguments
+func TestParseSymbolArgument(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("dictionary at: #Foo put: #bar:", nil, vmInstance)
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	messageNode, ok := node.(*ast.MessageSendNode)
+	if !ok || messageNode.Selector != "at:put:" {
+		t.Fatalf("Expected at:put: message, got %#v", node)
+	}
+	for i, want := range []string{"Foo", "bar:"} {
+		literalNode, ok := messageNode.Arguments[i].(*ast.LiteralNode)
+		if !ok || pile.ObjectToSymbol(literalNode.Value).GetValue() != want {
+			t.Errorf("Expected argument %d to be #%s, got %#v", i, want, messageNode.Arguments[i])
+		}
+	}
+}
+
+// TestParseByteArrayLiteral tests that byte array literals create a ByteArray
+func TestParseByteArrayLiteral(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("#[1 2 255]", nil, vmInstance)
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	literalNode, ok := node.(*ast.LiteralNode)
+	if !ok || literalNode.Value.Type() != pile.OBJ_BYTE_ARRAY {
+		t.Fatalf("Expected byte array literal, got %#v", node)
+	}
+	if vmInstance.GetClass(literalNode.Value).Name != "ByteArray" {
+		t.Errorf("Expected class ByteArray, got %s", vmInstance.GetClass(literalNode.Value).Name)
+	}
+
+	byteArray := pile.ObjectToByteArray(literalNode.Value)
+	expected := []byte{1, 2, 255}
+	if byteArray.Size() != len(expected) {
+		t.Fatalf("Expected %d bytes, got %d", len(expected), byteArray.Size())
+	}
+	for i, b := range expected {
+		if byteArray.At(i) != b {
+			t.Errorf("Expected byte %d to be %d, got %d", i, b, byteArray.At(i))
+		}
+	}
+
+	// Bytes can be written in any radix
+	node, err = NewParser("#[16rFF 2r101 8r17]", nil, vmInstance).ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing radix bytes: %v", err)
+	}
+	byteArray = pile.ObjectToByteArray(node.(*ast.LiteralNode).Value)
+	expected = []byte{255, 5, 15}
+	if byteArray.Size() != len(expected) {
+		t.Fatalf("Expected %d bytes, got %d", len(expected), byteArray.Size())
+	}
+	for i, b := range expected {
+		if byteArray.At(i) != b {
+			t.Errorf("Expected radix byte %d to be %d, got %d", i, b, byteArray.At(i))
+		}
+	}
+}
+
+// TestParseLiteralErrors tests that malformed literals are rejected
+func TestParseLiteralErrors(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	inputs := []string{
+		"#[1 256]",   // byte out of range
+		"#[16r100]",  // radix byte out of range
+		"#[1.5]",     // not an integer
+		"#[1 x]",     // not a byte
+		"#[1 2",      // unterminated byte array
+		"#(1 2",      // unterminated array
+		"#(1 [2])",   // blocks are not literals
+		"#(1 #(2 3)", // unterminated outer array
+		"#",          // missing symbol
+	}
+
+	for _, input := range inputs {
+		p := NewParser(input, nil, vmInstance)
+		if _, err := p.ParseExpression(); err == nil {
+			t.Errorf("Expected error parsing '%s'", input)
+		}
+	}
+}
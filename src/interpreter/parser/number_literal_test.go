@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"math"
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestParseNumberLiterals tests that each number syntax produces the right literal object
+func TestParseNumberLiterals(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"42", "42"},
+		{"-5", "-5"},
+		{"16r1F", "31"},
+		{"2r1010", "10"},
+		{"-16rFF", "-255"},
+		{"36rZZ", "1295"},
+		{"2e3", "2000"},
+		{"3.5", "3.5"},
+		{"-0.25", "-0.25"},
+		{"1.25s2", "1.25s2"},
+		{"3.1s3", "3.100s3"},
+		{"1.25s", "1.25s2"},
+		{"7s", "7s0"},
+		{"$a", "$a"},
+		{"$ ", "$ "},
+		{"$'", "$'"},
+		{"$$", "$$"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(tt.input, nil, vmInstance)
+
+		node, err := p.ParseExpression()
+		if err != nil {
+			t.Errorf("Error parsing '%s': %v", tt.input, err)
+			continue
+		}
+
+		literalNode, ok := node.(*ast.LiteralNode)
+		if !ok {
+			t.Errorf("Expected LiteralNode for '%s', got %T", tt.input, node)
+			continue
+		}
+
+		if got := literalNode.Value.String(); got != tt.want {
+			t.Errorf("Expected '%s' to parse to %s, got %s", tt.input, tt.want, got)
+		}
+	}
+}
+
+// TestParseFloatExponentLiterals tests floats with exponents, which lose
+// their last bits of precision when stored as immediates
+func TestParseFloatExponentLiterals(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"1.5e-3", 0.0015},
+		{"1e-2", 0.01},
+		{"2.5e2", 250},
+		{"-1.5e-3", -0.0015},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(tt.input, nil, vmInstance)
+
+		node, err := p.ParseExpression()
+		if err != nil {
+			t.Errorf("Error parsing '%s': %v", tt.input, err)
+			continue
+		}
+
+		value := node.(*ast.LiteralNode).Value
+		if !pile.IsFloatImmediate(value) {
+			t.Errorf("Expected '%s' to be a float, got %s", tt.input, value)
+			continue
+		}
+		if got := pile.GetFloatImmediate(value); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("Expected '%s' to parse to %g, got %g", tt.input, tt.want, got)
+		}
+	}
+}
+
+// TestParseNumberLiteralClasses tests that number literals have the expected classes
+func TestParseNumberLiteralClasses(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input string
+		class string
+	}{
+		{"16r1F", "Integer"},
+		{"3.14", "Float"},
+		{"1.25s2", "ScaledDecimal"},
+		{"$a", "Character"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(tt.input, nil, vmInstance)
+
+		node, err := p.ParseExpression()
+		if err != nil {
+			t.Fatalf("Error parsing '%s': %v", tt.input, err)
+		}
+
+		literalNode := node.(*ast.LiteralNode)
+		class := vmInstance.GetClass(literalNode.Value)
+		if class.Name != tt.class {
+			t.Errorf("Expected '%s' to be a %s, got %s", tt.input, tt.class, class.Name)
+		}
+	}
+}
+
+// TestParseNegativeLiteralsAndMinus tests when a minus sign starts a negative literal
+func TestParseNegativeLiteralsAndMinus(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	// x - 1 is a subtraction even without spaces
+	p := NewParser("x-1", nil, vmInstance)
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+	messageNode, ok := node.(*ast.MessageSendNode)
+	if !ok || messageNode.Selector != "-" {
+		t.Fatalf("Expected subtraction, got %#v", node)
+	}
+
+	// A keyword argument may be negative
+	p = NewParser("x at: -1", nil, vmInstance)
+	node, err = p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+	messageNode, ok = node.(*ast.MessageSendNode)
+	if !ok || messageNode.Selector != "at:" {
+		t.Fatalf("Expected at: message, got %#v", node)
+	}
+	argument := messageNode.Arguments[0].(*ast.LiteralNode)
+	if pile.GetIntegerImmediate(argument.Value) != -1 {
+		t.Errorf("Expected argument -1, got %s", argument.Value)
+	}
+
+	// Inside literal arrays a minus sign before a digit is always negative
+	p = NewParser("#(1 -2 $c 3.5)", nil, vmInstance)
+	node, err = p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+	array := pile.ObjectToArray(node.(*ast.LiteralNode).Value)
+	expected := []string{"1", "-2", "$c", "3.5"}
+	if array.Size() != len(expected) {
+		t.Fatalf("Expected %d elements, got %d", len(expected), array.Size())
+	}
+	for i, want := range expected {
+		if got := array.At(i).String(); got != want {
+			t.Errorf("Expected element %d to be %s, got %s", i, want, got)
+		}
+	}
+}
+
+// TestParseNumberLiteralErrors tests that invalid number literals are rejected
+func TestParseNumberLiteralErrors(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	inputs := []string{
+		"2r102",                // digit out of range for the radix
+		"40r10",                // radix too large
+		"99999999999999999999", // too large for an integer
+		"$",                    // missing character
+	}
+
+	for _, input := range inputs {
+		p := NewParser(input, nil, vmInstance)
+		if _, err := p.ParseExpression(); err == nil {
+			t.Errorf("Expected error parsing '%s'", input)
+		}
+	}
+}
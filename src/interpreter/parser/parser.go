@@ -2,12 +2,23 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"smalltalklsp/interpreter/ast"
 	"smalltalklsp/interpreter/pile"
 )
 
+// VMAccess is the part of the virtual machine the parser uses for
+// creating literals
+type VMAccess interface {
+	ast.LiteralFactory
+}
+
 // Parser parses Smalltalk code into an AST
 type Parser struct {
 	// Input is the input string to parse
@@ -16,19 +27,15 @@ type Parser struct {
 	// Class is the class the method belongs to
 	Class *pile.Object
 
-	// VM is the virtual machine used for creating literals and accessing globals
-	VM interface {
-		NewInteger(value int64) *pile.Object
-		NewString(value string) *pile.Object
-		NewArray(size int) *pile.Object
-		GetGlobal(name string) *pile.Object
-	}
+	// VM is the virtual machine used for creating literals
+	VM VMAccess
 
 	// Position is the current position in the input
 	Position int
 
-	// CurrentChar is the current character being processed
-	CurrentChar byte
+	// CurrentChar is the current character being processed, decoded from
+	// the UTF-8 input at Position
+	CurrentChar rune
 
 	// Tokens are the tokens extracted from the input
 	Tokens []Token
@@ -38,6 +45,20 @@ type Parser struct {
 
 	// CurrentTokenIndex is the index of the current token
 	CurrentTokenIndex int
+
+	// Comments are the comments found in the input, they are attached to
+	// the nodes of the AST after parsing
+	Comments []ast.Comment
+
+	// lineStarts are the offsets at which each line of the input starts
+	lineStarts []int
+
+	// recovering is true when the parser reports errors as diagnostics
+	// and continues parsing instead of stopping at the first error
+	recovering bool
+
+	// diagnostics are the errors reported while recovering
+	diagnostics []ast.Diagnostic
 }
 
 // TokenType represents the type of a token
@@ -51,7 +72,12 @@ const (
 	TOKEN_SYMBOL
 	TOKEN_KEYWORD
 	TOKEN_SPECIAL
-	TOKEN_ASSIGNMENT // New token type for :=
+	TOKEN_ASSIGNMENT          // New token type for :=
+	TOKEN_CHARACTER           // Character literal such as $a, the value holds the character
+	TOKEN_BINARY              // Binary selector such as +, <= or ->
+	TOKEN_LITERAL_ARRAY_START // Opening #( of a literal array
+	TOKEN_BYTE_ARRAY_START    // Opening #[ of a byte array
+	TOKEN_ERROR               // Input that could not be tokenized, the value holds the error message
 	TOKEN_EOF
 )
 
@@ -62,15 +88,68 @@ type Token struct {
 
 	// Value is the value of the token
 	Value string
+
+	// Source is the range of the token in the input
+	Source ast.SourceRange
+}
+
+// Range returns the range of the token in the input
+func (t Token) Range() ast.SourceRange {
+	return t.Source
+}
+
+// String returns the token value for use in error messages
+func (t Token) String() string {
+	if t.Type == TOKEN_EOF {
+		return "end of input"
+	}
+	return strconv.Quote(t.Value)
+}
+
+// Diagnostic codes of parse errors
+const (
+	CodeInvalidToken       = "invalid-token"       // Input that is not a valid token
+	CodeInvalidLiteral     = "invalid-literal"     // Malformed or out of range literal
+	CodeUnexpectedToken    = "unexpected-token"    // Token that can't appear at this point
+	CodeExpectedExpression = "expected-expression" // Missing expression
+	CodeExpectedIdentifier = "expected-identifier" // Missing variable or parameter name
+	CodeExpectedSelector   = "expected-selector"   // Missing method selector
+	CodeExpectedPeriod     = "expected-period"     // Missing period between statements or elements
+	CodeMissingDelimiter   = "missing-delimiter"   // Missing closing parenthesis, bracket, brace or bar
+	CodeInvalidCascade     = "invalid-cascade"     // Cascade that doesn't follow a message send
+	CodeInvalidAssignment  = "invalid-assignment"  // Assignment to a pseudo-variable such as self
+	CodeInternal           = "internal-error"      // Failure of the parser itself
+)
+
+// ParseError is an error found while tokenizing or parsing the input
+type ParseError struct {
+	// Code identifies the kind of error
+	Code string
+
+	// Message describes the error
+	Message string
+
+	// Range is the range of the input the error applies to
+	Range ast.SourceRange
+}
+
+// Error implements the error interface, prefixing the message with the line and column
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Range.Start.Line, e.Range.Start.Column, e.Message)
+}
+
+// Diagnostic converts the error into an error diagnostic
+func (e *ParseError) Diagnostic() ast.Diagnostic {
+	return ast.Diagnostic{
+		Range:    e.Range,
+		Severity: ast.SeverityError,
+		Code:     e.Code,
+		Message:  e.Message,
+	}
 }
 
 // NewParser creates a new parser
-func NewParser(input string, class *pile.Object, vm interface {
-	NewInteger(value int64) *pile.Object
-	NewString(value string) *pile.Object
-	NewArray(size int) *pile.Object
-	GetGlobal(name string) *pile.Object
-}) *Parser {
+func NewParser(input string, class *pile.Object, vm VMAccess) *Parser {
 	p := &Parser{
 		Input:             input,
 		Class:             class,
@@ -81,7 +160,7 @@ func NewParser(input string, class *pile.Object, vm interface {
 	}
 
 	if len(input) > 0 {
-		p.CurrentChar = input[0]
+		p.CurrentChar, _ = utf8.DecodeRuneInString(input)
 	}
 
 	return p
@@ -96,88 +175,194 @@ func (p *Parser) Parse() (ast.Node, error) {
 	}
 
 	// Parse the method
-	return p.parseMethod()
+	node, err := p.parseMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	p.attachComments(node)
+	return node, nil
 }
 
-// ParseExpression parses the input and returns an AST
-func (p *Parser) ParseExpression() (ast.Node, error) {
-	// Tokenize the input
+// Tokenize tokenizes the input and returns the tokens, ending with an EOF token
+func (p *Parser) Tokenize() ([]Token, error) {
 	err := p.tokenize()
 	if err != nil {
 		return nil, err
 	}
 
+	return p.Tokens, nil
+}
+
+// TokenizeWithDiagnostics tokenizes the input like Tokenize, but instead of
+// stopping at the first error it reports every error as a diagnostic and
+// returns error tokens in place of the input that could not be tokenized
+func (p *Parser) TokenizeWithDiagnostics() ([]Token, []ast.Diagnostic) {
+	p.recovering = true
+
+	// When recovering, tokenize reports every error instead of returning it
+	p.tokenize()
+	return p.Tokens, p.diagnostics
+}
+
+// ParseMethodPattern parses only the selector and parameter names at the
+// start of a method, without parsing the method body
+func (p *Parser) ParseMethodPattern() (string, []string, error) {
+	// Tokenize the input
+	err := p.tokenize()
+	if err != nil {
+		return "", nil, err
+	}
+
 	// Initialize the current token
 	p.CurrentToken = p.Tokens[0]
 	p.CurrentTokenIndex = 0
 
-	// Check if the input starts with a return statement
-	if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "^" {
-		// Skip the return token
-		p.advanceToken()
+	return p.parseMethodSelector()
+}
 
-		// Parse the expression
-		expr, err := p.parseExpression()
-		if err != nil {
-			return nil, err
+// ParseWithDiagnostics parses the input as a method like Parse, but instead of
+// stopping at the first error it reports every error as a diagnostic and
+// returns a partial AST in which the code that could not be parsed is
+// replaced by ErrorNodes
+func (p *Parser) ParseWithDiagnostics() (ast.Node, []ast.Diagnostic) {
+	p.recovering = true
+
+	node, err := p.Parse()
+	return p.recoveredResult(node, err)
+}
+
+// ParseExpressionWithDiagnostics parses the input as a doIt like ParseExpression,
+// but instead of stopping at the first error it reports every error as a
+// diagnostic and returns a partial AST in which the code that could not be
+// parsed is replaced by ErrorNodes
+func (p *Parser) ParseExpressionWithDiagnostics() (ast.Node, []ast.Diagnostic) {
+	p.recovering = true
+
+	node, err := p.ParseExpression()
+	return p.recoveredResult(node, err)
+}
+
+// recoveredResult returns the AST and diagnostics of a parse in recovery mode,
+// turning an error the parser could not recover from into a diagnostic
+func (p *Parser) recoveredResult(node ast.Node, err error) (ast.Node, []ast.Diagnostic) {
+	if err != nil {
+		p.report(err)
+		node = &ast.ErrorNode{
+			Message: err.Error(),
+			Source: ast.SourceRange{
+				Start: p.positionAt(0),
+				End:   p.positionAt(len(p.Input)),
+			},
 		}
+	}
 
-		// Create a return node
-		return &ast.ReturnNode{
-			Expression: expr,
-		}, nil
+	return node, p.diagnostics
+}
+
+// ParseExpression parses the input as a doIt and returns an AST.
+// A single statement without temporaries is returned as is,
+// anything else is returned as a SequenceNode.
+func (p *Parser) ParseExpression() (ast.Node, error) {
+	// Tokenize the input
+	err := p.tokenize()
+	if err != nil {
+		return nil, err
 	}
 
-	// Check for assignment directly here
-	if p.isAssignment() {
-		// Get the variable name
-		variableName := p.CurrentToken.Value
-		
-		// Skip the variable name and :=
-		p.advanceToken() // Skip variable name
-		p.advanceToken() // Skip :=
-		
-		// Parse the expression to be assigned
-		expression, err := p.parseKeywordMessage()
-		if err != nil {
-			return nil, err
-		}
-		
-		// Create and return an assignment node
-		return &ast.AssignmentNode{
-			Variable: variableName,
-			Expression: expression,
-		}, nil
+	// Initialize the current token
+	p.CurrentToken = p.Tokens[0]
+	p.CurrentTokenIndex = 0
+
+	// Parse the temporaries and statements
+	sequence, err := p.parseBody()
+	if err != nil {
+		return nil, err
 	}
+	p.attachComments(sequence)
 
-	// Parse the expression
-	return p.parseKeywordMessage()
+	// Return a lone statement directly
+	if len(sequence.Temporaries) == 0 && len(sequence.Statements) == 1 {
+		return sequence.Statements[0], nil
+	}
+
+	return sequence, nil
 }
 
 // tokenize tokenizes the input
 func (p *Parser) tokenize() error {
+	// The input only needs to be tokenized once
+	if len(p.Tokens) > 0 {
+		return nil
+	}
+
+	// Depth of the literal arrays being tokenized, inside them
+	// a minus sign followed by a digit is always a negative number
+	literalArrayDepth := 0
+
 	for p.Position < len(p.Input) {
+		// Remember where the token starts
+		start := p.Position
+
 		// Skip whitespace
 		if p.isWhitespace(p.CurrentChar) {
 			p.advance()
 			continue
 		}
 
-		// Parse identifiers
+		// Parse identifiers, inside literal arrays keywords such as at:put: form one symbol
 		if p.isAlpha(p.CurrentChar) {
-			p.Tokens = append(p.Tokens, p.parseIdentifier())
+			if literalArrayDepth > 0 {
+				p.addToken(p.parseKeywordSequence(), start)
+			} else {
+				p.addToken(p.parseIdentifier(), start)
+			}
 			continue
 		}
 
 		// Parse numbers
 		if p.isDigit(p.CurrentChar) {
-			p.Tokens = append(p.Tokens, p.parseNumber())
+			p.addToken(p.parseNumber(), start)
+			continue
+		}
+
+		// Parse negative numbers
+		if p.isNegativeNumberStart(literalArrayDepth > 0) {
+			p.addToken(p.parseNumber(), start)
+			continue
+		}
+
+		// Parse binary selectors
+		if p.isBinaryCharacter(p.CurrentChar) {
+			p.addToken(p.parseBinarySelector(), start)
 			continue
 		}
 
 		// Parse special characters
 		if p.isSpecial(p.CurrentChar) {
-			p.Tokens = append(p.Tokens, p.parseSpecial())
+			token := p.parseSpecial()
+
+			// Keep track of nested literal arrays, where bare parentheses also start an array
+			if token.Value == "(" && literalArrayDepth > 0 {
+				literalArrayDepth++
+			} else if token.Value == ")" && literalArrayDepth > 0 {
+				literalArrayDepth--
+			}
+
+			p.addToken(token, start)
+			continue
+		}
+
+		// Parse characters
+		if p.CurrentChar == '$' {
+			token, err := p.parseCharacter()
+			if err != nil {
+				if err := p.addErrorToken(start, err); err != nil {
+					return err
+				}
+				continue
+			}
+			p.addToken(token, start)
 			continue
 		}
 
@@ -185,9 +370,12 @@ func (p *Parser) tokenize() error {
 		if p.CurrentChar == '\'' {
 			token, err := p.parseString()
 			if err != nil {
-				return err
+				if err := p.addErrorToken(start, err); err != nil {
+					return err
+				}
+				continue
 			}
-			p.Tokens = append(p.Tokens, token)
+			p.addToken(token, start)
 			continue
 		}
 
@@ -195,76 +383,366 @@ func (p *Parser) tokenize() error {
 		if p.CurrentChar == '#' {
 			token, err := p.parseSymbol()
 			if err != nil {
-				return err
+				if err := p.addErrorToken(start, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Keep track of literal arrays
+			if token.Type == TOKEN_LITERAL_ARRAY_START {
+				literalArrayDepth++
 			}
-			p.Tokens = append(p.Tokens, token)
+
+			p.addToken(token, start)
 			continue
 		}
 
-		// Skip comments
+		// Keep comments apart from the tokens
 		if p.CurrentChar == '"' {
 			err := p.skipComment()
 			if err != nil {
-				return err
+				// An unterminated comment runs to the end of the input,
+				// so there is nothing left to recover
+				err = p.tokenError(start, err)
+				if !p.recovering {
+					return err
+				}
+				p.report(err)
+				continue
 			}
+			p.Comments = append(p.Comments, ast.Comment{
+				Text: p.Input[start+1 : p.Position-1],
+				Source: ast.SourceRange{
+					Start: p.positionAt(start),
+					End:   p.positionAt(p.Position),
+				},
+			})
 			continue
 		}
 
 		// Unknown character
-		return fmt.Errorf("unknown character: %c", p.CurrentChar)
+		p.advance()
+		if err := p.addErrorToken(start, fmt.Errorf("unknown character: %c", p.charAt(start))); err != nil {
+			return err
+		}
 	}
 
 	// Add EOF token
-	p.Tokens = append(p.Tokens, Token{Type: TOKEN_EOF, Value: ""})
+	p.addToken(Token{Type: TOKEN_EOF, Value: ""}, p.Position)
+
+	return nil
+}
+
+// addToken sets the range of a token that starts at the given offset
+// and ends at the current position, and appends it to the tokens
+func (p *Parser) addToken(token Token, start int) {
+	token.Source = ast.SourceRange{
+		Start: p.positionAt(start),
+		End:   p.positionAt(p.Position),
+	}
+	p.Tokens = append(p.Tokens, token)
+}
+
+// addErrorToken handles an error found while tokenizing the input between the
+// given offset and the current position. When recovering, the error is reported
+// and an error token is added in place of the bad input, otherwise it is returned.
+func (p *Parser) addErrorToken(start int, err error) error {
+	err = p.tokenError(start, err)
+	if !p.recovering {
+		return err
+	}
+
+	p.report(err)
+	p.addToken(Token{Type: TOKEN_ERROR, Value: err.(*ParseError).Message}, start)
 
 	return nil
 }
 
+// tokenError converts an error found while tokenizing the input between
+// the given offset and the current position into a ParseError
+func (p *Parser) tokenError(start int, err error) error {
+	return &ParseError{
+		Code:    CodeInvalidToken,
+		Message: err.Error(),
+		Range: ast.SourceRange{
+			Start: p.positionAt(start),
+			End:   p.positionAt(p.Position),
+		},
+	}
+}
+
+// errorf creates a ParseError with the given code at the current token
+func (p *Parser) errorf(code string, format string, args ...interface{}) error {
+	return &ParseError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Range:   p.CurrentToken.Source,
+	}
+}
+
+// report adds an error to the diagnostics
+func (p *Parser) report(err error) {
+	parseError, ok := err.(*ParseError)
+	if !ok {
+		parseError = &ParseError{Code: CodeInternal, Message: err.Error(), Range: p.CurrentToken.Source}
+	}
+
+	p.diagnostics = append(p.diagnostics, parseError.Diagnostic())
+}
+
+// recoverFrom reports an error and skips the rest of the broken statement or
+// expression that started at the given position. It returns an ErrorNode
+// covering the skipped code and the token synchronize stopped at.
+func (p *Parser) recoverFrom(err error, start ast.Position) (*ast.ErrorNode, Token) {
+	p.report(err)
+	stop := p.synchronize()
+
+	message := err.Error()
+	if parseError, ok := err.(*ParseError); ok {
+		message = parseError.Message
+	}
+
+	return &ast.ErrorNode{
+		Message: message,
+		Source:  p.rangeFrom(start),
+	}, stop
+}
+
+// synchronize skips tokens after an error up to the end of the broken statement
+// or expression, skipping nested brackets as a whole. It stops before a period,
+// a closing bracket or the end of the input, and after a closing parenthesis or
+// brace or a ! chunk separator. It returns the token it stopped at.
+func (p *Parser) synchronize() Token {
+	depth := 0
+
+	for p.CurrentToken.Type != TOKEN_EOF {
+		token := p.CurrentToken
+
+		switch {
+		case token.Type == TOKEN_LITERAL_ARRAY_START || token.Type == TOKEN_BYTE_ARRAY_START:
+			depth++
+		case token.Type == TOKEN_SPECIAL && strings.Contains("([{", token.Value):
+			depth++
+		case depth > 0 && token.Type == TOKEN_SPECIAL && strings.Contains(")]}", token.Value):
+			depth--
+		case token.Type == TOKEN_SPECIAL && (token.Value == "." || token.Value == "]"):
+			return token
+		case token.Type == TOKEN_SPECIAL && (token.Value == ")" || token.Value == "}"),
+			depth == 0 && token.Type == TOKEN_BINARY && token.Value == "!":
+			p.advanceToken()
+			return token
+		}
+
+		p.advanceToken()
+	}
+
+	return p.CurrentToken
+}
+
+// positionAt converts a byte offset in the input into a position
+func (p *Parser) positionAt(offset int) ast.Position {
+	// Find the line starts the first time a position is needed
+	if p.lineStarts == nil {
+		p.lineStarts = []int{0}
+		for i := 0; i < len(p.Input); i++ {
+			if p.Input[i] == '\n' {
+				p.lineStarts = append(p.lineStarts, i+1)
+			}
+		}
+	}
+
+	// Find the last line starting at or before the offset
+	line := sort.Search(len(p.lineStarts), func(i int) bool {
+		return p.lineStarts[i] > offset
+	}) - 1
+
+	return ast.Position{
+		Offset: offset,
+		Line:   line + 1,
+		Column: offset - p.lineStarts[line] + 1,
+	}
+}
+
+// rangeFrom returns the range from the given start position to the end
+// of the last consumed token
+func (p *Parser) rangeFrom(start ast.Position) ast.SourceRange {
+	end := start
+	if last := p.CurrentTokenIndex - 1; last >= 0 && len(p.Tokens) > 0 {
+		if last >= len(p.Tokens) {
+			last = len(p.Tokens) - 1
+		}
+		if p.Tokens[last].Source.End.Offset > start.Offset {
+			end = p.Tokens[last].Source.End
+		}
+	}
+
+	return ast.SourceRange{Start: start, End: end}
+}
+
 // parseMethod parses a method
 func (p *Parser) parseMethod() (ast.Node, error) {
 	// Initialize the current token
 	p.CurrentToken = p.Tokens[0]
+	start := p.CurrentToken.Source.Start
 
-	// Parse the method selector
+	// Parse the method selector, when recovering the body is parsed even if the selector is broken
 	selector, parameters, err := p.parseMethodSelector()
+	if err != nil {
+		if !p.recovering {
+			return nil, err
+		}
+		p.report(err)
+	}
+
+	// Pragmas may come before and after the temporaries
+	bodyStart := p.CurrentToken.Source.Start
+	pragmas, err := p.parsePragmas()
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse temporary variables
 	temporaries, err := p.parseTemporaries()
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse the method body
-	body, err := p.parseStatements()
+	morePragmas, err := p.parsePragmas()
+	if err != nil {
+		return nil, err
+	}
+	pragmas = append(pragmas, morePragmas...)
+
+	// Parse the method body (statements)
+	body, err := p.parseBody()
 	if err != nil {
 		return nil, err
 	}
+	body.Temporaries = append(temporaries, body.Temporaries...)
+	body.Source = p.rangeFrom(bodyStart)
 
 	// Create the method node
 	methodNode := &ast.MethodNode{
 		Selector:    selector,
 		Parameters:  parameters,
-		Temporaries: temporaries,
+		Temporaries: body.Temporaries,
+		Pragmas:     pragmas,
 		Body:        body,
 		Class:       p.Class,
+		Source:      p.rangeFrom(start),
 	}
 
 	return methodNode, nil
 }
 
+// parsePragmas parses the pragmas at the start of a method body, such as
+// <primitive: 60 error: ec> or <category: 'accessing'>. When recovering, a
+// broken pragma is reported and skipped up to its closing >.
+func (p *Parser) parsePragmas() ([]*ast.PragmaNode, error) {
+	pragmas := []*ast.PragmaNode{}
+
+	for p.CurrentToken.Type == TOKEN_BINARY && p.CurrentToken.Value == "<" {
+		pragma, err := p.parsePragma()
+		if err != nil {
+			if !p.recovering {
+				return nil, err
+			}
+			p.report(err)
+
+			// Skip the rest of the pragma
+			for p.CurrentToken.Type != TOKEN_EOF && !(p.CurrentToken.Type == TOKEN_BINARY && p.CurrentToken.Value == ">") {
+				p.advanceToken()
+			}
+			p.advanceToken()
+			continue
+		}
+
+		pragmas = append(pragmas, pragma)
+	}
+
+	return pragmas, nil
+}
+
+// parsePragma parses a single pragma between < and >
+func (p *Parser) parsePragma() (*ast.PragmaNode, error) {
+	start := p.CurrentToken.Source.Start
+	p.advanceToken()
+
+	pragma := &ast.PragmaNode{Arguments: []ast.Node{}}
+
+	switch {
+	case p.CurrentToken.Type == TOKEN_IDENTIFIER && strings.HasSuffix(p.CurrentToken.Value, ":"):
+		// Parse each keyword and its argument
+		var keywordParts []string
+		for p.CurrentToken.Type == TOKEN_IDENTIFIER && strings.HasSuffix(p.CurrentToken.Value, ":") {
+			keywordParts = append(keywordParts, p.CurrentToken.Value)
+			p.advanceToken()
+
+			argument, err := p.parsePragmaArgument()
+			if err != nil {
+				return nil, err
+			}
+			pragma.Arguments = append(pragma.Arguments, argument)
+		}
+		pragma.Selector = strings.Join(keywordParts, "")
+
+	case p.CurrentToken.Type == TOKEN_IDENTIFIER:
+		pragma.Selector = p.CurrentToken.Value
+		p.advanceToken()
+
+	default:
+		return nil, p.errorf(CodeExpectedSelector, "expected pragma selector, got %v", p.CurrentToken)
+	}
+
+	// Check for the closing >
+	if p.CurrentToken.Type != TOKEN_BINARY || p.CurrentToken.Value != ">" {
+		return nil, p.errorf(CodeMissingDelimiter, "expected > to end pragma, got %v", p.CurrentToken)
+	}
+	p.advanceToken()
+
+	pragma.Source = p.rangeFrom(start)
+	return pragma, nil
+}
+
+// parsePragmaArgument parses the argument of a pragma keyword, which is a
+// literal or a name such as the error code variable of a primitive
+func (p *Parser) parsePragmaArgument() (ast.Node, error) {
+	if p.CurrentToken.Type == TOKEN_IDENTIFIER && !strings.HasSuffix(p.CurrentToken.Value, ":") {
+		if !IsPseudoVariable(p.CurrentToken.Value) {
+			variable := &ast.VariableNode{Name: p.CurrentToken.Value, Source: p.CurrentToken.Source}
+			p.advanceToken()
+			return variable, nil
+		}
+	}
+
+	start := p.CurrentToken.Source.Start
+	argument, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	literal, ok := argument.(*ast.LiteralNode)
+	if !ok {
+		return nil, &ParseError{
+			Code:    CodeInvalidLiteral,
+			Message: "expected literal as pragma argument",
+			Range:   p.rangeFrom(start),
+		}
+	}
+
+	return literal, nil
+}
+
 // parseMethodSelector parses a method selector
 func (p *Parser) parseMethodSelector() (string, []string, error) {
 	// Handle binary selectors
-	if p.CurrentToken.Type == TOKEN_SPECIAL {
+	if p.isBinarySelectorToken() {
 		selector := p.CurrentToken.Value
 		p.advanceToken()
 
 		// Parse the parameter
 		if p.CurrentToken.Type != TOKEN_IDENTIFIER {
-			return "", nil, fmt.Errorf("expected identifier, got %v", p.CurrentToken)
+			return "", nil, p.errorf(CodeExpectedIdentifier, "expected identifier, got %v", p.CurrentToken)
 		}
 
 		parameter := p.CurrentToken.Value
@@ -275,18 +753,24 @@ func (p *Parser) parseMethodSelector() (string, []string, error) {
 
 	// Handle keyword selectors
 	if p.CurrentToken.Type == TOKEN_IDENTIFIER && strings.HasSuffix(p.CurrentToken.Value, ":") {
-		selector := p.CurrentToken.Value
-		p.advanceToken()
+		var keywordParts []string
+		var parameters []string
 
-		// Parse the parameter
-		if p.CurrentToken.Type != TOKEN_IDENTIFIER {
-			return "", nil, fmt.Errorf("expected identifier, got %v", p.CurrentToken)
-		}
+		// Parse each keyword part and its parameter
+		for p.CurrentToken.Type == TOKEN_IDENTIFIER && strings.HasSuffix(p.CurrentToken.Value, ":") {
+			keywordParts = append(keywordParts, p.CurrentToken.Value)
+			p.advanceToken()
 
-		parameter := p.CurrentToken.Value
-		p.advanceToken()
+			// Parse the parameter
+			if p.CurrentToken.Type != TOKEN_IDENTIFIER || strings.HasSuffix(p.CurrentToken.Value, ":") {
+				return "", nil, p.errorf(CodeExpectedIdentifier, "expected identifier, got %v", p.CurrentToken)
+			}
 
-		return selector, []string{parameter}, nil
+			parameters = append(parameters, p.CurrentToken.Value)
+			p.advanceToken()
+		}
+
+		return strings.Join(keywordParts, ""), parameters, nil
 	}
 
 	// Handle unary selectors
@@ -298,7 +782,7 @@ func (p *Parser) parseMethodSelector() (string, []string, error) {
 		return selector, []string{}, nil
 	}
 
-	return "", nil, fmt.Errorf("expected identifier or special, got %v", p.CurrentToken)
+	return "", nil, p.errorf(CodeExpectedSelector, "expected identifier or special, got %v", p.CurrentToken)
 }
 
 // parseTemporaries parses temporary variables
@@ -316,9 +800,14 @@ func (p *Parser) parseTemporaries() ([]string, error) {
 			p.advanceToken()
 		}
 
-		// Check for the closing |
+		// Check for the closing |, when recovering the temporaries are assumed to end here
 		if p.CurrentToken.Type != TOKEN_SPECIAL || p.CurrentToken.Value != "|" {
-			return nil, fmt.Errorf("expected |, got %v", p.CurrentToken)
+			err := p.errorf(CodeMissingDelimiter, "expected |, got %v", p.CurrentToken)
+			if !p.recovering {
+				return nil, err
+			}
+			p.report(err)
+			return temporaries, nil
 		}
 
 		p.advanceToken()
@@ -330,44 +819,143 @@ func (p *Parser) parseTemporaries() ([]string, error) {
 	return []string{}, nil
 }
 
-// parseStatements parses statements
-func (p *Parser) parseStatements() (ast.Node, error) {
-	// For now, we only handle a single return statement
-	// Skip any statements before the return
-	for p.CurrentToken.Type != TOKEN_EOF && !(p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "^") {
-		// Just advance to the next token
-		p.advanceToken()
+// parseBody parses the temporaries and statements of a method or doIt, which
+// extend to the end of the input. When recovering, stray closing brackets are
+// skipped and parsing continues after them.
+func (p *Parser) parseBody() (*ast.SequenceNode, error) {
+	body, err := p.parseSequence()
+	if err != nil {
+		return nil, err
+	}
 
-		// If we've reached the end of the tokens, break
-		if p.CurrentTokenIndex >= len(p.Tokens) {
-			break
+	// Make sure we consumed all of the input
+	for p.CurrentToken.Type != TOKEN_EOF {
+		err := p.errorf(CodeUnexpectedToken, "unexpected token after statements: %v", p.CurrentToken)
+		if !p.recovering {
+			return nil, err
 		}
-	}
 
-	// Parse the return statement
-	if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "^" {
+		// Replace the stray token with an error node
+		start := p.CurrentToken.Source.Start
+		p.report(err)
 		p.advanceToken()
+		body.Statements = append(body.Statements, &ast.ErrorNode{
+			Message: err.(*ParseError).Message,
+			Source:  p.rangeFrom(start),
+		})
 
-		// Initialize the current token index if needed
-		if p.CurrentTokenIndex >= len(p.Tokens) {
-			return nil, fmt.Errorf("unexpected end of input after return token")
-		}
-
-		// Parse the expression
-		expression, err := p.parseKeywordMessage()
+		// Parse the statements that follow it
+		statements, err := p.parseStatements()
 		if err != nil {
 			return nil, err
 		}
+		body.Statements = append(body.Statements, statements...)
+	}
+	body.Source = p.rangeFrom(body.Source.Start)
 
-		// Create the return node
-		returnNode := &ast.ReturnNode{
-			Expression: expression,
+	return body, nil
+}
+
+// parseSequence parses optional temporaries followed by a list of statements
+func (p *Parser) parseSequence() (*ast.SequenceNode, error) {
+	start := p.CurrentToken.Source.Start
+
+	// Parse temporary variables
+	temporaries, err := p.parseTemporaries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the statements
+	statements, err := p.parseStatements()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.SequenceNode{
+		Temporaries: temporaries,
+		Statements:  statements,
+		Source:      p.rangeFrom(start),
+	}, nil
+}
+
+// parseStatements parses statements separated by periods until
+// the end of the input or a closing bracket
+func (p *Parser) parseStatements() ([]ast.Node, error) {
+	statements := []ast.Node{}
+
+	for !p.isAtStatementsEnd() {
+		// Skip empty statements
+		if p.isSpecialToken(".") {
+			p.advanceToken()
+			continue
+		}
+
+		// Parse the statement, when recovering a broken statement becomes an error node
+		start := p.CurrentToken.Source.Start
+		statement, err := p.parseStatement()
+		if err != nil {
+			if !p.recovering {
+				return nil, err
+			}
+			errorNode, stop := p.recoverFrom(err, start)
+			statements = append(statements, errorNode)
+
+			// A closing parenthesis or brace or a ! ends the broken statement
+			// without a period, so the next statement starts right after it
+			if stop.Type != TOKEN_EOF && !p.isSpecialToken(".") && !p.isSpecialToken("]") {
+				continue
+			}
+		} else {
+			statements = append(statements, statement)
+		}
+
+		// Statements are separated by periods
+		if p.isSpecialToken(".") {
+			p.advanceToken()
+			continue
+		}
+
+		// Without a period the statement list must end here
+		if !p.isAtStatementsEnd() {
+			err := p.errorf(CodeExpectedPeriod, "expected period or end of statements, got %v", p.CurrentToken)
+			if !p.recovering {
+				return nil, err
+			}
+			errorNode, _ := p.recoverFrom(err, p.CurrentToken.Source.Start)
+			statements = append(statements, errorNode)
+		}
+	}
+
+	return statements, nil
+}
+
+// isAtStatementsEnd returns true if the current token ends a statement list
+func (p *Parser) isAtStatementsEnd() bool {
+	return p.CurrentToken.Type == TOKEN_EOF || p.isSpecialToken("]")
+}
+
+// parseStatement parses a single statement, which is either a return or an expression
+func (p *Parser) parseStatement() (ast.Node, error) {
+	// Parse the return statement
+	if p.isSpecialToken("^") {
+		start := p.CurrentToken.Source.Start
+		p.advanceToken()
+
+		// Parse the expression
+		expression, err := p.parseExpression()
+		if err != nil {
+			return nil, err
 		}
 
-		return returnNode, nil
+		// Create the return node
+		return &ast.ReturnNode{
+			Expression: expression,
+			Source:     p.rangeFrom(start),
+		}, nil
 	}
 
-	return nil, fmt.Errorf("expected return statement, got %v", p.CurrentToken)
+	return p.parseExpression()
 }
 
 // parseExpression parses an expression
@@ -377,7 +965,8 @@ func (p *Parser) parseExpression() (ast.Node, error) {
 	// 2. Unary messages (e.g., obj size)
 	// 3. Binary messages (e.g., a + b)
 	// 4. Keyword messages (e.g., dict at: key put: value)
-	// 5. Assignments (e.g., x := 5)
+	// 5. Cascades (e.g., Transcript show: 'a'; cr)
+	// 6. Assignments (e.g., x := 5)
 
 	// First check if this is an assignment expression
 	return p.parseAssignment()
@@ -396,13 +985,24 @@ func (p *Parser) parseAssignment() (ast.Node, error) {
 	if p.isAssignment() {
 		// Get the variable name
 		variableName := p.CurrentToken.Value
+		start := p.CurrentToken.Source.Start
+
+		// Pseudo-variables can't be assigned to, when recovering the
+		// assignment is still parsed
+		if IsPseudoVariable(variableName) {
+			err := p.errorf(CodeInvalidAssignment, "cannot assign to pseudo-variable %s", variableName)
+			if !p.recovering {
+				return nil, err
+			}
+			p.report(err)
+		}
 		
 		// Skip the variable name and :=
 		p.advanceToken() // Skip variable name
 		p.advanceToken() // Skip :=
 		
 		// Parse the expression to be assigned
-		expression, err := p.parseKeywordMessage()
+		expression, err := p.parseExpression()
 		if err != nil {
 			return nil, err
 		}
@@ -411,21 +1011,88 @@ func (p *Parser) parseAssignment() (ast.Node, error) {
 		return &ast.AssignmentNode{
 			Variable: variableName,
 			Expression: expression,
+			Source: p.rangeFrom(start),
 		}, nil
 	}
 	
 	// If it's not an assignment, continue with normal expression parsing
-	return p.parseKeywordMessage()
+	return p.parseCascade()
+}
+
+// parseCascade parses a keyword expression optionally followed by cascaded
+// messages separated by semicolons, e.g. Transcript show: 'a'; cr
+func (p *Parser) parseCascade() (ast.Node, error) {
+	start := p.CurrentToken.Source.Start
+
+	// First parse the leading message expression
+	first, err := p.parseKeywordMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	// If there's no semicolon, this is not a cascade
+	if !p.isSpecialToken(";") {
+		return first, nil
+	}
+
+	// The cascade receiver is the receiver of the last message in the first part
+	firstMessage, ok := first.(*ast.MessageSendNode)
+	if !ok {
+		return nil, p.errorf(CodeInvalidCascade, "cascade must follow a message send, got %v", p.CurrentToken)
+	}
+	receiver := firstMessage.Receiver
+	messages := []*ast.MessageSendNode{firstMessage}
+
+	// Parse each cascaded part
+	for p.isSpecialToken(";") {
+		// Skip the semicolon
+		p.advanceToken()
+
+		// Parse the messages sent to the shared receiver
+		part, err := p.parseUnaryTail(receiver, start)
+		if err != nil {
+			return nil, err
+		}
+		part, err = p.parseBinaryTail(part, start)
+		if err != nil {
+			return nil, err
+		}
+		part, err = p.parseKeywordTail(part, start)
+		if err != nil {
+			return nil, err
+		}
+
+		// Each part must send at least one message
+		message, ok := part.(*ast.MessageSendNode)
+		if !ok || part == receiver {
+			return nil, p.errorf(CodeInvalidCascade, "expected message in cascade, got %v", p.CurrentToken)
+		}
+		messages = append(messages, message)
+	}
+
+	return &ast.CascadeNode{
+		Receiver: receiver,
+		Messages: messages,
+		Source:   p.rangeFrom(start),
+	}, nil
 }
 
 // parseKeywordMessage parses a keyword message (lowest precedence)
 func (p *Parser) parseKeywordMessage() (ast.Node, error) {
+	start := p.CurrentToken.Source.Start
+
 	// First parse a binary expression
 	receiver, err := p.parseBinaryMessage()
 	if err != nil {
 		return nil, err
 	}
 
+	return p.parseKeywordTail(receiver, start)
+}
+
+// parseKeywordTail parses an optional keyword message sent to receiver,
+// which starts at the given position
+func (p *Parser) parseKeywordTail(receiver ast.Node, start ast.Position) (ast.Node, error) {
 	// Check if there's a keyword message
 	if p.CurrentToken.Type == TOKEN_IDENTIFIER && strings.HasSuffix(p.CurrentToken.Value, ":") {
 		// Collect all keyword parts and arguments
@@ -452,6 +1119,7 @@ func (p *Parser) parseKeywordMessage() (ast.Node, error) {
 			Receiver:  receiver,
 			Selector:  selector,
 			Arguments: arguments,
+			Source:    p.rangeFrom(start),
 		}, nil
 	}
 
@@ -460,20 +1128,24 @@ func (p *Parser) parseKeywordMessage() (ast.Node, error) {
 
 // parseBinaryMessage parses a binary message (medium precedence)
 func (p *Parser) parseBinaryMessage() (ast.Node, error) {
+	start := p.CurrentToken.Source.Start
+
 	// First parse a unary message
 	left, err := p.parseUnaryMessage()
 	if err != nil {
 		return nil, err
 	}
 
+	return p.parseBinaryTail(left, start)
+}
+
+// parseBinaryTail parses a chain of binary messages sent to left,
+// which starts at the given position
+func (p *Parser) parseBinaryTail(left ast.Node, start ast.Position) (ast.Node, error) {
 	// Parse a chain of binary messages
-	// Binary operators are special characters like +, -, *, /, <, >, etc.
-	// But NOT ), ], or other non-binary operators
-	for p.CurrentToken.Type == TOKEN_SPECIAL && 
-		p.CurrentToken.Value != ")" && 
-		p.CurrentToken.Value != "]" && 
-		p.CurrentToken.Value != "." {
-		
+	// Binary operators are selectors like +, -, *, /, <=, ->, etc.
+	for p.isBinarySelectorToken() {
+
 		// Get the binary selector
 		selector := p.CurrentToken.Value
 		p.advanceToken()
@@ -489,6 +1161,7 @@ func (p *Parser) parseBinaryMessage() (ast.Node, error) {
 			Receiver:  left,
 			Selector:  selector,
 			Arguments: []ast.Node{right},
+			Source:    p.rangeFrom(start),
 		}
 	}
 
@@ -497,12 +1170,20 @@ func (p *Parser) parseBinaryMessage() (ast.Node, error) {
 
 // parseUnaryMessage parses a unary message (highest precedence)
 func (p *Parser) parseUnaryMessage() (ast.Node, error) {
+	start := p.CurrentToken.Source.Start
+
 	// First parse a primary expression
 	receiver, err := p.parsePrimary()
 	if err != nil {
 		return nil, err
 	}
 
+	return p.parseUnaryTail(receiver, start)
+}
+
+// parseUnaryTail parses a chain of unary messages sent to receiver,
+// which starts at the given position
+func (p *Parser) parseUnaryTail(receiver ast.Node, start ast.Position) (ast.Node, error) {
 	// Parse a chain of unary messages
 	for p.CurrentToken.Type == TOKEN_IDENTIFIER && !strings.HasSuffix(p.CurrentToken.Value, ":") {
 		// Get the unary selector
@@ -514,6 +1195,7 @@ func (p *Parser) parseUnaryMessage() (ast.Node, error) {
 			Receiver:  receiver,
 			Selector:  selector,
 			Arguments: []ast.Node{},
+			Source:    p.rangeFrom(start),
 		}
 	}
 
@@ -522,10 +1204,33 @@ func (p *Parser) parseUnaryMessage() (ast.Node, error) {
 
 // parsePrimary parses a primary expression
 func (p *Parser) parsePrimary() (ast.Node, error) {
+	start := p.CurrentToken.Source.Start
+
 	// Handle self
 	if p.CurrentToken.Type == TOKEN_IDENTIFIER && p.CurrentToken.Value == "self" {
 		p.advanceToken()
-		return &ast.SelfNode{}, nil
+		return &ast.SelfNode{Source: p.rangeFrom(start)}, nil
+	}
+
+	// Handle super
+	if p.CurrentToken.Type == TOKEN_IDENTIFIER && p.CurrentToken.Value == "super" {
+		p.advanceToken()
+		return &ast.SuperNode{Source: p.rangeFrom(start)}, nil
+	}
+
+	// Handle thisContext
+	if p.CurrentToken.Type == TOKEN_IDENTIFIER && p.CurrentToken.Value == "thisContext" {
+		p.advanceToken()
+		return &ast.ThisContextNode{Source: p.rangeFrom(start)}, nil
+	}
+
+	// Handle nil
+	if p.CurrentToken.Type == TOKEN_IDENTIFIER && p.CurrentToken.Value == "nil" {
+		p.advanceToken()
+		return &ast.LiteralNode{
+			Value:  pile.MakeNilImmediate(),
+			Source: p.rangeFrom(start),
+		}, nil
 	}
 
 	// Handle true and false
@@ -537,10 +1242,11 @@ func (p *Parser) parsePrimary() (ast.Node, error) {
 		trueValue := pile.MakeTrueImmediate()
 		if trueValue == nil {
 			// Fallback if immediate creation fails
-			return nil, fmt.Errorf("failed to create immediate true value")
+			return nil, p.errorf(CodeInternal, "failed to create immediate true value")
 		}
 		return &ast.LiteralNode{
-			Value: trueValue,
+			Value:  trueValue,
+			Source: p.rangeFrom(start),
 		}, nil
 	}
 
@@ -552,10 +1258,11 @@ func (p *Parser) parsePrimary() (ast.Node, error) {
 		falseValue := pile.MakeFalseImmediate()
 		if falseValue == nil {
 			// Fallback if immediate creation fails
-			return nil, fmt.Errorf("failed to create immediate false value")
+			return nil, p.errorf(CodeInternal, "failed to create immediate false value")
 		}
 		return &ast.LiteralNode{
-			Value: falseValue,
+			Value:  falseValue,
+			Source: p.rangeFrom(start),
 		}, nil
 	}
 
@@ -563,7 +1270,8 @@ func (p *Parser) parsePrimary() (ast.Node, error) {
 	if p.CurrentToken.Type == TOKEN_STRING {
 		// Create a string literal node using the VM
 		literalNode := &ast.LiteralNode{
-			Value: p.VM.NewString(p.CurrentToken.Value),
+			Value:  p.VM.NewString(p.CurrentToken.Value),
+			Source: p.CurrentToken.Source,
 		}
 		p.advanceToken()
 		return literalNode, nil
@@ -571,41 +1279,90 @@ func (p *Parser) parsePrimary() (ast.Node, error) {
 
 	// Handle number literals
 	if p.CurrentToken.Type == TOKEN_NUMBER {
-		// Parse the number
-		var value int64
-		fmt.Sscanf(p.CurrentToken.Value, "%d", &value)
-
 		// Create a number literal node using the VM
+		value, err := p.newNumberLiteral(p.CurrentToken.Value)
+		if err != nil {
+			return nil, err
+		}
+		p.advanceToken()
+		return &ast.LiteralNode{Value: value, Source: p.rangeFrom(start)}, nil
+	}
+
+	// Handle character literals
+	if p.CurrentToken.Type == TOKEN_CHARACTER {
+		// Create a character literal node using the VM
 		literalNode := &ast.LiteralNode{
-			Value: p.VM.NewInteger(value),
+			Value:  p.VM.NewCharacter([]rune(p.CurrentToken.Value)[0]),
+			Source: p.CurrentToken.Source,
 		}
 		p.advanceToken()
 		return literalNode, nil
 	}
 
+	// Handle input that could not be tokenized, which has already been reported
+	if p.CurrentToken.Type == TOKEN_ERROR {
+		errorNode := &ast.ErrorNode{
+			Message: p.CurrentToken.Value,
+			Source:  p.CurrentToken.Source,
+		}
+		p.advanceToken()
+		return errorNode, nil
+	}
+
 	// Handle block expressions
 	if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "[" {
 		return p.parseBlock()
 	}
 
-	// Handle array literals - must check this before parenthesized expressions
-	if p.CurrentToken.Type == TOKEN_SYMBOL && p.CurrentToken.Value == "(" {
+	// Handle symbol literals
+	if p.CurrentToken.Type == TOKEN_SYMBOL {
+		// Create a symbol literal node using the VM
+		literalNode := &ast.LiteralNode{
+			Value:  p.VM.NewSymbol(p.CurrentToken.Value),
+			Source: p.CurrentToken.Source,
+		}
+		p.advanceToken()
+		return literalNode, nil
+	}
+
+	// Handle array literals
+	if p.CurrentToken.Type == TOKEN_LITERAL_ARRAY_START {
 		return p.parseArrayLiteral()
 	}
 
+	// Handle byte array literals
+	if p.CurrentToken.Type == TOKEN_BYTE_ARRAY_START {
+		return p.parseByteArrayLiteral()
+	}
+
+	// Handle brace arrays
+	if p.isSpecialToken("{") {
+		return p.parseDynamicArray()
+	}
+
 	// Handle parenthesized expressions
 	if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "(" {
 		p.advanceToken() // Skip the opening parenthesis
 
-		// Parse the expression inside the parentheses
-		expr, err := p.parseKeywordMessage()
+		// Parse the expression inside the parentheses, when recovering
+		// a broken expression becomes an error node
+		expr, err := p.parseExpression()
 		if err != nil {
-			return nil, err
+			if !p.recovering {
+				return nil, err
+			}
+			errorNode, _ := p.recoverFrom(err, start)
+			return errorNode, nil
 		}
 
-		// Expect a closing parenthesis
+		// Expect a closing parenthesis, when recovering it is assumed to be missing
 		if p.CurrentToken.Type != TOKEN_SPECIAL || p.CurrentToken.Value != ")" {
-			return nil, fmt.Errorf("expected closing parenthesis, got %v", p.CurrentToken)
+			err := p.errorf(CodeMissingDelimiter, "expected closing parenthesis, got %v", p.CurrentToken)
+			if !p.recovering {
+				return nil, err
+			}
+			p.report(err)
+			return expr, nil
 		}
 		p.advanceToken() // Skip the closing parenthesis
 
@@ -617,92 +1374,142 @@ func (p *Parser) parsePrimary() (ast.Node, error) {
 		name := p.CurrentToken.Value
 		p.advanceToken()
 
-		// Check if this is a global variable (starting with uppercase)
-		if len(name) > 0 && name[0] >= 'A' && name[0] <= 'Z' {
-			// Look up the global in the VM
-			globalObj := p.VM.GetGlobal(name)
-
-			// If it's a class or other global, return it as a literal node
-			// TODO look it up at runtime since the value may have changed
-			return &ast.LiteralNode{Value: globalObj}, nil
+		// Names starting with uppercase are globals, which are looked up at runtime
+		if ast.IsGlobalName(name) {
+			return &ast.GlobalNode{Name: name, Source: p.rangeFrom(start)}, nil
 		}
 
 		// Otherwise, treat it as a regular variable
-		return &ast.VariableNode{Name: name}, nil
+		return &ast.VariableNode{Name: name, Source: p.rangeFrom(start)}, nil
+	}
+
+	return nil, p.errorf(CodeExpectedExpression, "expected primary expression, got %v", p.CurrentToken)
+}
+
+// newNumberLiteral converts the text of a number token into a literal object:
+// an Integer for plain and radix integers, a ScaledDecimal when the text has
+// an s suffix and a Float when it has a fraction or a negative exponent
+func (p *Parser) newNumberLiteral(text string) (*pile.Object, error) {
+	// Handle radix integers such as 16r1F
+	if radixIndex := strings.IndexByte(text, 'r'); radixIndex >= 0 {
+		radix, err := strconv.Atoi(strings.TrimPrefix(text[:radixIndex], "-"))
+		if err != nil || radix < 2 || radix > 36 {
+			return nil, p.errorf(CodeInvalidLiteral, "invalid radix in number literal: %s", text)
+		}
+
+		value, err := strconv.ParseInt(text[radixIndex+1:], radix, 64)
+		if err != nil {
+			return nil, p.errorf(CodeInvalidLiteral, "invalid digits for radix %d in number literal: %s", radix, text)
+		}
+		if strings.HasPrefix(text, "-") {
+			value = -value
+		}
+
+		return p.newIntegerLiteral(value, text)
+	}
+
+	// Handle scaled decimals such as 1.25s2
+	if scaleIndex := strings.IndexByte(text, 's'); scaleIndex >= 0 {
+		mantissa := text[:scaleIndex]
+
+		value, ok := new(big.Rat).SetString(mantissa)
+		if !ok {
+			return nil, p.errorf(CodeInvalidLiteral, "invalid scaled decimal literal: %s", text)
+		}
+
+		// Without an explicit scale the number of fraction digits is used
+		scale := 0
+		if scaleIndex+1 < len(text) {
+			scale, _ = strconv.Atoi(text[scaleIndex+1:])
+		} else if dotIndex := strings.IndexByte(mantissa, '.'); dotIndex >= 0 {
+			scale = len(mantissa) - dotIndex - 1
+		}
+
+		return p.VM.NewScaledDecimal(value, scale), nil
 	}
 
-	return nil, fmt.Errorf("expected primary expression, got %v", p.CurrentToken)
+	// Handle floats such as 3.14 and 1.5e-3
+	if strings.Contains(text, ".") || strings.Contains(text, "e-") {
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, p.errorf(CodeInvalidLiteral, "invalid float literal: %s", text)
+		}
+
+		return p.VM.NewFloat(value), nil
+	}
+
+	// Handle integers with a positive exponent such as 2e3
+	if exponentIndex := strings.IndexByte(text, 'e'); exponentIndex >= 0 {
+		value, ok := new(big.Int).SetString(text[:exponentIndex], 10)
+		exponent, err := strconv.Atoi(text[exponentIndex+1:])
+		if !ok || err != nil {
+			return nil, p.errorf(CodeInvalidLiteral, "invalid number literal: %s", text)
+		}
+
+		value.Mul(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+		if !value.IsInt64() {
+			return nil, p.errorf(CodeInvalidLiteral, "integer literal out of range: %s", text)
+		}
+
+		return p.newIntegerLiteral(value.Int64(), text)
+	}
+
+	// Handle plain integers
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return nil, p.errorf(CodeInvalidLiteral, "integer literal out of range: %s", text)
+	}
+
+	return p.newIntegerLiteral(value, text)
+}
+
+// newIntegerLiteral creates an integer literal, reporting values that
+// don't fit in an immediate integer as errors
+func (p *Parser) newIntegerLiteral(value int64, text string) (*pile.Object, error) {
+	if value > 0x1FFFFFFFFFFFFFFF || value < -0x2000000000000000 {
+		return nil, p.errorf(CodeInvalidLiteral, "integer literal out of range: %s", text)
+	}
+
+	return p.VM.NewInteger(value), nil
 }
 
-// parseArrayLiteral parses an array literal like #(1 2 3)
+// parseArrayLiteral parses an array literal like #(1 #(2 3) foo $a nil #+)
 func (p *Parser) parseArrayLiteral() (ast.Node, error) {
-	// Skip the opening symbol token (the # has already been handled by the tokenizer)
+	start := p.CurrentToken.Source.Start
+
+	// Skip the opening #(
 	p.advanceToken()
 
-	// Check if the next token is the opening parenthesis
-	if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "(" {
-		// Skip the opening parenthesis
-		p.advanceToken()
-	} else {
-		return nil, fmt.Errorf("expected opening parenthesis for array literal, got %v", p.CurrentToken)
+	// Parse the elements and the closing parenthesis
+	arrayObj, err := p.parseLiteralArrayContents()
+	if err != nil {
+		return nil, err
 	}
 
+	// Create a literal node with the array object
+	return &ast.LiteralNode{
+		Value:  arrayObj,
+		Source: p.rangeFrom(start),
+	}, nil
+}
+
+// parseLiteralArrayContents parses the elements of a literal array up to and
+// including the closing parenthesis and creates the Array object
+func (p *Parser) parseLiteralArrayContents() (*pile.Object, error) {
 	// Parse the array elements
-	var elements []ast.Node
+	var elements []*pile.Object
 
 	// Continue parsing elements until we reach the closing parenthesis
-	for p.CurrentToken.Type != TOKEN_SPECIAL || p.CurrentToken.Value != ")" {
-		// Parse the element (only literals are allowed in array literals)
-		if p.CurrentToken.Type == TOKEN_NUMBER {
-			// Parse number literal
-			var value int64
-			fmt.Sscanf(p.CurrentToken.Value, "%d", &value)
-
-			element := &ast.LiteralNode{
-				Value: p.VM.NewInteger(value),
-			}
-			elements = append(elements, element)
-			p.advanceToken()
-		} else if p.CurrentToken.Type == TOKEN_STRING {
-			// Parse string literal
-			element := &ast.LiteralNode{
-				Value: p.VM.NewString(p.CurrentToken.Value),
-			}
-			elements = append(elements, element)
-			p.advanceToken()
-		} else if p.CurrentToken.Type == TOKEN_IDENTIFIER &&
-			(p.CurrentToken.Value == "true" || p.CurrentToken.Value == "false") {
-			// Parse boolean literal
-			var value *pile.Object
-			if p.CurrentToken.Value == "true" {
-				value = pile.MakeTrueImmediate()
-				if value == nil {
-					return nil, fmt.Errorf("failed to create true immediate value")
-				}
-			} else {
-				value = pile.MakeFalseImmediate()
-				if value == nil {
-					return nil, fmt.Errorf("failed to create false immediate value")
-				}
-			}
-			element := &ast.LiteralNode{
-				Value: value,
-			}
-			elements = append(elements, element)
-			p.advanceToken()
-		} else {
-			return nil, fmt.Errorf("unexpected token in array literal: %v", p.CurrentToken)
+	for !p.isSpecialToken(")") {
+		if p.CurrentToken.Type == TOKEN_EOF {
+			return nil, p.errorf(CodeMissingDelimiter, "expected closing parenthesis for array literal, got %v", p.CurrentToken)
 		}
 
-		// If we've reached the end of the array, break
-		if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == ")" {
-			break
+		element, err := p.parseLiteralArrayElement()
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	// Expect a closing parenthesis
-	if p.CurrentToken.Type != TOKEN_SPECIAL || p.CurrentToken.Value != ")" {
-		return nil, fmt.Errorf("expected closing parenthesis for array literal, got %v", p.CurrentToken)
+		elements = append(elements, element)
 	}
 	p.advanceToken() // Skip the closing parenthesis
 
@@ -712,150 +1519,265 @@ func (p *Parser) parseArrayLiteral() (ast.Node, error) {
 
 	// Fill the array with the parsed elements
 	for i, element := range elements {
-		// We need to extract the actual value from each element node
-		if literalNode, ok := element.(*ast.LiteralNode); ok {
-			array.AtPut(i, literalNode.Value)
-		} else {
-			return nil, fmt.Errorf("expected literal node for array element, got %T", element)
-		}
+		array.AtPut(i, element)
 	}
 
-	// Create a literal node with the array object
-	return &ast.LiteralNode{
-		Value: arrayObj,
-	}, nil
+	return arrayObj, nil
 }
 
-// parseBlock parses a block expression
-func (p *Parser) parseBlock() (ast.Node, error) {
-	// Skip the opening bracket
-	p.advanceToken()
+// parseLiteralArrayElement parses one element of a literal array. Only literals
+// are allowed, bare identifiers and binary selectors are read as symbols and
+// bare parentheses start a nested array.
+func (p *Parser) parseLiteralArrayElement() (*pile.Object, error) {
+	token := p.CurrentToken
 
-	// Parse block parameters (if any)
-	var parameters []string
+	switch token.Type {
+	case TOKEN_NUMBER:
+		// Parse number literal
+		value, err := p.newNumberLiteral(token.Value)
+		if err != nil {
+			return nil, err
+		}
+		p.advanceToken()
+		return value, nil
 
-	// Check for the block parameter pattern, which starts with a colon
-	if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == ":" {
-		// Skip the colon
+	case TOKEN_CHARACTER:
+		// Parse character literal
 		p.advanceToken()
+		return p.VM.NewCharacter([]rune(token.Value)[0]), nil
 
-		// Expect an identifier (the parameter name)
-		if p.CurrentToken.Type != TOKEN_IDENTIFIER {
-			return nil, fmt.Errorf("expected identifier after : in block parameter, got %v", p.CurrentToken)
+	case TOKEN_STRING:
+		// Parse string literal
+		p.advanceToken()
+		return p.VM.NewString(token.Value), nil
+
+	case TOKEN_SYMBOL, TOKEN_BINARY:
+		// Parse symbol literal, with or without the #
+		p.advanceToken()
+		return p.VM.NewSymbol(token.Value), nil
+
+	case TOKEN_IDENTIFIER:
+		p.advanceToken()
+
+		// Parse true, false and nil, anything else is a symbol
+		switch token.Value {
+		case "true":
+			return pile.MakeTrueImmediate(), nil
+		case "false":
+			return pile.MakeFalseImmediate(), nil
+		case "nil":
+			return pile.MakeNilImmediate(), nil
+		default:
+			return p.VM.NewSymbol(token.Value), nil
 		}
 
-		// Add the parameter name
-		parameters = append(parameters, p.CurrentToken.Value)
-		p.advanceToken() // Skip the parameter name
+	case TOKEN_LITERAL_ARRAY_START:
+		// Parse nested array literal
+		p.advanceToken()
+		return p.parseLiteralArrayContents()
 
-		// Check for additional parameters (would be another : token)
-		for p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == ":" {
-			// Skip the colon
+	case TOKEN_BYTE_ARRAY_START:
+		// Parse nested byte array literal
+		p.advanceToken()
+		return p.parseByteArrayContents()
+
+	case TOKEN_SPECIAL:
+		// Parse nested array literal without the #
+		if token.Value == "(" {
 			p.advanceToken()
+			return p.parseLiteralArrayContents()
+		}
 
-			// Expect an identifier
-			if p.CurrentToken.Type != TOKEN_IDENTIFIER {
-				return nil, fmt.Errorf("expected identifier after : in block parameter, got %v", p.CurrentToken)
-			}
+		// The vertical bar is a binary selector symbol
+		if token.Value == "|" {
+			p.advanceToken()
+			return p.VM.NewSymbol(token.Value), nil
+		}
+	}
 
-			// Add the parameter name
-			parameters = append(parameters, p.CurrentToken.Value)
-			p.advanceToken() // Skip the parameter name
+	return nil, p.errorf(CodeUnexpectedToken, "unexpected token in array literal: %v", token)
+}
+
+// parseByteArrayLiteral parses a byte array literal like #[1 2 255]
+func (p *Parser) parseByteArrayLiteral() (ast.Node, error) {
+	start := p.CurrentToken.Source.Start
+
+	// Skip the opening #[
+	p.advanceToken()
+
+	// Parse the bytes and the closing bracket
+	byteArrayObj, err := p.parseByteArrayContents()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a literal node with the byte array object
+	return &ast.LiteralNode{
+		Value:  byteArrayObj,
+		Source: p.rangeFrom(start),
+	}, nil
+}
+
+// parseByteArrayContents parses the bytes of a byte array literal up to and
+// including the closing bracket and creates the ByteArray object
+func (p *Parser) parseByteArrayContents() (*pile.Object, error) {
+	var bytes []byte
+
+	// Continue parsing bytes until we reach the closing bracket
+	for !p.isSpecialToken("]") {
+		// Only integers between 0 and 255 are allowed
+		if p.CurrentToken.Type != TOKEN_NUMBER {
+			return nil, p.errorf(CodeInvalidLiteral, "expected byte or closing bracket in byte array literal, got %v", p.CurrentToken)
 		}
 
-		// After parameters, expect a | token
-		if p.CurrentToken.Type != TOKEN_SPECIAL || p.CurrentToken.Value != "|" {
-			return nil, fmt.Errorf("expected | after block parameters, got %v", p.CurrentToken)
+		// The bytes are number literals such as 255 or 16rFF
+		value, err := p.newNumberLiteral(p.CurrentToken.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !pile.IsIntegerImmediate(value) || pile.GetIntegerImmediate(value) < 0 || pile.GetIntegerImmediate(value) > 255 {
+			return nil, p.errorf(CodeInvalidLiteral, "invalid byte in byte array literal: %s", p.CurrentToken.Value)
 		}
 
-		// Skip the | token
+		bytes = append(bytes, byte(pile.GetIntegerImmediate(value)))
 		p.advanceToken()
-	} else {
-		// No parameters, check for temporaries
-		if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "|" {
-			// Has temporaries
-			p.advanceToken() // Skip the opening |
-		}
 	}
+	p.advanceToken() // Skip the closing bracket
 
-	// Parse temporary variables if they exist
-	var temporaries []string
-	
-	// Parse temporaries if we didn't just parse parameters (in which case we've already consumed the |)
-	if len(parameters) == 0 && p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "|" {
-		// Skip the opening bar for temporaries
-		p.advanceToken()
+	// Create an actual ByteArray object using the VM
+	byteArrayObj := p.VM.NewByteArray(len(bytes))
+	byteArray := pile.ObjectToByteArray(byteArrayObj)
 
-		// Parse temporary variable names
-		for p.CurrentToken.Type == TOKEN_IDENTIFIER {
-			temporaries = append(temporaries, p.CurrentToken.Value)
-			p.advanceToken()
-		}
+	// Fill the byte array with the parsed bytes
+	for i, b := range bytes {
+		byteArray.AtPut(i, b)
+	}
 
-		// Expect a closing bar
-		if p.CurrentToken.Type != TOKEN_SPECIAL || p.CurrentToken.Value != "|" {
-			return nil, fmt.Errorf("expected closing | for block temporaries, got %v", p.CurrentToken)
+	return byteArrayObj, nil
+}
+
+// parseDynamicArray parses a brace array like {a. b + 1. c}, whose
+// period-separated elements are evaluated at runtime
+func (p *Parser) parseDynamicArray() (ast.Node, error) {
+	start := p.CurrentToken.Source.Start
+
+	// Skip the opening brace
+	p.advanceToken()
+
+	elements := []ast.Node{}
+
+	// Continue parsing elements until we reach the closing brace
+	for !p.isSpecialToken("}") {
+		// Parse the element
+		elementStart := p.CurrentToken.Source.Start
+		element, err := p.parseExpression()
+		if err == nil {
+			elements = append(elements, element)
+
+			// Elements are separated by periods, the last one may be followed by one
+			if p.isSpecialToken(".") {
+				p.advanceToken()
+				continue
+			} else if p.isSpecialToken("}") {
+				continue
+			}
+
+			err = p.errorf(CodeExpectedPeriod, "expected period or closing brace in brace array, got %v", p.CurrentToken)
+			elementStart = p.CurrentToken.Source.Start
+		}
+		if !p.recovering {
+			return nil, err
 		}
 
-		// Skip the closing bar
+		// Replace the broken element with an error node
+		errorNode, stop := p.recoverFrom(err, elementStart)
+		elements = append(elements, errorNode)
+
+		// Continue with the next element after a period, anything else ends the array
+		if stop.Type != TOKEN_SPECIAL || stop.Value != "." {
+			return &ast.DynamicArrayNode{
+				Elements: elements,
+				Source:   p.rangeFrom(start),
+			}, nil
+		}
 		p.advanceToken()
-	} else if len(parameters) == 0 {
-		// No parameters and no temporaries, do nothing
 	}
+	p.advanceToken() // Skip the closing brace
 
-	// Parse the block body
-	// We'll handle a sequence of expressions separated by periods
-	var bodyExpressions []ast.Node
+	return &ast.DynamicArrayNode{
+		Elements: elements,
+		Source:   p.rangeFrom(start),
+	}, nil
+}
 
-	// Parse expressions until we reach the closing bracket or EOF
-	for p.CurrentToken.Type != TOKEN_EOF && (p.CurrentToken.Type != TOKEN_SPECIAL || p.CurrentToken.Value != "]") {
-		// Parse an expression
-		expr, err := p.parseKeywordMessage()
-		if err != nil {
-			return nil, err
+// parseBlock parses a block expression
+func (p *Parser) parseBlock() (ast.Node, error) {
+	start := p.CurrentToken.Source.Start
+
+	// Skip the opening bracket
+	p.advanceToken()
+
+	// Parse block parameters (if any)
+	parameters := []string{}
+
+	// Each block parameter starts with a colon
+	hasParameters := false
+	for p.isSpecialToken(":") {
+		// Skip the colon
+		p.advanceToken()
+		hasParameters = true
+
+		// Expect an identifier (the parameter name), when recovering the parameters end here
+		if p.CurrentToken.Type != TOKEN_IDENTIFIER {
+			err := p.errorf(CodeExpectedIdentifier, "expected identifier after : in block parameter, got %v", p.CurrentToken)
+			if !p.recovering {
+				return nil, err
+			}
+			p.report(err)
+			break
 		}
 
-		bodyExpressions = append(bodyExpressions, expr)
+		// Add the parameter name
+		parameters = append(parameters, p.CurrentToken.Value)
+		p.advanceToken() // Skip the parameter name
+	}
 
-		// If the next token is a period, skip it and continue parsing
-		if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "." {
+	// After parameters, expect a | token unless the block is empty
+	if hasParameters {
+		if p.isSpecialToken("|") {
 			p.advanceToken()
-		} else if p.CurrentToken.Type == TOKEN_EOF || (p.CurrentToken.Type != TOKEN_SPECIAL || p.CurrentToken.Value != "]") {
-			// If it's not a period or closing bracket (and not EOF), it's a syntax error
-			if p.CurrentToken.Type != TOKEN_EOF {
-				return nil, fmt.Errorf("expected period or closing bracket in block, got %v", p.CurrentToken)
+		} else if !p.isSpecialToken("]") {
+			err := p.errorf(CodeMissingDelimiter, "expected | after block parameters, got %v", p.CurrentToken)
+			if !p.recovering {
+				return nil, err
 			}
-			// If it's EOF, we'll just end the block
-			break
+			p.report(err)
 		}
 	}
 
-	// Skip the closing bracket
-	if p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == "]" {
-		p.advanceToken()
+	// Parse the block body (temporaries and statements)
+	body, err := p.parseSequence()
+	if err != nil {
+		return nil, err
 	}
 
-	// If the block has no expressions, return a nil block
-	if len(bodyExpressions) == 0 {
-		nilValue := pile.MakeNilImmediate()
-		if nilValue == nil {
-			return nil, fmt.Errorf("failed to create nil immediate value")
+	// Expect the closing bracket, when recovering it is assumed to be missing
+	if p.isSpecialToken("]") {
+		p.advanceToken()
+	} else {
+		err := p.errorf(CodeMissingDelimiter, "expected closing bracket for block, got %v", p.CurrentToken)
+		if !p.recovering {
+			return nil, err
 		}
-		return &ast.BlockNode{
-			Parameters:  parameters,
-			Temporaries: temporaries,
-			Body:        &ast.LiteralNode{Value: nilValue},
-		}, nil
+		p.report(err)
 	}
 
-	// Use the last expression as the block's body (for now, ignoring statements before the last one)
-	body := bodyExpressions[len(bodyExpressions)-1]
-
 	// Create the block node
 	blockNode := &ast.BlockNode{
 		Parameters:  parameters,
-		Temporaries: temporaries,
+		Temporaries: body.Temporaries,
 		Body:        body,
+		Source:      p.rangeFrom(start),
 	}
 
 	return blockNode, nil
@@ -866,10 +1788,24 @@ func (p *Parser) parseBlock() (ast.Node, error) {
 
 // advance advances to the next character
 func (p *Parser) advance() {
-	p.Position++
+	size := 1
 	if p.Position < len(p.Input) {
-		p.CurrentChar = p.Input[p.Position]
+		_, size = utf8.DecodeRuneInString(p.Input[p.Position:])
 	}
+
+	p.Position += size
+	p.CurrentChar = p.charAt(p.Position)
+}
+
+// charAt returns the character starting at the given byte offset of the
+// input, or 0 at the end of the input. Invalid UTF-8 is read one byte at a time.
+func (p *Parser) charAt(offset int) rune {
+	if offset >= len(p.Input) {
+		return 0
+	}
+
+	c, _ := utf8.DecodeRuneInString(p.Input[offset:])
+	return c
 }
 
 // advanceToken advances to the next token
@@ -880,24 +1816,80 @@ func (p *Parser) advanceToken() {
 	}
 }
 
+// IsPseudoVariable returns true if the name is a pseudo-variable, which
+// refers to a value provided by the system and can't be assigned to
+func IsPseudoVariable(name string) bool {
+	switch name {
+	case "self", "super", "thisContext", "nil", "true", "false":
+		return true
+	}
+	return false
+}
+
+// isBinarySelectorToken returns true if the current token is a binary selector,
+// including the vertical bar which is tokenized as a special character
+func (p *Parser) isBinarySelectorToken() bool {
+	return p.CurrentToken.Type == TOKEN_BINARY || p.isSpecialToken("|")
+}
+
+// isSpecialToken returns true if the current token is the given special character
+func (p *Parser) isSpecialToken(value string) bool {
+	return p.CurrentToken.Type == TOKEN_SPECIAL && p.CurrentToken.Value == value
+}
+
 // isWhitespace returns true if the character is whitespace
-func (p *Parser) isWhitespace(c byte) bool {
+func (p *Parser) isWhitespace(c rune) bool {
 	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
 }
 
-// isAlpha returns true if the character is alphabetic
-func (p *Parser) isAlpha(c byte) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+// isAlpha returns true if the character is a letter, in any script, or an underscore
+func (p *Parser) isAlpha(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
 }
 
 // isDigit returns true if the character is a digit
-func (p *Parser) isDigit(c byte) bool {
+func (p *Parser) isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
 // isSpecial returns true if the character is a special character
-func (p *Parser) isSpecial(c byte) bool {
-	return strings.ContainsRune("+-*/=<>[](){}^.|:,~", rune(c))
+func (p *Parser) isSpecial(c rune) bool {
+	return strings.ContainsRune("[](){}^.|:;", c)
+}
+
+// isBinaryCharacter returns true if the character can be part of a binary selector.
+// The vertical bar is also a binary selector in Smalltalk, but since it delimits
+// temporaries and block parameters it is always tokenized on its own as a special.
+func (p *Parser) isBinaryCharacter(c rune) bool {
+	return strings.ContainsRune("!%&*+,-/<=>?@\\~", c)
+}
+
+// isNegativeNumberStart returns true if the current character is a minus sign
+// that starts a negative number literal rather than a binary minus. Outside of
+// literal arrays that is only the case when no operand precedes it, so that
+// "3 -4" still subtracts while "x := -4" and "3 + -4" use a negative literal.
+func (p *Parser) isNegativeNumberStart(inLiteralArray bool) bool {
+	// The minus sign must be directly followed by a digit
+	if p.CurrentChar != '-' || p.Position+1 >= len(p.Input) || !p.isDigit(p.charAt(p.Position+1)) {
+		return false
+	}
+
+	if inLiteralArray || len(p.Tokens) == 0 {
+		return true
+	}
+
+	// Check whether the previous token is an operand
+	last := p.Tokens[len(p.Tokens)-1]
+	switch last.Type {
+	case TOKEN_IDENTIFIER:
+		return strings.HasSuffix(last.Value, ":")
+	case TOKEN_NUMBER, TOKEN_STRING, TOKEN_SYMBOL, TOKEN_CHARACTER:
+		return false
+	case TOKEN_SPECIAL:
+		return last.Value != ")" && last.Value != "]" && last.Value != "}"
+	default:
+		return true
+	}
 }
 
 // parseIdentifier parses an identifier
@@ -905,7 +1897,7 @@ func (p *Parser) parseIdentifier() Token {
 	var value strings.Builder
 
 	for p.Position < len(p.Input) && (p.isAlpha(p.CurrentChar) || p.isDigit(p.CurrentChar)) {
-		value.WriteByte(p.CurrentChar)
+		value.WriteRune(p.CurrentChar)
 		p.advance()
 	}
 
@@ -919,36 +1911,125 @@ func (p *Parser) parseIdentifier() Token {
 	return Token{Type: TOKEN_IDENTIFIER, Value: value.String()}
 }
 
-// parseNumber parses a number
+// parseNumber parses a number literal. Besides plain integers it accepts
+// negative numbers (-5), radix integers (16r1F), fractions and exponents
+// (1.5e-3) and scaled decimals (1.25s2). The value is converted later by
+// newNumberLiteral.
 func (p *Parser) parseNumber() Token {
 	var value strings.Builder
 
-	for p.Position < len(p.Input) && p.isDigit(p.CurrentChar) {
-		value.WriteByte(p.CurrentChar)
+	// Handle the sign of negative numbers
+	if p.CurrentChar == '-' {
+		value.WriteByte('-')
 		p.advance()
 	}
 
+	p.readDigits(&value)
+
+	// Handle radix integers such as 16r1F
+	if p.Position+1 < len(p.Input) && p.CurrentChar == 'r' && p.isRadixDigit(p.charAt(p.Position+1)) {
+		value.WriteByte('r')
+		p.advance()
+
+		for p.Position < len(p.Input) && p.isRadixDigit(p.CurrentChar) {
+			value.WriteRune(p.CurrentChar)
+			p.advance()
+		}
+
+		return Token{Type: TOKEN_NUMBER, Value: value.String()}
+	}
+
 	// Handle decimal point
 	if p.Position < len(p.Input) && p.CurrentChar == '.' {
 		// Make sure the next character is a digit
-		if p.Position+1 < len(p.Input) && p.isDigit(p.Input[p.Position+1]) {
+		if p.Position+1 < len(p.Input) && p.isDigit(p.charAt(p.Position+1)) {
 			value.WriteByte('.')
 			p.advance()
 
-			for p.Position < len(p.Input) && p.isDigit(p.CurrentChar) {
-				value.WriteByte(p.CurrentChar)
+			p.readDigits(&value)
+		}
+	}
+
+	// Handle the exponent, which may be negative
+	if p.Position < len(p.Input) && p.CurrentChar == 'e' {
+		next := p.Position + 1
+		if next < len(p.Input) && p.charAt(next) == '-' {
+			next++
+		}
+		if next < len(p.Input) && p.isDigit(p.charAt(next)) {
+			// Copy the e and the optional minus sign
+			for p.Position < next {
+				value.WriteRune(p.CurrentChar)
 				p.advance()
 			}
+
+			p.readDigits(&value)
+		}
+	}
+
+	// Handle scaled decimals such as 1.25s2 or 1.25s
+	if p.Position < len(p.Input) && p.CurrentChar == 's' {
+		next := p.Position + 1
+		if next >= len(p.Input) || !p.isAlpha(p.charAt(next)) {
+			value.WriteByte('s')
+			p.advance()
+
+			p.readDigits(&value)
 		}
 	}
 
 	return Token{Type: TOKEN_NUMBER, Value: value.String()}
 }
 
+// readDigits appends the decimal digits at the current position to value
+func (p *Parser) readDigits(value *strings.Builder) {
+	for p.Position < len(p.Input) && p.isDigit(p.CurrentChar) {
+		value.WriteRune(p.CurrentChar)
+		p.advance()
+	}
+}
+
+// isRadixDigit returns true if the character is a digit or an uppercase letter
+func (p *Parser) isRadixDigit(c rune) bool {
+	return p.isDigit(c) || (c >= 'A' && c <= 'Z')
+}
+
+// parseCharacter parses a character literal such as $a
+func (p *Parser) parseCharacter() (Token, error) {
+	// Skip the $ character
+	p.advance()
+
+	if p.Position >= len(p.Input) {
+		return Token{}, fmt.Errorf("expected character after $")
+	}
+
+	// Any character, including whitespace and quotes, follows the $
+	value := string(p.CurrentChar)
+	p.advance()
+
+	return Token{Type: TOKEN_CHARACTER, Value: value}, nil
+}
+
+// parseBinarySelector parses a binary selector made of one or more binary characters.
+// A minus sign is only allowed as the first character, so that "3+-4" adds -4.
+func (p *Parser) parseBinarySelector() Token {
+	var value strings.Builder
+
+	value.WriteRune(p.CurrentChar)
+	p.advance()
+
+	for p.Position < len(p.Input) && p.isBinaryCharacter(p.CurrentChar) && p.CurrentChar != '-' {
+		value.WriteRune(p.CurrentChar)
+		p.advance()
+	}
+
+	return Token{Type: TOKEN_BINARY, Value: value.String()}
+}
+
 // parseSpecial parses a special character or assignment operator
 func (p *Parser) parseSpecial() Token {
 	// Check for assignment operator :=
-	if p.CurrentChar == ':' && p.Position+1 < len(p.Input) && p.Input[p.Position+1] == '=' {
+	if p.CurrentChar == ':' && p.Position+1 < len(p.Input) && p.charAt(p.Position+1) == '=' {
 		p.advance() // Skip :
 		p.advance() // Skip =
 		return Token{Type: TOKEN_ASSIGNMENT, Value: ":="}
@@ -967,16 +2048,19 @@ func (p *Parser) parseString() (Token, error) {
 	// Skip the opening quote
 	p.advance()
 
-	for p.Position < len(p.Input) && p.CurrentChar != '\'' {
-		// Handle escaped quotes
-		if p.CurrentChar == '\'' && p.Position+1 < len(p.Input) && p.Input[p.Position+1] == '\'' {
+	for p.Position < len(p.Input) {
+		// Handle escaped quotes, a single quote ends the string
+		if p.CurrentChar == '\'' {
+			if p.Position+1 >= len(p.Input) || p.charAt(p.Position+1) != '\'' {
+				break
+			}
 			value.WriteByte('\'')
 			p.advance() // Skip the first quote
 			p.advance() // Skip the second quote
 			continue
 		}
 
-		value.WriteByte(p.CurrentChar)
+		value.WriteRune(p.CurrentChar)
 		p.advance()
 	}
 
@@ -990,11 +2074,16 @@ func (p *Parser) parseString() (Token, error) {
 	return Token{Type: TOKEN_STRING, Value: value.String()}, nil
 }
 
-// parseSymbol parses a symbol
+// parseSymbol parses a symbol such as #foo, #at:put:, #+ or #'hello world',
+// or the opening of a literal array #( or byte array #[
 func (p *Parser) parseSymbol() (Token, error) {
 	// Skip the # character
 	p.advance()
 
+	if p.Position >= len(p.Input) {
+		return Token{}, fmt.Errorf("invalid symbol")
+	}
+
 	// If the next character is a quote, parse a string symbol
 	if p.CurrentChar == '\'' {
 		token, err := p.parseString()
@@ -1006,19 +2095,53 @@ func (p *Parser) parseSymbol() (Token, error) {
 
 	// If the next character is an opening parenthesis, it's an array literal
 	if p.CurrentChar == '(' {
-		// Return a special token for array literals
-		return Token{Type: TOKEN_SYMBOL, Value: "("}, nil
+		p.advance()
+		return Token{Type: TOKEN_LITERAL_ARRAY_START, Value: "#("}, nil
+	}
+
+	// If the next character is an opening bracket, it's a byte array literal
+	if p.CurrentChar == '[' {
+		p.advance()
+		return Token{Type: TOKEN_BYTE_ARRAY_START, Value: "#["}, nil
 	}
 
-	// Otherwise, parse an identifier symbol
+	// Parse an identifier or keyword symbol
 	if p.isAlpha(p.CurrentChar) {
-		token := p.parseIdentifier()
+		token := p.parseKeywordSequence()
 		return Token{Type: TOKEN_SYMBOL, Value: token.Value}, nil
 	}
 
+	// Parse a binary selector symbol, where any binary character may follow the first
+	if p.isBinaryCharacter(p.CurrentChar) || p.CurrentChar == '|' {
+		var value strings.Builder
+
+		for p.Position < len(p.Input) && (p.isBinaryCharacter(p.CurrentChar) || p.CurrentChar == '|') {
+			value.WriteRune(p.CurrentChar)
+			p.advance()
+		}
+
+		return Token{Type: TOKEN_SYMBOL, Value: value.String()}, nil
+	}
+
 	return Token{}, fmt.Errorf("invalid symbol")
 }
 
+// parseKeywordSequence parses an identifier, keeping keywords that follow a
+// keyword without whitespace, so at:put: becomes a single token
+func (p *Parser) parseKeywordSequence() Token {
+	var value strings.Builder
+
+	token := p.parseIdentifier()
+	value.WriteString(token.Value)
+
+	for strings.HasSuffix(token.Value, ":") && p.Position < len(p.Input) && p.isAlpha(p.CurrentChar) {
+		token = p.parseIdentifier()
+		value.WriteString(token.Value)
+	}
+
+	return Token{Type: TOKEN_IDENTIFIER, Value: value.String()}
+}
+
 // skipComment skips a comment
 func (p *Parser) skipComment() error {
 	// Skip the opening quote
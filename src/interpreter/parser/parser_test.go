@@ -50,9 +50,14 @@ func TestParseYourself(t *testing.T) {
 	}
 
 	// Check the method body
-	returnNode, ok := methodNode.Body.(*ast.ReturnNode)
+	sequenceNode, ok := methodNode.Body.(*ast.SequenceNode)
+	if !ok || len(sequenceNode.Statements) != 1 {
+		t.Fatalf("Expected sequence node with 1 statement, got %#v", methodNode.Body)
+	}
+
+	returnNode, ok := sequenceNode.Statements[0].(*ast.ReturnNode)
 	if !ok {
-		t.Fatalf("Expected return node, got %T", methodNode.Body)
+		t.Fatalf("Expected return node, got %T", sequenceNode.Statements[0])
 	}
 
 	// Check the return expression
@@ -112,9 +117,14 @@ func TestParseAdd(t *testing.T) {
 	}
 
 	// Check the method body
-	returnNode, ok := methodNode.Body.(*ast.ReturnNode)
+	sequenceNode, ok := methodNode.Body.(*ast.SequenceNode)
+	if !ok || len(sequenceNode.Statements) != 1 {
+		t.Fatalf("Expected sequence node with 1 statement, got %#v", methodNode.Body)
+	}
+
+	returnNode, ok := sequenceNode.Statements[0].(*ast.ReturnNode)
 	if !ok {
-		t.Fatalf("Expected return node, got %T", methodNode.Body)
+		t.Fatalf("Expected return node, got %T", sequenceNode.Statements[0])
 	}
 
 	// Check the return expression
@@ -246,9 +256,14 @@ func TestParseWithBlock(t *testing.T) {
 	}
 
 	// Check the method body
-	returnNode, ok := methodNode.Body.(*ast.ReturnNode)
+	sequenceNode, ok := methodNode.Body.(*ast.SequenceNode)
+	if !ok || len(sequenceNode.Statements) != 1 {
+		t.Fatalf("Expected sequence node with 1 statement, got %#v", methodNode.Body)
+	}
+
+	returnNode, ok := sequenceNode.Statements[0].(*ast.ReturnNode)
 	if !ok {
-		t.Fatalf("Expected return node, got %T", methodNode.Body)
+		t.Fatalf("Expected return node, got %T", sequenceNode.Statements[0])
 	}
 
 	// Check the return expression
@@ -334,9 +349,14 @@ func TestParseBlockValueMessage(t *testing.T) {
 	}
 
 	// Check the block body
-	literalNode, ok := blockNode.Body.(*ast.LiteralNode)
+	sequenceNode, ok := blockNode.Body.(*ast.SequenceNode)
+	if !ok || len(sequenceNode.Statements) != 1 {
+		t.Fatalf("Expected sequence node with 1 statement, got %#v", blockNode.Body)
+	}
+
+	literalNode, ok := sequenceNode.Statements[0].(*ast.LiteralNode)
 	if !ok {
-		t.Fatalf("Expected literal node, got %T", blockNode.Body)
+		t.Fatalf("Expected literal node, got %T", sequenceNode.Statements[0])
 	}
 
 	// Check that the literal is 5
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestParsePragmas tests parsing pragmas before and after the temporaries
+func TestParsePragmas(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input     string
+		selectors []string
+		arguments []int
+	}{
+		{"foo <primitive: 1> ^self", []string{"primitive:"}, []int{1}},
+		{"foo | a | <primitive: 1> ^a", []string{"primitive:"}, []int{1}},
+		{"foo <primitive: 60 error: ec> | a | <inline> ^a", []string{"primitive:error:", "inline"}, []int{2, 0}},
+		{"foo <key: 'a' value: #(1 2) flag: true other: nil> ^self", []string{"key:value:flag:other:"}, []int{4}},
+		{"foo ^self", []string{}, []int{}},
+	}
+
+	for _, test := range tests {
+		p := NewParser(test.input, nil, vmInstance)
+		node, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Error parsing '%s': %v", test.input, err)
+		}
+
+		method := node.(*ast.MethodNode)
+		if len(method.Pragmas) != len(test.selectors) {
+			t.Fatalf("Expected %d pragmas for '%s', got %d", len(test.selectors), test.input, len(method.Pragmas))
+		}
+		for i, pragma := range method.Pragmas {
+			if pragma.Selector != test.selectors[i] {
+				t.Errorf("Expected pragma %s for '%s', got %s", test.selectors[i], test.input, pragma.Selector)
+			}
+			if len(pragma.Arguments) != test.arguments[i] {
+				t.Errorf("Expected %d arguments for pragma %s, got %d", test.arguments[i], pragma.Selector, len(pragma.Arguments))
+			}
+		}
+	}
+}
+
+// TestParsePragmaNodes tests the arguments and range of a pragma
+func TestParsePragmaNodes(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("at: i <primitive: 60 error: ec> ^ec", nil, vmInstance)
+	node, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing method: %v", err)
+	}
+
+	method := node.(*ast.MethodNode)
+	pragma := method.Pragma("primitive:error:")
+	if pragma == nil {
+		t.Fatalf("Expected primitive:error: pragma")
+	}
+	if _, ok := pragma.Arguments[0].(*ast.LiteralNode); !ok {
+		t.Errorf("Expected primitive index to be a LiteralNode, got %T", pragma.Arguments[0])
+	}
+	if variable, ok := pragma.Arguments[1].(*ast.VariableNode); !ok || variable.Name != "ec" {
+		t.Errorf("Expected error code to be the variable ec, got %#v", pragma.Arguments[1])
+	}
+	if pragma.Source.Start.Offset != 6 || pragma.Source.End.Offset != 31 {
+		t.Errorf("Expected pragma range 6-31, got %d-%d", pragma.Source.Start.Offset, pragma.Source.End.Offset)
+	}
+
+	// The body still starts at the pragma
+	if method.Body.Range().Start.Offset != 6 {
+		t.Errorf("Expected body to start at 6, got %d", method.Body.Range().Start.Offset)
+	}
+}
+
+// TestParsePragmaErrors tests that malformed pragmas are rejected
+func TestParsePragmaErrors(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{"foo <primitive: 1 ^self", CodeMissingDelimiter},
+		{"foo <1> ^self", CodeExpectedSelector},
+		{"foo <primitive: [1]> ^self", CodeInvalidLiteral},
+		{"foo <primitive: (1 + 2)> ^self", CodeInvalidLiteral},
+	}
+
+	for _, test := range tests {
+		p := NewParser(test.input, nil, vmInstance)
+		_, err := p.Parse()
+		if err == nil {
+			t.Errorf("Expected error parsing '%s'", test.input)
+			continue
+		}
+		if parseError, ok := err.(*ParseError); !ok || parseError.Code != test.code {
+			t.Errorf("Expected %s error parsing '%s', got %v", test.code, test.input, err)
+		}
+	}
+
+	// When recovering, the broken pragma is skipped
+	p := NewParser("foo <1> <inline> ^self", nil, vmInstance)
+	node, diagnostics := p.ParseWithDiagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	method := node.(*ast.MethodNode)
+	if len(method.Pragmas) != 1 || method.Pragmas[0].Selector != "inline" {
+		t.Errorf("Expected the inline pragma to be parsed, got %v", method.Pragmas)
+	}
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestParsePseudoVariables tests parsing super, thisContext and nil
+func TestParsePseudoVariables(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("super foo: thisContext bar: nil", nil, vmInstance)
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	send, ok := node.(*ast.MessageSendNode)
+	if !ok {
+		t.Fatalf("Expected MessageSendNode, got %T", node)
+	}
+	if superNode, ok := send.Receiver.(*ast.SuperNode); !ok {
+		t.Errorf("Expected receiver to be a SuperNode, got %T", send.Receiver)
+	} else if superNode.Source.Start.Offset != 0 || superNode.Source.End.Offset != 5 {
+		t.Errorf("Expected super at 0-5, got %s", superNode.Source)
+	}
+	if _, ok := send.Arguments[0].(*ast.ThisContextNode); !ok {
+		t.Errorf("Expected first argument to be a ThisContextNode, got %T", send.Arguments[0])
+	}
+	if literal, ok := send.Arguments[1].(*ast.LiteralNode); !ok || !pile.IsNilImmediate(literal.Value) {
+		t.Errorf("Expected second argument to be the nil literal, got %#v", send.Arguments[1])
+	}
+}
+
+// TestAssignToPseudoVariable tests that assignments to pseudo-variables are rejected
+func TestAssignToPseudoVariable(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	for _, name := range []string{"self", "super", "thisContext", "nil", "true", "false"} {
+		input := "x := 1. " + name + " := 3"
+		p := NewParser(input, nil, vmInstance)
+		_, err := p.ParseExpression()
+		if err == nil {
+			t.Errorf("Expected error parsing '%s'", input)
+			continue
+		}
+
+		parseError, ok := err.(*ParseError)
+		if !ok || parseError.Code != CodeInvalidAssignment {
+			t.Errorf("Expected %s error parsing '%s', got %v", CodeInvalidAssignment, input, err)
+			continue
+		}
+		if parseError.Message != "cannot assign to pseudo-variable "+name {
+			t.Errorf("Unexpected message for '%s': %s", input, parseError.Message)
+		}
+		if parseError.Range.Start.Offset != 8 || parseError.Range.End.Offset != 8+len(name) {
+			t.Errorf("Expected error to cover %s, got %s", name, parseError.Range)
+		}
+	}
+
+	// When recovering, the assignment is reported and still parsed
+	p := NewParser("self := 3. x := 4", nil, vmInstance)
+	node, diagnostics := p.ParseExpressionWithDiagnostics()
+	if len(diagnostics) != 1 || diagnostics[0].Code != CodeInvalidAssignment {
+		t.Fatalf("Expected one %s diagnostic, got %v", CodeInvalidAssignment, diagnostics)
+	}
+	sequence := node.(*ast.SequenceNode)
+	if len(sequence.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(sequence.Statements))
+	}
+	if _, ok := sequence.Statements[0].(*ast.AssignmentNode); !ok {
+		t.Errorf("Expected the invalid assignment to be kept, got %T", sequence.Statements[0])
+	}
+}
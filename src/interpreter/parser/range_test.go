@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestTokenRanges tests the offsets, lines and columns recorded for tokens
+func TestTokenRanges(t *testing.T) {
+	p := NewParser("x := 3 + 4.\n  'ab' foo: #bar", nil, nil)
+	if err := p.tokenize(); err != nil {
+		t.Fatalf("Error tokenizing: %v", err)
+	}
+
+	expected := []struct {
+		value      string
+		start, end int
+		line, col  int
+	}{
+		{"x", 0, 1, 1, 1},
+		{":=", 2, 4, 1, 3},
+		{"3", 5, 6, 1, 6},
+		{"+", 7, 8, 1, 8},
+		{"4", 9, 10, 1, 10},
+		{".", 10, 11, 1, 11},
+		{"ab", 14, 18, 2, 3},
+		{"foo:", 19, 23, 2, 8},
+		{"bar", 24, 28, 2, 13},
+		{"", 28, 28, 2, 17},
+	}
+
+	if len(p.Tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(p.Tokens))
+	}
+	for i, e := range expected {
+		r := p.Tokens[i].Range()
+		if p.Tokens[i].Value != e.value {
+			t.Errorf("Expected token %d to be %q, got %q", i, e.value, p.Tokens[i].Value)
+		}
+		if r.Start.Offset != e.start || r.End.Offset != e.end {
+			t.Errorf("Expected token %d to span %d-%d, got %d-%d", i, e.start, e.end, r.Start.Offset, r.End.Offset)
+		}
+		if r.Start.Line != e.line || r.Start.Column != e.col {
+			t.Errorf("Expected token %d to start at %d:%d, got %s", i, e.line, e.col, r.Start)
+		}
+	}
+}
+
+// TestNodeRanges tests the ranges recorded for AST nodes
+func TestNodeRanges(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	input := "| a |\na := (3 + 4) * 2.\n^ {a. [:x | x]} foo; bar"
+	p := NewParser(input, nil, vmInstance)
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	source := func(n ast.Node) string {
+		r := n.Range()
+		return input[r.Start.Offset:r.End.Offset]
+	}
+
+	sequence := node.(*ast.SequenceNode)
+	if source(sequence) != input {
+		t.Errorf("Expected sequence to cover the whole input, got %q", source(sequence))
+	}
+
+	assignment := sequence.Statements[0].(*ast.AssignmentNode)
+	if source(assignment) != "a := (3 + 4) * 2" {
+		t.Errorf("Unexpected assignment source %q", source(assignment))
+	}
+	if assignment.Range().Start.Line != 2 || assignment.Range().Start.Column != 1 {
+		t.Errorf("Expected assignment to start at 2:1, got %s", assignment.Range().Start)
+	}
+
+	// The parentheses are part of the message send but not of the inner expression
+	product := assignment.Expression.(*ast.MessageSendNode)
+	if source(product) != "(3 + 4) * 2" {
+		t.Errorf("Unexpected product source %q", source(product))
+	}
+	if source(product.Receiver) != "3 + 4" {
+		t.Errorf("Unexpected sum source %q", source(product.Receiver))
+	}
+	if source(product.Arguments[0]) != "2" {
+		t.Errorf("Unexpected argument source %q", source(product.Arguments[0]))
+	}
+
+	returnNode := sequence.Statements[1].(*ast.ReturnNode)
+	if source(returnNode) != "^ {a. [:x | x]} foo; bar" {
+		t.Errorf("Unexpected return source %q", source(returnNode))
+	}
+
+	cascade := returnNode.Expression.(*ast.CascadeNode)
+	if source(cascade) != "{a. [:x | x]} foo; bar" {
+		t.Errorf("Unexpected cascade source %q", source(cascade))
+	}
+	if source(cascade.Messages[1]) != "{a. [:x | x]} foo; bar" {
+		t.Errorf("Unexpected cascade part source %q", source(cascade.Messages[1]))
+	}
+
+	array := cascade.Receiver.(*ast.DynamicArrayNode)
+	if source(array) != "{a. [:x | x]}" {
+		t.Errorf("Unexpected array source %q", source(array))
+	}
+	block := array.Elements[1].(*ast.BlockNode)
+	if source(block) != "[:x | x]" {
+		t.Errorf("Unexpected block source %q", source(block))
+	}
+	if source(block.Body) != "x" {
+		t.Errorf("Unexpected block body source %q", source(block.Body))
+	}
+}
+
+// TestMethodRange tests that a method node covers the whole method source
+func TestMethodRange(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	input := "at: index put: value\n\t^ #(1 2) at: index"
+	p := NewParser(input, nil, vmInstance)
+	node, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing method: %v", err)
+	}
+
+	r := node.Range()
+	if r.Start.Offset != 0 || r.End.Offset != len(input) {
+		t.Errorf("Expected method to span 0-%d, got %d-%d", len(input), r.Start.Offset, r.End.Offset)
+	}
+	if r.End.Line != 2 {
+		t.Errorf("Expected method to end on line 2, got %d", r.End.Line)
+	}
+}
+
+// TestParseErrorRanges tests that parse errors report where they happened
+func TestParseErrorRanges(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input     string
+		line, col int
+	}{
+		{"3 + 4.\n  (5 + 6", 2, 9},   // missing closing parenthesis at the end
+		{"x := 3.\ny := 'abc", 2, 6}, // unterminated string
+		{"3 +\n\n    ]", 3, 5},       // unexpected bracket
+		{"a foo.\n  b ` c", 2, 5},    // unknown character
+		{"{1 2}", 1, 4},              // missing period in brace array
+	}
+
+	for _, test := range tests {
+		p := NewParser(test.input, nil, vmInstance)
+		_, err := p.ParseExpression()
+		if err == nil {
+			t.Errorf("Expected error parsing %q", test.input)
+			continue
+		}
+
+		var parseError *ParseError
+		if !errors.As(err, &parseError) {
+			t.Errorf("Expected a ParseError for %q, got %T", test.input, err)
+			continue
+		}
+		if parseError.Range.Start.Line != test.line || parseError.Range.Start.Column != test.col {
+			t.Errorf("Expected error for %q at %d:%d, got %s", test.input, test.line, test.col, parseError.Range.Start)
+		}
+		if !strings.HasPrefix(err.Error(), "line ") {
+			t.Errorf("Expected error message to start with the line, got %q", err.Error())
+		}
+	}
+}
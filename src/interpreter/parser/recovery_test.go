@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestRecoverBrokenStatements tests that broken statements become error nodes
+// while the statements around them are still parsed
+func TestRecoverBrokenStatements(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("a := 3 + . b foo. c := ) 4. d bar", nil, vmInstance)
+	node, diagnostics := p.ParseExpressionWithDiagnostics()
+
+	sequence, ok := node.(*ast.SequenceNode)
+	if !ok {
+		t.Fatalf("Expected SequenceNode, got %T", node)
+	}
+
+	// a := 3 + . | b foo | c := ) | 4 | d bar
+	expectedTypes := []string{"error", "send", "error", "literal", "send"}
+	if len(sequence.Statements) != len(expectedTypes) {
+		t.Fatalf("Expected %d statements, got %d", len(expectedTypes), len(sequence.Statements))
+	}
+	for i, statement := range sequence.Statements {
+		var actual string
+		switch statement.(type) {
+		case *ast.ErrorNode:
+			actual = "error"
+		case *ast.MessageSendNode:
+			actual = "send"
+		case *ast.LiteralNode:
+			actual = "literal"
+		default:
+			actual = "other"
+		}
+		if actual != expectedTypes[i] {
+			t.Errorf("Expected statement %d to be %s, got %T", i, expectedTypes[i], statement)
+		}
+	}
+
+	// The error node covers the broken statement
+	errorNode := sequence.Statements[0].(*ast.ErrorNode)
+	if errorNode.Range().Start.Offset != 0 || errorNode.Range().End.Offset != 8 {
+		t.Errorf("Expected the first error node to span 0-8, got %s", errorNode.Range())
+	}
+
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity != ast.SeverityError || diagnostic.Code != CodeExpectedExpression {
+			t.Errorf("Expected an expected-expression error, got %s", diagnostic)
+		}
+	}
+	if diagnostics[1].Range.Start.Column != 24 {
+		t.Errorf("Expected the second diagnostic at column 24, got %s", diagnostics[1].Range.Start)
+	}
+}
+
+// TestRecoverDiagnostics tests the diagnostics reported for common mistakes
+func TestRecoverDiagnostics(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	tests := []struct {
+		input string
+		codes []string
+	}{
+		{"3 + 4", nil},
+		{"a foo b: 3 c", nil},
+		{"(3 + 4", []string{CodeMissingDelimiter}},
+		{"[:x | x + 1", []string{CodeMissingDelimiter}},
+		{"[:x x]", []string{CodeMissingDelimiter}},
+		{"| a b . a := 3", []string{CodeMissingDelimiter}},
+		{"3 4", []string{CodeExpectedPeriod}},
+		{"x := 'abc", []string{CodeInvalidToken}},
+		{"x := ` + 3", []string{CodeInvalidToken}},
+		{"3 + 4] foo", []string{CodeUnexpectedToken}},
+		{"{1 2. 3}. x", []string{CodeExpectedPeriod}},
+		{"x := (3 +). y := 16r. z := 1 + #( 2", []string{CodeExpectedExpression, CodeMissingDelimiter}},
+		{"a := 3 + ! b := 4", []string{CodeExpectedExpression}},
+		{"x foo; + ; bar", []string{CodeExpectedExpression}},
+	}
+
+	for _, test := range tests {
+		p := NewParser(test.input, nil, vmInstance)
+		node, diagnostics := p.ParseExpressionWithDiagnostics()
+		if node == nil {
+			t.Errorf("Expected a partial AST for %q", test.input)
+			continue
+		}
+
+		if len(diagnostics) != len(test.codes) {
+			t.Errorf("Expected %d diagnostics for %q, got %v", len(test.codes), test.input, diagnostics)
+			continue
+		}
+		for i, diagnostic := range diagnostics {
+			if diagnostic.Code != test.codes[i] {
+				t.Errorf("Expected diagnostic %d for %q to be %s, got %s", i, test.input, test.codes[i], diagnostic)
+			}
+		}
+	}
+}
+
+// TestRecoverNestedBlock tests that an error inside a block doesn't break the enclosing statements
+func TestRecoverNestedBlock(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("x do: [:each | each + ]. y", nil, vmInstance)
+	node, diagnostics := p.ParseExpressionWithDiagnostics()
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+
+	sequence := node.(*ast.SequenceNode)
+	if len(sequence.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(sequence.Statements))
+	}
+
+	send, ok := sequence.Statements[0].(*ast.MessageSendNode)
+	if !ok || send.Selector != "do:" {
+		t.Fatalf("Expected do: message, got %#v", sequence.Statements[0])
+	}
+	block := send.Arguments[0].(*ast.BlockNode)
+	body := block.Body.(*ast.SequenceNode)
+	if _, ok := body.Statements[0].(*ast.ErrorNode); !ok {
+		t.Errorf("Expected block statement to be an ErrorNode, got %T", body.Statements[0])
+	}
+
+	if _, ok := sequence.Statements[1].(*ast.VariableNode); !ok {
+		t.Errorf("Expected second statement to be a VariableNode, got %T", sequence.Statements[1])
+	}
+}
+
+// TestRecoverMethod tests recovering from errors in a method
+func TestRecoverMethod(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("at: index put:\n\t| a |\n\ta := index + .\n\t^ a", nil, vmInstance)
+	node, diagnostics := p.ParseWithDiagnostics()
+
+	method, ok := node.(*ast.MethodNode)
+	if !ok {
+		t.Fatalf("Expected MethodNode, got %T", node)
+	}
+	if len(method.Temporaries) != 1 {
+		t.Errorf("Expected 1 temporary, got %v", method.Temporaries)
+	}
+
+	body := method.Body.(*ast.SequenceNode)
+	if len(body.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(body.Statements))
+	}
+	if _, ok := body.Statements[1].(*ast.ReturnNode); !ok {
+		t.Errorf("Expected last statement to be a ReturnNode, got %T", body.Statements[1])
+	}
+
+	codes := []string{CodeExpectedIdentifier, CodeExpectedExpression}
+	if len(diagnostics) != len(codes) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(codes), diagnostics)
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.Code != codes[i] {
+			t.Errorf("Expected diagnostic %d to be %s, got %s", i, codes[i], diagnostic)
+		}
+	}
+	if diagnostics[1].Range.Start.Line != 3 {
+		t.Errorf("Expected second diagnostic on line 3, got %s", diagnostics[1].Range.Start)
+	}
+}
+
+// TestParseWithoutRecovery tests that the regular entry points still stop at the first error
+func TestParseWithoutRecovery(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	p := NewParser("a := 3 + . b := ", nil, vmInstance)
+	node, err := p.ParseExpression()
+	if err == nil {
+		t.Fatalf("Expected an error, got %#v", node)
+	}
+
+	parseError, ok := err.(*ParseError)
+	if !ok || parseError.Code != CodeExpectedExpression {
+		t.Errorf("Expected an expected-expression ParseError, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestParseDoItSequence tests parsing a doIt with temporaries and several statements
+func TestParseDoItSequence(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	// Create a parser with the test input
+	p := NewParser("| a b | a := 3. b := a + 1. b", nil, vmInstance)
+
+	// Parse the expression
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	// Check if it's a sequence node
+	sequenceNode, ok := node.(*ast.SequenceNode)
+	if !ok {
+		t.Fatalf("Expected SequenceNode, got %T", node)
+	}
+
+	// Check the temporaries
+	if len(sequenceNode.Temporaries) != 2 || sequenceNode.Temporaries[0] != "a" || sequenceNode.Temporaries[1] != "b" {
+		t.Errorf("Expected temporaries [a b], got %v", sequenceNode.Temporaries)
+	}
+
+	// Check the statements
+	if len(sequenceNode.Statements) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(sequenceNode.Statements))
+	}
+	if _, ok := sequenceNode.Statements[0].(*ast.AssignmentNode); !ok {
+		t.Errorf("Expected statement 0 to be an assignment, got %T", sequenceNode.Statements[0])
+	}
+	if _, ok := sequenceNode.Statements[1].(*ast.AssignmentNode); !ok {
+		t.Errorf("Expected statement 1 to be an assignment, got %T", sequenceNode.Statements[1])
+	}
+	if variable, ok := sequenceNode.Statements[2].(*ast.VariableNode); !ok || variable.Name != "b" {
+		t.Errorf("Expected statement 2 to be variable b, got %#v", sequenceNode.Statements[2])
+	}
+}
+
+// TestParseMethodSequence tests parsing a keyword method with several statements
+func TestParseMethodSequence(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	// Create a parser with the test input
+	p := NewParser("at: index put: value | old | old := index. ^old + value.", nil, vmInstance)
+
+	// Parse the method
+	node, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing method: %v", err)
+	}
+
+	methodNode, ok := node.(*ast.MethodNode)
+	if !ok {
+		t.Fatalf("Expected MethodNode, got %T", node)
+	}
+
+	// Check the selector and parameters
+	if methodNode.Selector != "at:put:" {
+		t.Errorf("Expected selector 'at:put:', got '%s'", methodNode.Selector)
+	}
+	if len(methodNode.Parameters) != 2 || methodNode.Parameters[0] != "index" || methodNode.Parameters[1] != "value" {
+		t.Errorf("Expected parameters [index value], got %v", methodNode.Parameters)
+	}
+
+	// Check the body
+	sequenceNode, ok := methodNode.Body.(*ast.SequenceNode)
+	if !ok {
+		t.Fatalf("Expected SequenceNode body, got %T", methodNode.Body)
+	}
+	if len(sequenceNode.Temporaries) != 1 || sequenceNode.Temporaries[0] != "old" {
+		t.Errorf("Expected temporaries [old], got %v", sequenceNode.Temporaries)
+	}
+	if len(sequenceNode.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(sequenceNode.Statements))
+	}
+	if _, ok := sequenceNode.Statements[1].(*ast.ReturnNode); !ok {
+		t.Errorf("Expected the last statement to be a return, got %T", sequenceNode.Statements[1])
+	}
+}
+
+// TestParseBlockSequence tests parsing a block with parameters, temporaries and several statements
+func TestParseBlockSequence(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	// Create a parser with the test input
+	p := NewParser("[:x :y | | sum | sum := x + y. sum * 2]", nil, vmInstance)
+
+	// Parse the expression
+	node, err := p.ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing expression: %v", err)
+	}
+
+	blockNode, ok := node.(*ast.BlockNode)
+	if !ok {
+		t.Fatalf("Expected BlockNode, got %T", node)
+	}
+
+	// Check the parameters and temporaries
+	if len(blockNode.Parameters) != 2 || blockNode.Parameters[0] != "x" || blockNode.Parameters[1] != "y" {
+		t.Errorf("Expected parameters [x y], got %v", blockNode.Parameters)
+	}
+	if len(blockNode.Temporaries) != 1 || blockNode.Temporaries[0] != "sum" {
+		t.Errorf("Expected temporaries [sum], got %v", blockNode.Temporaries)
+	}
+
+	// Check the statements
+	sequenceNode, ok := blockNode.Body.(*ast.SequenceNode)
+	if !ok {
+		t.Fatalf("Expected SequenceNode body, got %T", blockNode.Body)
+	}
+	if len(sequenceNode.Statements) != 2 {
+		t.Errorf("Expected 2 statements, got %d", len(sequenceNode.Statements))
+	}
+}
+
+// TestParseEmptyBlock tests parsing blocks without statements
+func TestParseEmptyBlock(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	for _, input := range []string{"[]", "[:x]", "[:x | ]", "[ | t | ]"} {
+		p := NewParser(input, nil, vmInstance)
+
+		node, err := p.ParseExpression()
+		if err != nil {
+			t.Fatalf("Error parsing '%s': %v", input, err)
+		}
+
+		blockNode, ok := node.(*ast.BlockNode)
+		if !ok {
+			t.Fatalf("Expected BlockNode for '%s', got %T", input, node)
+		}
+
+		sequenceNode, ok := blockNode.Body.(*ast.SequenceNode)
+		if !ok || len(sequenceNode.Statements) != 0 {
+			t.Errorf("Expected empty SequenceNode for '%s', got %#v", input, blockNode.Body)
+		}
+	}
+}
+
+// TestParseSequenceErrors tests that malformed statement lists are rejected
+func TestParseSequenceErrors(t *testing.T) {
+	// Create a VM for testing
+	vmInstance := vm.NewVM()
+
+	inputs := []string{
+		"a := 3 b := 4", // missing period between statements
+		"[3. 4",         // missing closing bracket
+		"3. 4]",         // unexpected closing bracket
+		"| a b",         // unterminated temporaries
+	}
+
+	for _, input := range inputs {
+		p := NewParser(input, nil, vmInstance)
+		if _, err := p.ParseExpression(); err == nil {
+			t.Errorf("Expected error parsing '%s'", input)
+		}
+	}
+}
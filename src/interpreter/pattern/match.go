@@ -0,0 +1,361 @@
+package pattern
+
+import (
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/pile"
+)
+
+// Bindings holds what the metavariables of a pattern matched
+type Bindings struct {
+	// nodes holds the node matched by each single metavariable
+	nodes map[string]ast.Node
+
+	// lists holds the nodes matched by each list metavariable
+	lists map[string][]ast.Node
+
+	// selectors holds the selector matched by each selector metavariable
+	selectors map[string]string
+}
+
+// newBindings returns empty bindings
+func newBindings() *Bindings {
+	return &Bindings{
+		nodes:     map[string]ast.Node{},
+		lists:     map[string][]ast.Node{},
+		selectors: map[string]string{},
+	}
+}
+
+// clone returns a copy of the bindings to try a match that may fail
+func (b *Bindings) clone() *Bindings {
+	c := newBindings()
+	for name, node := range b.nodes {
+		c.nodes[name] = node
+	}
+	for name, list := range b.lists {
+		c.lists[name] = list
+	}
+	for name, selector := range b.selectors {
+		c.selectors[name] = selector
+	}
+	return c
+}
+
+// Node returns the node matched by a metavariable, or nil
+func (b *Bindings) Node(name string) ast.Node {
+	return b.nodes[name]
+}
+
+// List returns the nodes matched by a list metavariable, or nil
+func (b *Bindings) List(name string) []ast.Node {
+	return b.lists[name]
+}
+
+// Selector returns the selector matched by a selector metavariable, or an empty string
+func (b *Bindings) Selector(name string) string {
+	return b.selectors[name]
+}
+
+// Match is a node found by a pattern
+type Match struct {
+	// Node is the node matched by the whole pattern
+	Node ast.Node
+
+	// Bindings holds what the metavariables matched
+	Bindings *Bindings
+}
+
+// Match matches a node against the pattern
+func (p *Pattern) Match(node ast.Node) (*Bindings, bool) {
+	b := newBindings()
+	if !b.match(p.node, node) {
+		return nil, false
+	}
+	return b, true
+}
+
+// Find returns the nodes of an AST that match the pattern, outer nodes
+// before the nodes inside them
+func (p *Pattern) Find(root ast.Node) []Match {
+	var matches []Match
+	ast.Inspect(root, func(node ast.Node) bool {
+		if node == nil {
+			return false
+		}
+		if b, ok := p.Match(node); ok {
+			matches = append(matches, Match{Node: node, Bindings: b})
+		}
+		return true
+	})
+	return matches
+}
+
+// Equal returns true if two ASTs are the same apart from their source
+// ranges and comments
+func Equal(a ast.Node, b ast.Node) bool {
+	// Without metavariables, matching compares the nodes
+	return newBindings().match(a, b)
+}
+
+// match matches a node against a pattern node, adding to the bindings
+func (b *Bindings) match(pattern ast.Node, node ast.Node) bool {
+	if pattern == nil || node == nil {
+		return pattern == nil && node == nil
+	}
+
+	if name, k, ok := metavariable(pattern); ok {
+		return b.bind(name, k, node)
+	}
+
+	switch p := pattern.(type) {
+	case *ast.MethodNode:
+		n, ok := node.(*ast.MethodNode)
+		return ok &&
+			b.matchSelector(p.Selector, n.Selector) &&
+			b.matchNames(p.Parameters, n.Parameters) &&
+			b.matchNames(p.Temporaries, n.Temporaries) &&
+			b.matchList(pragmaNodes(p.Pragmas), pragmaNodes(n.Pragmas), false) &&
+			b.match(p.Body, n.Body)
+
+	case *ast.ReturnNode:
+		n, ok := node.(*ast.ReturnNode)
+		return ok && b.match(p.Expression, n.Expression)
+
+	case *ast.SelfNode:
+		_, ok := node.(*ast.SelfNode)
+		return ok
+
+	case *ast.SuperNode:
+		_, ok := node.(*ast.SuperNode)
+		return ok
+
+	case *ast.ThisContextNode:
+		_, ok := node.(*ast.ThisContextNode)
+		return ok
+
+	case *ast.LiteralNode:
+		n, ok := node.(*ast.LiteralNode)
+		return ok && equalLiterals(p, n)
+
+	case *ast.VariableNode:
+		n, ok := node.(*ast.VariableNode)
+		return ok && p.Name == n.Name
+
+	case *ast.GlobalNode:
+		n, ok := node.(*ast.GlobalNode)
+		return ok && p.Name == n.Name
+
+	case *ast.AssignmentNode:
+		n, ok := node.(*ast.AssignmentNode)
+		return ok &&
+			b.matchNames([]string{p.Variable}, []string{n.Variable}) &&
+			b.match(p.Expression, n.Expression)
+
+	case *ast.MessageSendNode:
+		n, ok := node.(*ast.MessageSendNode)
+		return ok &&
+			b.match(p.Receiver, n.Receiver) &&
+			b.matchMessage(p, n)
+
+	case *ast.BlockNode:
+		n, ok := node.(*ast.BlockNode)
+		return ok &&
+			b.matchNames(p.Parameters, n.Parameters) &&
+			b.matchNames(p.Temporaries, n.Temporaries) &&
+			b.match(p.Body, n.Body)
+
+	case *ast.CascadeNode:
+		n, ok := node.(*ast.CascadeNode)
+		if !ok || len(p.Messages) != len(n.Messages) || !b.match(p.Receiver, n.Receiver) {
+			return false
+		}
+		for i, message := range p.Messages {
+			if !b.matchCascadePart(message, n.Messages[i], p.Receiver, n.Receiver) {
+				return false
+			}
+		}
+		return true
+
+	case *ast.SequenceNode:
+		n, ok := node.(*ast.SequenceNode)
+		return ok &&
+			b.matchNames(p.Temporaries, n.Temporaries) &&
+			b.matchList(p.Statements, n.Statements, false)
+
+	case *ast.DynamicArrayNode:
+		n, ok := node.(*ast.DynamicArrayNode)
+		return ok && b.matchList(p.Elements, n.Elements, false)
+
+	case *ast.PragmaNode:
+		n, ok := node.(*ast.PragmaNode)
+		return ok && b.matchMessage(&ast.MessageSendNode{Selector: p.Selector, Arguments: p.Arguments},
+			&ast.MessageSendNode{Selector: n.Selector, Arguments: n.Arguments})
+	}
+
+	// Error nodes never match
+	return false
+}
+
+// bind binds a metavariable to a node, or checks that the node is equal to
+// the one it is bound to
+func (b *Bindings) bind(name string, k kind, node ast.Node) bool {
+	switch k {
+	case variableKind:
+		switch node.(type) {
+		case *ast.VariableNode, *ast.GlobalNode:
+		default:
+			return false
+		}
+	case literalKind:
+		if _, ok := node.(*ast.LiteralNode); !ok {
+			return false
+		}
+	case listKind:
+		return b.bindList(name, []ast.Node{node})
+	}
+
+	if bound, ok := b.nodes[name]; ok {
+		return Equal(bound, node)
+	}
+	if _, ok := b.lists[name]; ok {
+		return false
+	}
+
+	b.nodes[name] = node
+	return true
+}
+
+// bindList binds a list metavariable to a list of nodes, or checks that the
+// nodes are equal to the ones it is bound to
+func (b *Bindings) bindList(name string, nodes []ast.Node) bool {
+	if bound, ok := b.lists[name]; ok {
+		if len(bound) != len(nodes) {
+			return false
+		}
+		for i := range bound {
+			if !Equal(bound[i], nodes[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if _, ok := b.nodes[name]; ok {
+		return false
+	}
+
+	b.lists[name] = nodes
+	return true
+}
+
+// matchMessage matches the selector and arguments of a message
+func (b *Bindings) matchMessage(pattern *ast.MessageSendNode, node *ast.MessageSendNode) bool {
+	if !b.matchSelector(pattern.Selector, node.Selector) || len(pattern.Arguments) != len(node.Arguments) {
+		return false
+	}
+
+	for i, argument := range pattern.Arguments {
+		if !b.match(argument, node.Arguments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCascadePart matches a message part of a cascade, whose receiver
+// chain ends in the receiver of the cascade
+func (b *Bindings) matchCascadePart(pattern *ast.MessageSendNode, node *ast.MessageSendNode, patternReceiver ast.Node, nodeReceiver ast.Node) bool {
+	if !b.matchMessage(pattern, node) {
+		return false
+	}
+
+	if pattern.Receiver == patternReceiver || node.Receiver == nodeReceiver {
+		return pattern.Receiver == patternReceiver && node.Receiver == nodeReceiver
+	}
+
+	p, ok := pattern.Receiver.(*ast.MessageSendNode)
+	if !ok {
+		return false
+	}
+	n, ok := node.Receiver.(*ast.MessageSendNode)
+	return ok && b.matchCascadePart(p, n, patternReceiver, nodeReceiver)
+}
+
+// matchSelector matches a selector, which may be a metavariable
+func (b *Bindings) matchSelector(pattern string, selector string) bool {
+	name, ok := selectorMetavariable(pattern)
+	if !ok {
+		return pattern == selector
+	}
+	if numArgs(pattern) != numArgs(selector) {
+		return false
+	}
+
+	if bound, ok := b.selectors[name]; ok {
+		return bound == selector
+	}
+	b.selectors[name] = selector
+	return true
+}
+
+// matchNames matches the names of parameters, temporaries or an assigned
+// variable, in which `@name matches a list of names
+func (b *Bindings) matchNames(pattern []string, names []string) bool {
+	return b.matchList(nameNodes(pattern), nameNodes(names), true)
+}
+
+// matchList matches a list of nodes against a list of pattern nodes, in
+// which list metavariables match any number of nodes. In a list of names,
+// `@name is a list metavariable too.
+func (b *Bindings) matchList(patterns []ast.Node, nodes []ast.Node, names bool) bool {
+	if len(patterns) == 0 {
+		return len(nodes) == 0
+	}
+
+	name, k, ok := metavariable(patterns[0])
+	if ok && (k == listKind || names && k == expressionKind) {
+		for count := 0; count <= len(nodes); count++ {
+			c := b.clone()
+			if c.bindList(name, nodes[:count]) && c.matchList(patterns[1:], nodes[count:], names) {
+				*b = *c
+				return true
+			}
+		}
+		return false
+	}
+
+	return len(nodes) > 0 &&
+		b.match(patterns[0], nodes[0]) &&
+		b.matchList(patterns[1:], nodes[1:], names)
+}
+
+// equalLiterals returns true if two literals have the same value
+func equalLiterals(a *ast.LiteralNode, b *ast.LiteralNode) bool {
+	if a.Value == b.Value {
+		return true
+	}
+	if a.Value == nil || b.Value == nil || pile.IsImmediate(a.Value) || pile.IsImmediate(b.Value) {
+		return false
+	}
+
+	// Literals of different ASTs are different objects
+	return a.Value.Type() == b.Value.Type() && a.Value.String() == b.Value.String()
+}
+
+// nameNodes returns the variables of a list of names, so they can be
+// matched like other nodes
+func nameNodes(names []string) []ast.Node {
+	nodes := make([]ast.Node, len(names))
+	for i, name := range names {
+		nodes[i] = &ast.VariableNode{Name: name}
+	}
+	return nodes
+}
+
+// pragmaNodes returns the pragmas of a method as a list of nodes
+func pragmaNodes(pragmas []*ast.PragmaNode) []ast.Node {
+	nodes := make([]ast.Node, len(pragmas))
+	for i, pragma := range pragmas {
+		nodes[i] = pragma
+	}
+	return nodes
+}
@@ -0,0 +1,256 @@
+// Package pattern searches and rewrites ASTs with patterns in the style of
+// the Refactoring Browser. A pattern is Smalltalk source code in which
+// metavariables, names starting with a backquote, stand for parts of the AST:
+//
+//	`name    a variable
+//	`#name   a literal
+//	`@name   any expression
+//	`.name   a statement
+//	`.@name  a list of statements or brace array elements, possibly empty
+//
+// A metavariable used as a selector, as in `@rcv `msg or `@rcv `at: `@i put: `@v,
+// matches any selector with the same number of arguments and is named by
+// its first part. Used as a block parameter, a temporary or the variable of
+// an assignment, `name matches one name and `@name a list of names.
+// A metavariable that appears more than once must match equal nodes.
+// Temporaries are matched like statements, so a pattern for the statements
+// of a method or block that may declare temporaries starts with | `@temps |.
+//
+// Comments in patterns are ignored, and source ranges and comments don't
+// take part in matching.
+package pattern
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/parser"
+	"smalltalklsp/interpreter/vm"
+)
+
+// kind is the kind of node a metavariable matches
+type kind byte
+
+const (
+	variableKind   kind = 'v'
+	literalKind    kind = 'k'
+	expressionKind kind = 'e'
+	statementKind  kind = 's'
+	listKind       kind = 'l'
+)
+
+// prefix starts the names metavariables are turned into before parsing a
+// pattern. It is followed by the kind, an underscore and the name.
+const prefix = "__pv_"
+
+// Pattern is a compiled pattern
+type Pattern struct {
+	// Source is the source code of the pattern
+	Source string
+
+	// node is the AST of the pattern, in which metavariables are variables
+	// and selectors with encoded names
+	node ast.Node
+}
+
+// Compile compiles the source code of a pattern. A pattern with a single
+// statement and no temporaries matches that statement, anything else
+// matches a whole sequence of statements. Patterns are parsed with the
+// literals of the shared VM.
+func Compile(source string) (*Pattern, error) {
+	encoded, err := encode(source)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := parser.NewParser(encoded, nil, vm.DefaultVM).ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pattern{Source: source, node: node}, nil
+}
+
+// MustCompile compiles a pattern like Compile, but panics if it doesn't parse
+func MustCompile(source string) *Pattern {
+	p, err := Compile(source)
+	if err != nil {
+		panic(fmt.Sprintf("pattern %q: %v", source, err))
+	}
+	return p
+}
+
+// String returns the source code of the pattern
+func (p *Pattern) String() string {
+	return p.Source
+}
+
+// encode turns the metavariables of a pattern into names the parser accepts,
+// leaving strings, comments and character literals alone. Lines are kept, so
+// parse errors point to the right line of the pattern.
+func encode(source string) (string, error) {
+	var out strings.Builder
+
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch c {
+		case '\'', '"':
+			end := strings.IndexByte(source[i+1:], c)
+			if end < 0 {
+				out.WriteString(source[i:])
+				return out.String(), nil
+			}
+			out.WriteString(source[i : i+end+2])
+			i += end + 2
+
+		case '$':
+			_, size := utf8.DecodeRuneInString(source[i+1:])
+			out.WriteString(source[i : i+1+size])
+			i += 1 + size
+
+		case '`':
+			start := i
+			i++
+			k := variableKind
+			switch {
+			case strings.HasPrefix(source[i:], ".@"):
+				k = listKind
+				i += 2
+			case strings.HasPrefix(source[i:], "."):
+				k = statementKind
+				i++
+			case strings.HasPrefix(source[i:], "@"):
+				k = expressionKind
+				i++
+			case strings.HasPrefix(source[i:], "#"):
+				k = literalKind
+				i++
+			}
+
+			nameStart := i
+			for i < len(source) {
+				r, size := utf8.DecodeRuneInString(source[i:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				i += size
+			}
+			if i == nameStart {
+				return "", fmt.Errorf("metavariable without a name at offset %d", start)
+			}
+
+			out.WriteString(prefix)
+			out.WriteByte(byte(k))
+			out.WriteByte('_')
+			out.WriteString(source[nameStart:i])
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String(), nil
+}
+
+// decode returns the name and kind of a metavariable encoded as an identifier
+func decode(identifier string) (string, kind, bool) {
+	if !strings.HasPrefix(identifier, prefix) || len(identifier) < len(prefix)+3 {
+		return "", 0, false
+	}
+
+	rest := identifier[len(prefix):]
+	if rest[1] != '_' {
+		return "", 0, false
+	}
+
+	switch k := kind(rest[0]); k {
+	case variableKind, literalKind, expressionKind, statementKind, listKind:
+		return rest[2:], k, true
+	}
+	return "", 0, false
+}
+
+// metavariable returns the name and kind of a pattern node standing for a metavariable
+func metavariable(node ast.Node) (string, kind, bool) {
+	variable, ok := node.(*ast.VariableNode)
+	if !ok {
+		return "", 0, false
+	}
+	return decode(variable.Name)
+}
+
+// selectorMetavariable returns the name of a metavariable used as a selector
+func selectorMetavariable(selector string) (string, bool) {
+	first := selector
+	if colon := strings.IndexByte(selector, ':'); colon >= 0 {
+		first = selector[:colon]
+	}
+
+	name, k, ok := decode(first)
+	if !ok || k != variableKind {
+		return "", false
+	}
+	return name, true
+}
+
+// numArgs returns the number of arguments of a selector
+func numArgs(selector string) int {
+	if colons := strings.Count(selector, ":"); colons > 0 {
+		return colons
+	}
+
+	r, _ := utf8.DecodeRuneInString(selector)
+	if unicode.IsLetter(r) || r == '_' {
+		return 0
+	}
+	return 1
+}
+
+// metavariables returns the names of the metavariables of a pattern
+func (p *Pattern) metavariables() map[string]bool {
+	names := map[string]bool{}
+	addName := func(identifier string) {
+		if name, _, ok := decode(identifier); ok {
+			names[name] = true
+		}
+	}
+	addNames := func(identifiers []string) {
+		for _, identifier := range identifiers {
+			addName(identifier)
+		}
+	}
+	addSelector := func(selector string) {
+		if name, ok := selectorMetavariable(selector); ok {
+			names[name] = true
+		}
+	}
+
+	ast.Inspect(p.node, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.VariableNode:
+			addName(n.Name)
+		case *ast.MethodNode:
+			addSelector(n.Selector)
+			addNames(n.Parameters)
+			addNames(n.Temporaries)
+		case *ast.AssignmentNode:
+			addName(n.Variable)
+		case *ast.MessageSendNode:
+			addSelector(n.Selector)
+		case *ast.PragmaNode:
+			addSelector(n.Selector)
+		case *ast.BlockNode:
+			addNames(n.Parameters)
+			addNames(n.Temporaries)
+		case *ast.SequenceNode:
+			addNames(n.Temporaries)
+		}
+		return true
+	})
+
+	return names
+}
@@ -0,0 +1,237 @@
+package pattern
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/formatter"
+	"smalltalklsp/interpreter/parser"
+	"smalltalklsp/interpreter/vm"
+)
+
+// parseExpression parses the source code of a doIt
+func parseExpression(t *testing.T, source string) ast.Node {
+	t.Helper()
+
+	node, err := parser.NewParser(source, nil, vm.DefaultVM).ParseExpression()
+	if err != nil {
+		t.Fatalf("Error parsing %q: %v", source, err)
+	}
+	return node
+}
+
+// TestMatch tests matching expressions against patterns
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		source  string
+		matches bool
+	}{
+		{"`@rcv ifTrue: [`@a] ifFalse: [`@b]", "x > 0 ifTrue: [1] ifFalse: [self foo: 2]", true},
+		{"`@rcv ifTrue: [`@a] ifFalse: [`@b]", "x > 0 ifTrue: [1. 2] ifFalse: [3]", false},
+		{"`@rcv ifTrue: [`.@a] ifFalse: [`.@b]", "x > 0 ifTrue: [1. 2] ifFalse: []", true},
+		{"`x + `x", "a + a", true},
+		{"`x + `x", "a + b", false},
+		{"`x + 1", "(a foo) + 1", false},
+		{"`@x + 1", "(a foo) + 1", true},
+		{"`@x + `#n", "a + 'one'", true},
+		{"`@x + `#n", "a + b", false},
+		{"`@x + 'one'", "a + 'one'", true},
+		{"`@x + 'one'", "a + #one", false},
+		{"`@rcv `msg", "a size", true},
+		{"`@rcv `msg", "a at: 1", false},
+		{"`@rcv `at: `@i put: `@v", "a at: 1 put: 2", true},
+		{"`@rcv `at: `@i put: `@v", "a x: 1 y: 2", true},
+		{"`@rcv do: [:`each | `.@body]", "c do: [:x | x foo. x bar]", true},
+		{"`@rcv do: [:`each | `each foo]", "c do: [:x | x foo]", true},
+		{"`@rcv do: [:`each | `each foo]", "c do: [:x | y foo]", false},
+		{"`v := `@e", "a := 3 + 4", true},
+		{"Transcript show: `@a; cr", "Transcript show: 'x'; cr", true},
+		{"Transcript show: `@a; cr", "Transcript show: 'x'; tab", false},
+		{"{`.@before. nil. `.@after}", "{1. 2. nil. 3}", true},
+		{"{`.@before. nil. `.@after}", "{1. 2. 3}", false},
+		{"`.@before. ^nil", "self foo. self bar. ^nil", true},
+		{"| `@temps | `.@statements", "| a b | a := 1. ^a", true},
+		{"^`@x", "^self", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+" ~ "+test.source, func(t *testing.T) {
+			p, err := Compile(test.pattern)
+			if err != nil {
+				t.Fatalf("Error compiling pattern: %v", err)
+			}
+
+			_, matches := p.Match(parseExpression(t, test.source))
+			if matches != test.matches {
+				t.Errorf("Expected match %v, got %v", test.matches, matches)
+			}
+		})
+	}
+}
+
+// TestBindings tests what the metavariables of a match stand for
+func TestBindings(t *testing.T) {
+	p := MustCompile("`@rcv `at: `@i put: [`.@body]")
+
+	b, ok := p.Match(parseExpression(t, "dict at: #key put: [self foo. 3]"))
+	if !ok {
+		t.Fatalf("Expected a match")
+	}
+
+	if variable, ok := b.Node("rcv").(*ast.VariableNode); !ok || variable.Name != "dict" {
+		t.Errorf("Expected rcv to be dict, got %v", b.Node("rcv"))
+	}
+	if b.Selector("at") != "at:put:" {
+		t.Errorf("Expected the selector at:put:, got %q", b.Selector("at"))
+	}
+	if len(b.List("body")) != 2 {
+		t.Errorf("Expected 2 statements in body, got %d", len(b.List("body")))
+	}
+}
+
+// TestFind tests finding the nodes of a method that match a pattern
+func TestFind(t *testing.T) {
+	node, err := parser.NewParser("foo ^(a isNil) | (b foo isNil)", nil, vm.NewVM()).Parse()
+	if err != nil {
+		t.Fatalf("Error parsing: %v", err)
+	}
+
+	matches := MustCompile("`@x isNil").Find(node)
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matches))
+	}
+	if _, ok := matches[1].Bindings.Node("x").(*ast.MessageSendNode); !ok {
+		t.Errorf("Expected the second match to bind b foo, got %T", matches[1].Bindings.Node("x"))
+	}
+}
+
+// TestRewrite tests rewriting methods with rules
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		search   string
+		replace  string
+		source   string
+		expected string
+	}{
+		{
+			name:     "swap branches",
+			search:   "`@rcv ifTrue: [`.@a] ifFalse: [`.@b]",
+			replace:  "`@rcv not ifTrue: [`.@b] ifFalse: [`.@a]",
+			source:   "foo ^x > 0 ifTrue: ['pos'] ifFalse: [self bar. 'neg']",
+			expected: "foo\n    ^(x > 0) not\n        ifTrue: [\n            self bar.\n            'neg']\n        ifFalse: ['pos']",
+		},
+		{
+			name:     "nested matches",
+			search:   "`@x isNil ifTrue: [`@y]",
+			replace:  "`@x ifNil: [`@y]",
+			source:   "foo a isNil ifTrue: [b isNil ifTrue: [1]]",
+			expected: "foo\n    a ifNil: [b ifNil: [1]]",
+		},
+		{
+			name:     "selector",
+			search:   "`@rcv `msg: `@arg",
+			replace:  "`@arg `msg: `@rcv",
+			source:   "foo self bar: 1",
+			expected: "foo\n    1 bar: self",
+		},
+		{
+			name:     "cascade receiver",
+			search:   "Transcript",
+			replace:  "Display",
+			source:   "foo Transcript show: 'a'; cr",
+			expected: "foo\n    Display show: 'a'; cr",
+		},
+		{
+			name:     "statements",
+			search:   "| `@temps | `.@before. self halt. `.@after",
+			replace:  "| `@temps | `.@before. `.@after",
+			source:   "foo | a | a := 1. self halt. ^a",
+			expected: "foo\n    | a |\n    a := 1.\n    ^a",
+		},
+		{
+			name:     "block parameters",
+			search:   "`@c do: [:`e | `@c remove: `e]",
+			replace:  "`@c removeAll: `@c copy",
+			source:   "foo items do: [:x | items remove: x]",
+			expected: "foo\n    items removeAll: items copy",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := NewRule(test.search, test.replace)
+			if err != nil {
+				t.Fatalf("Error compiling rule: %v", err)
+			}
+
+			node, err := parser.NewParser(test.source, nil, vm.NewVM()).Parse()
+			if err != nil {
+				t.Fatalf("Error parsing: %v", err)
+			}
+
+			rewritten, _, err := Rewrite(node, []*Rule{rule})
+			if err != nil {
+				t.Fatalf("Error rewriting: %v", err)
+			}
+
+			formatted := formatter.Format(rewritten, test.source, formatter.DefaultOptions())
+			if formatted != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, formatted)
+			}
+		})
+	}
+}
+
+// TestRuleErrors tests rules that can't be compiled
+func TestRuleErrors(t *testing.T) {
+	if _, err := NewRule("`@x foo", "`@y foo"); err == nil || err.Error() != "metavariable y of the replacement is not in the search pattern" {
+		t.Errorf("Expected an unbound metavariable error, got %v", err)
+	}
+	if _, err := Compile("` foo"); err == nil {
+		t.Errorf("Expected an error for a metavariable without a name")
+	}
+}
+
+// TestParseRules tests reading rules from a chunk file
+func TestParseRules(t *testing.T) {
+	source := "\"Use ifNil:\"\n`@x isNil ifTrue: [`.@a]!\n`@x ifNil: [`.@a]!\n\n`@x notNil!\n`@x isNil not!\n"
+
+	rules, err := ParseRules("rules.st", source)
+	if err != nil {
+		t.Fatalf("Error parsing rules: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+	if rules[1].Replace.String() != "`@x isNil not" {
+		t.Errorf("Expected the second replacement to be `@x isNil not, got %q", rules[1].Replace)
+	}
+
+	_, err = ParseRules("rules.st", "`@x foo!\n`@x bar!\n`@x baz!\n")
+	if err == nil || err.Error() != "rules.st:3: rule without a replacement" {
+		t.Errorf("Expected a missing replacement error, got %v", err)
+	}
+}
+
+// TestRewriteChunks tests that only the methods of a chunk file that match a
+// rule are rewritten
+func TestRewriteChunks(t *testing.T) {
+	rule, err := NewRule("`@x isNil not", "`@x notNil")
+	if err != nil {
+		t.Fatalf("Error compiling the rule: %v", err)
+	}
+	f := formatter.NewFormatter(formatter.DefaultOptions(), vm.DefaultVM)
+
+	source := "!Foo methodsFor: 'a'!\na  ^a isNil not! !\n!Foo methodsFor: 'b'!\nb  ^b! !\n"
+	rewritten, count, err := RewriteChunks("Foo.st", source, []*Rule{rule}, f)
+	if err != nil {
+		t.Fatalf("Error rewriting chunks: %v", err)
+	}
+
+	expected := "!Foo methodsFor: 'a'!\na\n    ^a notNil! !\n!Foo methodsFor: 'b'!\nb  ^b! !\n"
+	if count != 1 || rewritten != expected {
+		t.Errorf("Expected 1 replacement and:\n%s\nGot %d and:\n%s", expected, count, rewritten)
+	}
+}
@@ -0,0 +1,427 @@
+package pattern
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/chunk"
+	"smalltalklsp/interpreter/definition"
+	"smalltalklsp/interpreter/formatter"
+	"smalltalklsp/interpreter/parser"
+)
+
+// Rule replaces the nodes matching a search pattern by a replacement
+// pattern, in which the metavariables stand for what they matched
+type Rule struct {
+	// Search is the pattern of the nodes to replace
+	Search *Pattern
+
+	// Replace is the pattern of the replacement
+	Replace *Pattern
+}
+
+// NewRule compiles a rule. Every metavariable of the replacement must
+// appear in the search pattern.
+func NewRule(search string, replace string) (*Rule, error) {
+	s, err := Compile(search)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Compile(replace)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRule(s, r)
+}
+
+// newRule checks the metavariables of a rule
+func newRule(search *Pattern, replace *Pattern) (*Rule, error) {
+	bound := search.metavariables()
+	var unbound []string
+	for name := range replace.metavariables() {
+		if !bound[name] {
+			unbound = append(unbound, name)
+		}
+	}
+	if len(unbound) > 0 {
+		sort.Strings(unbound)
+		return nil, fmt.Errorf("metavariable %s of the replacement is not in the search pattern", unbound[0])
+	}
+
+	return &Rule{Search: search, Replace: replace}, nil
+}
+
+// ParseRules parses a rule file. It is a chunk file in which every rule is
+// a chunk with the search pattern followed by a chunk with the replacement:
+//
+//	"Use ifNil:"
+//	`@x isNil ifTrue: [`.@body]!
+//	`@x ifNil: [`.@body]!
+func ParseRules(file string, source string) ([]*Rule, error) {
+	var chunks []chunk.Chunk
+	for _, c := range chunk.Split(file, source) {
+		if !c.IsEmpty() {
+			chunks = append(chunks, c)
+		}
+	}
+
+	var rules []*Rule
+	for i := 0; i < len(chunks); i += 2 {
+		if i+1 == len(chunks) {
+			return nil, definition.Errorf(chunks[i].Location, "rule without a replacement")
+		}
+
+		search, err := Compile(chunks[i].Text)
+		if err != nil {
+			return nil, chunk.LocatedError(chunks[i], err)
+		}
+		replace, err := Compile(chunks[i+1].Text)
+		if err != nil {
+			return nil, chunk.LocatedError(chunks[i+1], err)
+		}
+
+		rule, err := newRule(search, replace)
+		if err != nil {
+			return nil, definition.Errorf(chunks[i+1].Location, "%s", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Rewrite applies rules to an AST. Nodes are rewritten from the leaves up,
+// each by the first rule whose search pattern matches it, and replacements
+// are not matched again. It returns the new AST, which shares the nodes that
+// didn't change with the original one, and the number of replacements.
+func Rewrite(root ast.Node, rules []*Rule) (ast.Node, int, error) {
+	count := 0
+	var failure error
+
+	result, err := ast.Rewrite(root, func(node ast.Node) ast.Node {
+		if failure != nil {
+			return node
+		}
+
+		for _, rule := range rules {
+			b, ok := rule.Search.Match(node)
+			if !ok {
+				continue
+			}
+
+			replacement, err := b.instantiate(rule.Replace.node)
+			if err != nil {
+				failure = fmt.Errorf("rule %q: %v", rule.Search.Source, err)
+				return node
+			}
+			count++
+			return replacement
+		}
+		return node
+	})
+	if failure != nil {
+		return nil, 0, failure
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, count, nil
+}
+
+// instantiate builds a node from a pattern node, replacing the
+// metavariables by what they matched. The nodes built have no source range,
+// the matched nodes keep theirs.
+func (b *Bindings) instantiate(pattern ast.Node) (ast.Node, error) {
+	if pattern == nil {
+		return nil, nil
+	}
+
+	if name, _, ok := metavariable(pattern); ok {
+		if node, ok := b.nodes[name]; ok {
+			return node, nil
+		}
+		if list, ok := b.lists[name]; ok && len(list) == 1 {
+			return list[0], nil
+		}
+		return nil, fmt.Errorf("metavariable %s doesn't stand for a single node", name)
+	}
+
+	switch p := pattern.(type) {
+	case *ast.MethodNode:
+		selector, err := b.instantiateSelector(p.Selector)
+		if err != nil {
+			return nil, err
+		}
+		parameters, err := b.instantiateNames(p.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		temporaries, err := b.instantiateNames(p.Temporaries)
+		if err != nil {
+			return nil, err
+		}
+		pragmas, err := b.instantiateList(pragmaNodes(p.Pragmas))
+		if err != nil {
+			return nil, err
+		}
+		body, err := b.instantiate(p.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		method := &ast.MethodNode{Selector: selector, Parameters: parameters, Temporaries: temporaries, Body: body, Class: p.Class}
+		for _, node := range pragmas {
+			pragma, ok := node.(*ast.PragmaNode)
+			if !ok {
+				return nil, fmt.Errorf("a pragma can't be replaced by a %T", node)
+			}
+			method.Pragmas = append(method.Pragmas, pragma)
+		}
+		return method, nil
+
+	case *ast.ReturnNode:
+		expression, err := b.instantiate(p.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ReturnNode{Expression: expression}, nil
+
+	case *ast.SelfNode:
+		return &ast.SelfNode{}, nil
+
+	case *ast.SuperNode:
+		return &ast.SuperNode{}, nil
+
+	case *ast.ThisContextNode:
+		return &ast.ThisContextNode{}, nil
+
+	case *ast.LiteralNode:
+		return &ast.LiteralNode{Value: p.Value}, nil
+
+	case *ast.VariableNode:
+		return &ast.VariableNode{Name: p.Name}, nil
+
+	case *ast.GlobalNode:
+		return &ast.GlobalNode{Name: p.Name}, nil
+
+	case *ast.AssignmentNode:
+		variable, err := b.instantiateNames([]string{p.Variable})
+		if err != nil {
+			return nil, err
+		}
+		if len(variable) != 1 {
+			return nil, fmt.Errorf("metavariable %s doesn't stand for a single name", p.Variable)
+		}
+		expression, err := b.instantiate(p.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.AssignmentNode{Variable: variable[0], Expression: expression}, nil
+
+	case *ast.MessageSendNode:
+		receiver, err := b.instantiate(p.Receiver)
+		if err != nil {
+			return nil, err
+		}
+		return b.instantiateMessage(p, receiver)
+
+	case *ast.BlockNode:
+		parameters, err := b.instantiateNames(p.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		temporaries, err := b.instantiateNames(p.Temporaries)
+		if err != nil {
+			return nil, err
+		}
+		body, err := b.instantiate(p.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.BlockNode{Parameters: parameters, Temporaries: temporaries, Body: body}, nil
+
+	case *ast.CascadeNode:
+		receiver, err := b.instantiate(p.Receiver)
+		if err != nil {
+			return nil, err
+		}
+
+		cascade := &ast.CascadeNode{Receiver: receiver}
+		for _, message := range p.Messages {
+			part, err := b.instantiateCascadePart(message, p.Receiver, receiver)
+			if err != nil {
+				return nil, err
+			}
+			cascade.Messages = append(cascade.Messages, part)
+		}
+		return cascade, nil
+
+	case *ast.SequenceNode:
+		temporaries, err := b.instantiateNames(p.Temporaries)
+		if err != nil {
+			return nil, err
+		}
+		statements, err := b.instantiateList(p.Statements)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.SequenceNode{Temporaries: temporaries, Statements: statements}, nil
+
+	case *ast.DynamicArrayNode:
+		elements, err := b.instantiateList(p.Elements)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.DynamicArrayNode{Elements: elements}, nil
+
+	case *ast.PragmaNode:
+		message, err := b.instantiateMessage(&ast.MessageSendNode{Selector: p.Selector, Arguments: p.Arguments}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.PragmaNode{Selector: message.Selector, Arguments: message.Arguments}, nil
+	}
+
+	return nil, fmt.Errorf("can't build a %T", pattern)
+}
+
+// instantiateMessage builds a message with the selector and arguments of a
+// pattern sent to a receiver
+func (b *Bindings) instantiateMessage(pattern *ast.MessageSendNode, receiver ast.Node) (*ast.MessageSendNode, error) {
+	selector, err := b.instantiateSelector(pattern.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &ast.MessageSendNode{Receiver: receiver, Selector: selector}
+	for _, argument := range pattern.Arguments {
+		node, err := b.instantiate(argument)
+		if err != nil {
+			return nil, err
+		}
+		message.Arguments = append(message.Arguments, node)
+	}
+
+	if numArgs(selector) != len(message.Arguments) {
+		return nil, fmt.Errorf("selector %s doesn't take %d arguments", selector, len(message.Arguments))
+	}
+	return message, nil
+}
+
+// instantiateCascadePart builds a message part of a cascade, whose receiver
+// chain ends in the receiver of the cascade
+func (b *Bindings) instantiateCascadePart(pattern *ast.MessageSendNode, patternReceiver ast.Node, receiver ast.Node) (*ast.MessageSendNode, error) {
+	if pattern.Receiver != patternReceiver {
+		inner, ok := pattern.Receiver.(*ast.MessageSendNode)
+		if !ok {
+			return nil, fmt.Errorf("invalid cascade message receiver: %T", pattern.Receiver)
+		}
+
+		var err error
+		receiver, err = b.instantiateCascadePart(inner, patternReceiver, receiver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b.instantiateMessage(pattern, receiver)
+}
+
+// instantiateSelector returns the selector a selector metavariable matched,
+// or the selector itself
+func (b *Bindings) instantiateSelector(selector string) (string, error) {
+	name, ok := selectorMetavariable(selector)
+	if !ok {
+		return selector, nil
+	}
+
+	bound, ok := b.selectors[name]
+	if !ok {
+		return "", fmt.Errorf("metavariable %s doesn't stand for a selector", name)
+	}
+	return bound, nil
+}
+
+// instantiateList builds a list of nodes, in which a list metavariable
+// stands for all the nodes it matched
+func (b *Bindings) instantiateList(patterns []ast.Node) ([]ast.Node, error) {
+	var nodes []ast.Node
+	for _, pattern := range patterns {
+		if name, _, ok := metavariable(pattern); ok {
+			if list, ok := b.lists[name]; ok {
+				nodes = append(nodes, list...)
+				continue
+			}
+		}
+
+		node, err := b.instantiate(pattern)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// instantiateNames builds a list of names, in which a metavariable stands
+// for the names it matched
+func (b *Bindings) instantiateNames(patterns []string) ([]string, error) {
+	nodes, err := b.instantiateList(nameNodes(patterns))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, node := range nodes {
+		variable, ok := node.(*ast.VariableNode)
+		if !ok {
+			return nil, fmt.Errorf("a name can't be replaced by a %T", node)
+		}
+		names = append(names, variable.Name)
+	}
+	return names, nil
+}
+
+// RewriteChunks applies rules to the methods of a chunk file. The methods are
+// parsed with the literals of the formatter, and those that change are
+// formatted with it, everything else is left as it is. It returns the new
+// source and the number of replacements.
+func RewriteChunks(file string, source string, rules []*Rule, f *formatter.Formatter) (string, int, error) {
+	var out strings.Builder
+	position := 0
+	total := 0
+
+	for _, method := range chunk.MethodChunks(chunk.Split(file, source)) {
+		node, err := parser.NewParser(method.Text, nil, f.Literals).Parse()
+		if err != nil {
+			return "", 0, chunk.LocatedError(method, err)
+		}
+
+		rewritten, count, err := Rewrite(node, rules)
+		if err != nil {
+			return "", 0, definition.Errorf(method.Location, "%s", err)
+		}
+		if count == 0 {
+			continue
+		}
+		total += count
+
+		formatted := formatter.Format(rewritten, method.Text, f.Options)
+
+		// Files that don't escape the ! in strings and comments are kept that way
+		if source[method.Start:method.End] != method.Text {
+			formatted = chunk.Escape(formatted)
+		}
+
+		out.WriteString(source[position:method.Start])
+		out.WriteString(formatted)
+		position = method.End
+	}
+	out.WriteString(source[position:])
+
+	return out.String(), total, nil
+}
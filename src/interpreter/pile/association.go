@@ -0,0 +1,57 @@
+package pile
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Association represents a Smalltalk association, a key and a value. The
+// bindings of global variables are associations shared by the methods that
+// refer to the global.
+type Association struct {
+	Object
+	Key   *Object
+	Value *Object
+}
+
+// NewAssociationInternal creates a new association object without setting its class field
+// This is a private helper function used by vm.NewAssociation
+func NewAssociationInternal(key *Object, value *Object) *Association {
+	return &Association{
+		Object: Object{
+			TypeField: OBJ_ASSOCIATION,
+		},
+		Key:   key,
+		Value: value,
+	}
+}
+
+// AssociationToObject converts an Association to an Object
+func AssociationToObject(a *Association) *Object {
+	return (*Object)(unsafe.Pointer(a))
+}
+
+// ObjectToAssociation converts an Object to an Association
+func ObjectToAssociation(o *Object) *Association {
+	return (*Association)(unsafe.Pointer(o))
+}
+
+// String returns a string representation of the association, e.g. #Foo->3
+func (a *Association) String() string {
+	return fmt.Sprintf("%s->%s", a.Key, a.Value)
+}
+
+// GetKey returns the key of the association
+func (a *Association) GetKey() *Object {
+	return a.Key
+}
+
+// GetValue returns the value of the association
+func (a *Association) GetValue() *Object {
+	return a.Value
+}
+
+// SetValue sets the value of the association
+func (a *Association) SetValue(value *Object) {
+	a.Value = value
+}
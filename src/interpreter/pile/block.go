@@ -10,6 +10,7 @@ type Block struct {
 	Bytecodes    []byte
 	Literals     []*Object
 	TempVarNames []string
+	NumArgs      int         // The arguments are the first NumArgs temporary variables
 	OuterContext interface{} // Using interface{} to avoid circular dependency
 }
 
@@ -104,6 +105,16 @@ func (b *Block) AddTempVarName(name string) {
 	b.TempVarNames = append(b.TempVarNames, name)
 }
 
+// GetNumArgs returns the number of arguments the block takes
+func (b *Block) GetNumArgs() int {
+	return b.NumArgs
+}
+
+// SetNumArgs sets the number of arguments the block takes
+func (b *Block) SetNumArgs(numArgs int) {
+	b.NumArgs = numArgs
+}
+
 // GetOuterContext returns the outer context of the block
 func (b *Block) GetOuterContext() interface{} {
 	return b.OuterContext
@@ -68,6 +68,32 @@ func AddClassInstanceVarName(c *Class, name string) {
 	c.InstanceVarNames = append(c.InstanceVarNames, name)
 }
 
+// GetClassAllInstanceVarNames returns the instance variable names of the class
+// and its superclasses in slot order, the names of the superclasses first
+func GetClassAllInstanceVarNames(c *Class) []string {
+	if c == nil {
+		return nil
+	}
+
+	var names []string
+	if c.SuperClass != nil {
+		names = GetClassAllInstanceVarNames(ObjectToClass(c.SuperClass))
+	}
+	return append(names, c.InstanceVarNames...)
+}
+
+// GetClassInstanceVarIndex returns the slot of the named instance variable in
+// instances of the class, or -1 if neither the class nor a superclass declares it
+func GetClassInstanceVarIndex(c *Class, name string) int {
+	names := GetClassAllInstanceVarNames(c)
+	for i := len(names) - 1; i >= 0; i-- {
+		if names[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // GetClassMethodDictionary returns the method dictionary of the class
 func GetClassMethodDictionary(c *Class) *Dictionary {
 	return ObjectToDictionary(c.MethodDictionary)
@@ -102,12 +128,9 @@ func LookupClassMethod(c *Class, selector *Object) *Object {
 
 // NewClassInstance creates a new instance of the class
 func NewClassInstance(c *Class) *Object {
-	// Initialize instance variables array with nil values
-	instVarsSize := 0
-	if c != nil && len(c.InstanceVarNames) > 0 {
-		instVarsSize = len(c.InstanceVarNames)
-	}
-	instVars := make([]*Object, instVarsSize)
+	// Initialize instance variables array with nil values, with slots for the
+	// instance variables of the superclasses too
+	instVars := make([]*Object, len(GetClassAllInstanceVarNames(c)))
 	for i := range instVars {
 		instVars[i] = MakeNilImmediate()
 	}
@@ -1,6 +1,7 @@
 package pile_test
 
 import (
+	"strings"
 	"testing"
 
 	"smalltalklsp/interpreter/pile"
@@ -201,6 +202,46 @@ func TestNewClassInstance(t *testing.T) {
 	}
 }
 
+func TestClassAllInstanceVarNames(t *testing.T) {
+	// Create a superclass and a subclass, each with instance variables
+	superClass := pile.NewClass("Pair", nil)
+	pile.AddClassInstanceVarName(superClass, "key")
+	subClass := pile.NewClass("Triple", superClass)
+	pile.AddClassInstanceVarName(subClass, "value")
+	pile.AddClassInstanceVarName(subClass, "extra")
+
+	// The inherited instance variables come first
+	names := pile.GetClassAllInstanceVarNames(subClass)
+	if strings.Join(names, " ") != "key value extra" {
+		t.Errorf("GetClassAllInstanceVarNames(subClass) = %v, want [key value extra]", names)
+	}
+
+	tests := []struct {
+		class *pile.Class
+		name  string
+		index int
+	}{
+		{subClass, "key", 0},
+		{subClass, "value", 1},
+		{subClass, "extra", 2},
+		{subClass, "missing", -1},
+		{superClass, "key", 0},
+		{superClass, "value", -1},
+	}
+	for _, test := range tests {
+		if index := pile.GetClassInstanceVarIndex(test.class, test.name); index != test.index {
+			t.Errorf("GetClassInstanceVarIndex(%s, %q) = %d, want %d", test.class.Name, test.name, index, test.index)
+		}
+	}
+
+	// Instances have slots for the inherited instance variables too
+	for _, instance := range []*pile.Object{pile.NewClassInstance(subClass), pile.NewInstance(subClass)} {
+		if len(instance.InstanceVars()) != 3 {
+			t.Errorf("len(instance.InstanceVars()) = %d, want 3", len(instance.InstanceVars()))
+		}
+	}
+}
+
 func TestGetClassNameFromObject(t *testing.T) {
 	// Create a class
 	class := pile.NewClass("TestClass", nil)
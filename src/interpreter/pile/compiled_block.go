@@ -0,0 +1,61 @@
+package pile
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// CompiledBlock is the code of a block as the compiler produces it. It is a
+// literal of the method or block the block appears in, and executing
+// CREATE_BLOCK makes a closure running this code in the current context.
+type CompiledBlock struct {
+	Object
+	Bytecodes    []byte
+	Literals     []*Object
+	TempVarNames []string
+	NumArgs      int
+
+	// NonLocalReturn is set when the block contains a ^ returning from its
+	// home method
+	NonLocalReturn bool
+}
+
+// NewCompiledBlock creates a compiled block. The arguments are the first
+// numArgs temporary variables.
+func NewCompiledBlock(bytecodes []byte, literals []*Object, tempVarNames []string, numArgs int) *CompiledBlock {
+	return &CompiledBlock{
+		Object: Object{
+			TypeField: OBJ_COMPILED_BLOCK,
+		},
+		Bytecodes:    bytecodes,
+		Literals:     literals,
+		TempVarNames: tempVarNames,
+		NumArgs:      numArgs,
+	}
+}
+
+// CompiledBlockToObject converts a CompiledBlock to an Object
+func CompiledBlockToObject(b *CompiledBlock) *Object {
+	return (*Object)(unsafe.Pointer(b))
+}
+
+// ObjectToCompiledBlock converts an Object to a CompiledBlock
+func ObjectToCompiledBlock(o *Object) *CompiledBlock {
+	if o == nil || IsImmediate(o) || o.Type() != OBJ_COMPILED_BLOCK {
+		return nil
+	}
+	return (*CompiledBlock)(unsafe.Pointer(o))
+}
+
+// NumTemps returns the number of temporary variables, including the arguments
+func (b *CompiledBlock) NumTemps() int {
+	return len(b.TempVarNames)
+}
+
+// String returns a string representation of the compiled block
+func (b *CompiledBlock) String() string {
+	if b.NonLocalReturn {
+		return fmt.Sprintf("CompiledBlock(%d args, %d temps, non-local return)", b.NumArgs, b.NumTemps())
+	}
+	return fmt.Sprintf("CompiledBlock(%d args, %d temps)", b.NumArgs, b.NumTemps())
+}
@@ -19,6 +19,10 @@ const (
 	SPECIAL_NIL   = 0x1 // 01 (TAG_SPECIAL | 0 << 2)
 	SPECIAL_TRUE  = 0x5 // 101 (TAG_SPECIAL | 1 << 2)
 	SPECIAL_FALSE = 0x9 // 1001 (TAG_SPECIAL | 2 << 2)
+
+	// Characters are special values carrying their code point above the low 4 bits
+	SPECIAL_CHARACTER      = 0xD // 1101 (TAG_SPECIAL | 3 << 2)
+	SPECIAL_CHARACTER_MASK = 0xF // Mask for the character tag bits
 )
 
 // IsImmediate returns true if the value is an immediate value
@@ -157,6 +161,34 @@ func GetFloatImmediate(obj ObjectInterface) float64 {
 	return math.Float64frombits(uint64(bits))
 }
 
+// IsCharacterImmediate returns true if the value is an immediate character
+func IsCharacterImmediate(obj ObjectInterface) bool {
+	// Convert the pointer to an integer
+	converted := obj.(*Object)
+	ptr := uintptr(unsafe.Pointer(converted))
+
+	// Check the special character tag bits
+	return (ptr & SPECIAL_CHARACTER_MASK) == SPECIAL_CHARACTER
+}
+
+// MakeCharacterImmediate returns an immediate character value for a code point
+func MakeCharacterImmediate(value rune) *Object {
+	// Shift the code point left by 4 bits and set the character tag bits
+	imm := (uintptr(value) << 4) | SPECIAL_CHARACTER
+
+	// Convert to a pointer
+	return (*Object)(unsafe.Pointer(imm))
+}
+
+// GetCharacterImmediate extracts the code point from an immediate character
+func GetCharacterImmediate(obj ObjectInterface) rune {
+	// Convert the pointer to an integer
+	converted := obj.(*Object)
+	ptr := uintptr(unsafe.Pointer(converted))
+
+	return rune(ptr >> 4)
+}
+
 // NewBoolean creates a new boolean object
 // This returns an immediate value
 func NewBoolean(value bool) ObjectInterface {
@@ -150,4 +150,36 @@ func TestNilCreation(t *testing.T) {
 	if !pile.IsNilImmediate(nilObj) {
 		t.Errorf("Expected NewNil() to return a nil immediate value")
 	}
-}
\ No newline at end of file
+}
+func TestCharacterImmediate(t *testing.T) {
+	for _, value := range []rune{'a', 'Z', ' ', '$', 0, 0x10FFFF} {
+		charObj := pile.MakeCharacterImmediate(value)
+
+		// Characters are special immediates
+		if pile.GetTag(charObj) != pile.TAG_SPECIAL {
+			t.Errorf("Expected tag to be TAG_SPECIAL for %q, got %d", value, pile.GetTag(charObj))
+		}
+		if !pile.IsCharacterImmediate(charObj) {
+			t.Errorf("Expected %q to be a character immediate", value)
+		}
+		if pile.IsNilImmediate(charObj) || pile.IsTrueImmediate(charObj) || pile.IsFalseImmediate(charObj) {
+			t.Errorf("Expected %q not to be nil, true or false", value)
+		}
+
+		// The code point round-trips
+		if got := pile.GetCharacterImmediate(charObj); got != value {
+			t.Errorf("Expected code point %d, got %d", value, got)
+		}
+	}
+
+	// nil, true and false are not characters
+	if pile.IsCharacterImmediate(pile.MakeNilImmediate()) ||
+		pile.IsCharacterImmediate(pile.MakeTrueImmediate()) ||
+		pile.IsCharacterImmediate(pile.MakeFalseImmediate()) {
+		t.Errorf("Expected nil, true and false not to be characters")
+	}
+
+	if got := pile.MakeCharacterImmediate('a').String(); got != "$a" {
+		t.Errorf("Expected $a, got %s", got)
+	}
+}
@@ -224,6 +224,16 @@ func (om *ObjectMemory) updateReferences(obj *Object, toPtr *int) {
 			}
 		}
 
+	case OBJ_ASSOCIATION:
+		// Update the key and the value
+		association := (*Association)(unsafe.Pointer(obj))
+		if association.Key != nil {
+			association.Key = om.copyObject(association.Key, toPtr)
+		}
+		if association.Value != nil {
+			association.Value = om.copyObject(association.Value, toPtr)
+		}
+
 	case OBJ_INSTANCE:
 		// Update instance variables
 		instanceVars := obj.InstanceVars()
@@ -280,6 +290,15 @@ func (om *ObjectMemory) updateReferences(obj *Object, toPtr *int) {
 				block.Literals[i] = om.copyObject(lit, toPtr)
 			}
 		}
+
+	case OBJ_COMPILED_BLOCK:
+		// Update the literals of the compiled block
+		compiledBlock := (*CompiledBlock)(unsafe.Pointer(obj))
+		for i, lit := range compiledBlock.Literals {
+			if lit != nil {
+				compiledBlock.Literals[i] = om.copyObject(lit, toPtr)
+			}
+		}
 	}
 }
 
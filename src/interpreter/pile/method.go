@@ -15,6 +15,18 @@ type Method struct {
 	MethodClass    *Class
 	IsPrimitive    bool
 	PrimitiveIndex int
+	Pragmas        []*Pragma
+}
+
+// Pragma is an annotation of a method such as <primitive: 1>, kept on the
+// method for reflection
+type Pragma struct {
+	// Selector is the selector of the pragma, e.g. primitive:error:
+	Selector string
+
+	// Arguments are the literal arguments, names such as the error
+	// code variable of a primitive are stored as symbols
+	Arguments []*Object
 }
 
 // newMethod creates a new method object without setting its class field
@@ -126,4 +138,24 @@ func (m *Method) GetPrimitiveIndex() int {
 // SetPrimitiveIndex sets the primitive index of the method
 func (m *Method) SetPrimitiveIndex(index int) {
 	m.PrimitiveIndex = index
-}
\ No newline at end of file
+}
+
+// GetPragmas returns the pragmas of the method
+func (m *Method) GetPragmas() []*Pragma {
+	return m.Pragmas
+}
+
+// AddPragma adds a pragma to the method
+func (m *Method) AddPragma(pragma *Pragma) {
+	m.Pragmas = append(m.Pragmas, pragma)
+}
+
+// GetPragma returns the first pragma with the given selector, or nil
+func (m *Method) GetPragma(selector string) *Pragma {
+	for _, pragma := range m.Pragmas {
+		if pragma.Selector == selector {
+			return pragma
+		}
+	}
+	return nil
+}
@@ -22,6 +22,9 @@ const (
 	OBJ_SYMBOL
 	OBJ_EXCEPTION
 	OBJ_BYTE_ARRAY
+	OBJ_SCALED_DECIMAL
+	OBJ_ASSOCIATION
+	OBJ_COMPILED_BLOCK
 )
 
 // Object represents a Smalltalk object
@@ -135,6 +138,9 @@ func (o *Object) String() string {
 		if IsFloatImmediate(o) {
 			return fmt.Sprintf("%g", GetFloatImmediate(o))
 		}
+		if IsCharacterImmediate(o) {
+			return fmt.Sprintf("$%c", GetCharacterImmediate(o))
+		}
 		return "Immediate value"
 	}
 
@@ -169,11 +175,17 @@ func (o *Object) String() string {
 		return fmt.Sprintf("Array(%d)", len(array.Elements))
 	case OBJ_BYTE_ARRAY:
 		return "ByteArray"
+	case OBJ_SCALED_DECIMAL:
+		return ObjectToScaledDecimal(o).String()
+	case OBJ_ASSOCIATION:
+		return ObjectToAssociation(o).String()
 	case OBJ_DICTIONARY:
 		dict := (*Dictionary)(unsafe.Pointer(o))
 		return fmt.Sprintf("Dictionary(%d)", dict.GetEntryCount())
 	case OBJ_BLOCK:
 		return "Block"
+	case OBJ_COMPILED_BLOCK:
+		return (*CompiledBlock)(unsafe.Pointer(o)).String()
 	case OBJ_METHOD:
 		method := (*Method)(unsafe.Pointer(o))
 		if method != nil && method.Selector != nil {
@@ -206,12 +218,9 @@ type Class struct {
 
 // NewInstance creates a new instance of a class
 func NewInstance(class *Class) *Object {
-	// Initialize instance variables array with nil values
-	instVarsSize := 0
-	if class != nil && len(class.InstanceVarNames) > 0 {
-		instVarsSize = len(class.InstanceVarNames)
-	}
-	instVars := make([]*Object, instVarsSize)
+	// Initialize instance variables array with nil values, with slots for the
+	// instance variables of the superclasses too
+	instVars := make([]*Object, len(GetClassAllInstanceVarNames(class)))
 	for i := range instVars {
 		instVars[i] = MakeNilImmediate()
 	}
@@ -0,0 +1,52 @@
+package pile
+
+import (
+	"fmt"
+	"math/big"
+	"unsafe"
+)
+
+// ScaledDecimal represents a Smalltalk scaled decimal, an exact fraction
+// printed with a fixed number of digits after the decimal point
+type ScaledDecimal struct {
+	Object
+	Value *big.Rat
+	Scale int
+}
+
+// NewScaledDecimalInternal creates a new scaled decimal object without setting its class field
+// This is a private helper function used by vm.NewScaledDecimal
+func NewScaledDecimalInternal(value *big.Rat, scale int) *ScaledDecimal {
+	return &ScaledDecimal{
+		Object: Object{
+			TypeField: OBJ_SCALED_DECIMAL,
+		},
+		Value: new(big.Rat).Set(value),
+		Scale: scale,
+	}
+}
+
+// ScaledDecimalToObject converts a ScaledDecimal to an Object
+func ScaledDecimalToObject(sd *ScaledDecimal) *Object {
+	return (*Object)(unsafe.Pointer(sd))
+}
+
+// ObjectToScaledDecimal converts an Object to a ScaledDecimal
+func ObjectToScaledDecimal(o *Object) *ScaledDecimal {
+	return (*ScaledDecimal)(unsafe.Pointer(o))
+}
+
+// String returns a string representation of the scaled decimal, e.g. 1.25s2
+func (sd *ScaledDecimal) String() string {
+	return fmt.Sprintf("%ss%d", sd.Value.FloatString(sd.Scale), sd.Scale)
+}
+
+// GetValue returns the exact value of the scaled decimal
+func (sd *ScaledDecimal) GetValue() *big.Rat {
+	return sd.Value
+}
+
+// GetScale returns the number of digits printed after the decimal point
+func (sd *ScaledDecimal) GetScale() int {
+	return sd.Scale
+}
@@ -0,0 +1,45 @@
+package pile_test
+
+import (
+	"math/big"
+	"testing"
+
+	"smalltalklsp/interpreter/pile"
+)
+
+func TestNewScaledDecimalInternal(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		scale int
+		want  string
+	}{
+		{"Two digits", "1.25", 2, "1.25s2"},
+		{"Padded digits", "3.1", 3, "3.100s3"},
+		{"No digits", "7", 0, "7s0"},
+		{"Negative", "-0.5", 1, "-0.5s1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := new(big.Rat).SetString(tt.value)
+			if !ok {
+				t.Fatalf("invalid test value %s", tt.value)
+			}
+
+			sd := pile.NewScaledDecimalInternal(value, tt.scale)
+			if sd.Type() != pile.OBJ_SCALED_DECIMAL {
+				t.Errorf("Type() = %d, want %d", sd.Type(), pile.OBJ_SCALED_DECIMAL)
+			}
+			if sd.GetScale() != tt.scale {
+				t.Errorf("GetScale() = %d, want %d", sd.GetScale(), tt.scale)
+			}
+			if sd.GetValue().Cmp(value) != 0 {
+				t.Errorf("GetValue() = %s, want %s", sd.GetValue(), value)
+			}
+			if got := pile.ScaledDecimalToObject(sd).String(); got != tt.want {
+				t.Errorf("String() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
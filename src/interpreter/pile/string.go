@@ -2,13 +2,22 @@ package pile
 
 import (
 	"fmt"
+	"unicode/utf8"
 	"unsafe"
 )
 
-// String represents a Smalltalk string object
+// String represents a Smalltalk string object. Value holds the string as UTF-8,
+// and its size and indices count characters rather than bytes. A string with
+// characters outside ASCII also keeps its code points, like the WideString of
+// other Smalltalks, so indexing it doesn't decode the string every time.
 type String struct {
 	Object
 	Value string
+
+	// wide holds the code points of a string with characters outside ASCII,
+	// decoded on first use. It is nil for ASCII strings, whose bytes are
+	// their characters.
+	wide []rune
 }
 
 // NewString creates a new string object (deprecated - use vm.NewString instead)
@@ -39,26 +48,81 @@ func (s *String) GetValue() string {
 // SetValue sets the string value
 func (s *String) SetValue(value string) {
 	s.Value = value
+	s.wide = nil
+}
+
+// IsWide returns true if the string has characters outside ASCII
+func (s *String) IsWide() bool {
+	return s.codePoints() != nil
+}
+
+// codePoints returns the code points of a wide string, or nil for an ASCII string
+func (s *String) codePoints() []rune {
+	if s.wide == nil && !isASCII(s.Value) {
+		s.wide = []rune(s.Value)
+	}
+	return s.wide
 }
 
-// Length returns the length of the string
+// isASCII returns true if every byte of the value is an ASCII character
+func isASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Length returns the number of characters of the string
 func (s *String) Length() int {
+	if wide := s.codePoints(); wide != nil {
+		return len(wide)
+	}
 	return len(s.Value)
 }
 
 // CharAt returns the character at the given index
-func (s *String) CharAt(index int) byte {
-	if index < 0 || index >= len(s.Value) {
+func (s *String) CharAt(index int) rune {
+	if index < 0 || index >= s.Length() {
 		panic("index out of bounds")
 	}
-	return s.Value[index]
+	if wide := s.codePoints(); wide != nil {
+		return wide[index]
+	}
+	return rune(s.Value[index])
+}
+
+// AtPut sets the character at the given index. Putting a character outside
+// ASCII into an ASCII string makes it a wide string.
+func (s *String) AtPut(index int, value rune) {
+	if index < 0 || index >= s.Length() {
+		panic("index out of bounds")
+	}
+
+	wide := s.codePoints()
+	if wide == nil && value < utf8.RuneSelf {
+		s.Value = s.Value[:index] + string(value) + s.Value[index+1:]
+		return
+	}
+
+	if wide == nil {
+		wide = []rune(s.Value)
+	}
+	wide[index] = value
+	s.Value = string(wide)
+	s.wide = wide
 }
 
-// Substring returns a substring of the string
+// Substring returns the characters of the string from start up to end
 func (s *String) Substring(start, end int) *String {
-	if start < 0 || start >= len(s.Value) || end < 0 || end > len(s.Value) || start > end {
+	length := s.Length()
+	if start < 0 || start >= length || end < 0 || end > length || start > end {
 		panic("invalid substring range")
 	}
+	if wide := s.codePoints(); wide != nil {
+		return NewString(string(wide[start:end]))
+	}
 	return NewString(s.Value[start:end])
 }
 
@@ -103,6 +103,7 @@ func TestStringLength(t *testing.T) {
 		{"Empty string", "", 0},
 		{"Simple string", "hello", 5},
 		{"String with spaces", "hello world", 11},
+		{"Wide string", "naïve ☃", 7},
 	}
 
 	for _, tt := range tests {
@@ -121,7 +122,7 @@ func TestStringCharAt(t *testing.T) {
 	tests := []struct {
 		name  string
 		index int
-		want  byte
+		want  rune
 	}{
 		{"First char", 0, 'h'},
 		{"Middle char", 2, 'l'},
@@ -156,6 +157,35 @@ func TestStringCharAt(t *testing.T) {
 	}()
 }
 
+func TestWideString(t *testing.T) {
+	str := pile.NewString("naïve")
+	if !str.IsWide() {
+		t.Errorf("Expected %q to be a wide string", str.Value)
+	}
+	if str.CharAt(2) != 'ï' || str.CharAt(3) != 'v' {
+		t.Errorf("Expected characters ï and v at 2 and 3, got %c and %c", str.CharAt(2), str.CharAt(3))
+	}
+	if sub := str.Substring(1, 4); sub.Value != "aïv" {
+		t.Errorf("str.Substring(1, 4) = %q, want %q", sub.Value, "aïv")
+	}
+
+	str.AtPut(0, 'N')
+	str.AtPut(2, 'i')
+	if str.Value != "Naive" || str.Length() != 5 {
+		t.Errorf("Expected Naive after putting N and i, got %q", str.Value)
+	}
+
+	// Putting a character outside ASCII widens an ASCII string
+	ascii := pile.NewString("snow")
+	if ascii.IsWide() {
+		t.Errorf("Expected %q not to be a wide string", ascii.Value)
+	}
+	ascii.AtPut(3, '☃')
+	if ascii.Value != "sno☃" || ascii.Length() != 4 || ascii.CharAt(3) != '☃' {
+		t.Errorf("Expected sno☃ after putting ☃, got %q", ascii.Value)
+	}
+}
+
 func TestStringSubstring(t *testing.T) {
 	str := pile.NewString("hello world")
 
@@ -2,6 +2,7 @@ package pile
 
 import (
 	"fmt"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -53,9 +54,9 @@ func (s *Symbol) SetValue(value string) {
 	s.Value = value
 }
 
-// Length returns the length of the symbol
+// Length returns the number of characters of the symbol
 func (s *Symbol) Length() int {
-	return len(s.Value)
+	return utf8.RuneCountInString(s.Value)
 }
 
 // Equal returns true if this symbol is equal to another symbol
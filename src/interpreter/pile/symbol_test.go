@@ -111,6 +111,7 @@ func TestSymbolLength(t *testing.T) {
 		{"Empty symbol", "", 0},
 		{"Simple symbol", "hello", 5},
 		{"Symbol with spaces", "hello world", 11},
+		{"Wide symbol", "größe", 5},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,237 @@
+// Package scanner splits Smalltalk source code into lexemes for tools such as
+// syntax highlighters and formatters. Unlike the tokens of the parser, the
+// lexemes cover every byte of the input, including whitespace and comments,
+// so the source can be rebuilt by joining their text.
+package scanner
+
+import (
+	"sort"
+	"strings"
+
+	"smalltalklsp/interpreter/ast"
+	"smalltalklsp/interpreter/parser"
+)
+
+// Kind is the kind of a lexeme
+type Kind int
+
+const (
+	// Lexeme kinds
+	Whitespace        Kind = iota // Run of spaces, tabs and line breaks
+	Comment                       // "comment", the value holds the text between the quotes
+	Identifier                    // Variable or unary selector such as foo
+	PseudoVariable                // self, super, thisContext, nil, true or false
+	Keyword                       // Keyword such as at:
+	Binary                        // Binary selector such as +, <= or ->
+	Assignment                    // :=
+	Return                        // ^
+	Number                        // Number literal such as 3, -1.5e3 or 16r1F
+	Character                     // Character literal such as $a, the value holds the character
+	String                        // 'string', the value holds the string without quotes
+	Symbol                        // #symbol, the value holds the symbol without #
+	LiteralArrayStart             // Opening #( of a literal array
+	ByteArrayStart                // Opening #[ of a byte array
+	Punctuation                   // One of ( ) [ ] { } . ; : |
+	Error                         // Input that could not be scanned, the value holds the error message
+	EOF                           // End of the input
+)
+
+// kindNames are the names of the kinds
+var kindNames = [...]string{
+	Whitespace:        "whitespace",
+	Comment:           "comment",
+	Identifier:        "identifier",
+	PseudoVariable:    "pseudo-variable",
+	Keyword:           "keyword",
+	Binary:            "binary",
+	Assignment:        "assignment",
+	Return:            "return",
+	Number:            "number",
+	Character:         "character",
+	String:            "string",
+	Symbol:            "symbol",
+	LiteralArrayStart: "literal-array-start",
+	ByteArrayStart:    "byte-array-start",
+	Punctuation:       "punctuation",
+	Error:             "error",
+	EOF:               "eof",
+}
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Lexeme is a piece of the input
+type Lexeme struct {
+	// Kind is the kind of the lexeme
+	Kind Kind
+
+	// Text is the source text of the lexeme
+	Text string
+
+	// Value is the value of literals and comments, or the message of errors,
+	// for other kinds it is the same as the text
+	Value string
+
+	// Source is the range of the lexeme in the input
+	Source ast.SourceRange
+}
+
+// Range returns the range of the lexeme in the input
+func (l Lexeme) Range() ast.SourceRange {
+	return l.Source
+}
+
+// Scan splits the input into lexemes, ending with an EOF lexeme. Scanning
+// never fails, input that is not valid Smalltalk becomes Error lexemes.
+func Scan(input string) []Lexeme {
+	p := parser.NewParser(input, nil, nil)
+	tokens, _ := p.TokenizeWithDiagnostics()
+
+	// Merge the comments into the tokens, both are in source order
+	lexemes := make([]Lexeme, 0, 2*len(tokens))
+	comments := p.Comments
+	literalArrayDepth := 0
+	for _, token := range tokens {
+		for len(comments) > 0 && comments[0].Source.Start.Offset < token.Source.Start.Offset {
+			lexemes = append(lexemes, Lexeme{
+				Kind:   Comment,
+				Text:   input[comments[0].Source.Start.Offset:comments[0].Source.End.Offset],
+				Value:  comments[0].Text,
+				Source: comments[0].Source,
+			})
+			comments = comments[1:]
+		}
+
+		lexeme := Lexeme{
+			Kind:   kindOf(token, literalArrayDepth),
+			Text:   input[token.Source.Start.Offset:token.Source.End.Offset],
+			Value:  token.Value,
+			Source: token.Source,
+		}
+
+		// Inside literal arrays bare parentheses also start an array
+		switch {
+		case lexeme.Kind == LiteralArrayStart,
+			literalArrayDepth > 0 && lexeme.Text == "(":
+			literalArrayDepth++
+		case literalArrayDepth > 0 && lexeme.Text == ")":
+			literalArrayDepth--
+		}
+
+		lexemes = append(lexemes, lexeme)
+	}
+
+	return fillGaps(input, lexemes)
+}
+
+// kindOf returns the kind of a token of the parser
+func kindOf(token parser.Token, literalArrayDepth int) Kind {
+	switch token.Type {
+	case parser.TOKEN_IDENTIFIER:
+		switch {
+		case parser.IsPseudoVariable(token.Value):
+			return PseudoVariable
+		case literalArrayDepth > 0:
+			// Inside literal arrays identifiers and keywords are symbols
+			return Symbol
+		case strings.HasSuffix(token.Value, ":"):
+			return Keyword
+		}
+		return Identifier
+	case parser.TOKEN_NUMBER:
+		return Number
+	case parser.TOKEN_STRING:
+		return String
+	case parser.TOKEN_SYMBOL:
+		return Symbol
+	case parser.TOKEN_KEYWORD:
+		return Keyword
+	case parser.TOKEN_ASSIGNMENT:
+		return Assignment
+	case parser.TOKEN_CHARACTER:
+		return Character
+	case parser.TOKEN_BINARY:
+		return Binary
+	case parser.TOKEN_LITERAL_ARRAY_START:
+		return LiteralArrayStart
+	case parser.TOKEN_BYTE_ARRAY_START:
+		return ByteArrayStart
+	case parser.TOKEN_SPECIAL:
+		if token.Value == "^" {
+			return Return
+		}
+		return Punctuation
+	case parser.TOKEN_EOF:
+		return EOF
+	}
+	return Error
+}
+
+// fillGaps adds lexemes for the input between the given lexemes, which is
+// whitespace or an unterminated comment that runs to the end of the input
+func fillGaps(input string, lexemes []Lexeme) []Lexeme {
+	var result []Lexeme
+	position := ast.Position{Offset: 0, Line: 1, Column: 1}
+
+	for _, lexeme := range lexemes {
+		for position.Offset < lexeme.Source.Start.Offset {
+			gap := input[position.Offset:lexeme.Source.Start.Offset]
+
+			length := strings.IndexFunc(gap, func(r rune) bool {
+				return r != ' ' && r != '\t' && r != '\n' && r != '\r'
+			})
+
+			var next Lexeme
+			switch {
+			case length < 0:
+				next = Lexeme{Kind: Whitespace, Text: gap, Value: gap}
+			case length > 0:
+				next = Lexeme{Kind: Whitespace, Text: gap[:length], Value: gap[:length]}
+			default:
+				next = Lexeme{Kind: Error, Text: gap, Value: "unterminated comment"}
+			}
+
+			end := advance(position, next.Text)
+			next.Source = ast.SourceRange{Start: position, End: end}
+			result = append(result, next)
+			position = end
+		}
+
+		result = append(result, lexeme)
+		position = lexeme.Source.End
+	}
+
+	return result
+}
+
+// advance returns the position after the given text
+func advance(position ast.Position, text string) ast.Position {
+	position.Offset += len(text)
+
+	lines := strings.Count(text, "\n")
+	if lines == 0 {
+		position.Column += len(text)
+		return position
+	}
+
+	position.Line += lines
+	position.Column = len(text) - strings.LastIndex(text, "\n")
+	return position
+}
+
+// At returns the lexeme that contains the given offset, or the EOF lexeme
+// if the offset is at or after the end of the input
+func At(lexemes []Lexeme, offset int) Lexeme {
+	i := sort.Search(len(lexemes), func(i int) bool {
+		return lexemes[i].Source.End.Offset > offset
+	})
+	if i == len(lexemes) {
+		return lexemes[len(lexemes)-1]
+	}
+	return lexemes[i]
+}
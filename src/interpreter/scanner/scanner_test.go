@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestScan tests the kinds and text of the lexemes of a method
+func TestScan(t *testing.T) {
+	input := "at: i \"index\"\n\t^ self foo: #(a b: 3) + x := $a. 'it''s' ~= #[1] | nil"
+
+	expected := []struct {
+		kind Kind
+		text string
+	}{
+		{Keyword, "at:"}, {Whitespace, " "}, {Identifier, "i"}, {Whitespace, " "},
+		{Comment, "\"index\""}, {Whitespace, "\n\t"},
+		{Return, "^"}, {Whitespace, " "}, {PseudoVariable, "self"}, {Whitespace, " "},
+		{Keyword, "foo:"}, {Whitespace, " "},
+		{LiteralArrayStart, "#("}, {Symbol, "a"}, {Whitespace, " "}, {Symbol, "b:"}, {Whitespace, " "},
+		{Number, "3"}, {Punctuation, ")"}, {Whitespace, " "},
+		{Binary, "+"}, {Whitespace, " "}, {Identifier, "x"}, {Whitespace, " "},
+		{Assignment, ":="}, {Whitespace, " "}, {Character, "$a"}, {Punctuation, "."}, {Whitespace, " "},
+		{String, "'it''s'"}, {Whitespace, " "}, {Binary, "~="}, {Whitespace, " "},
+		{ByteArrayStart, "#["}, {Number, "1"}, {Punctuation, "]"}, {Whitespace, " "},
+		{Punctuation, "|"}, {Whitespace, " "}, {PseudoVariable, "nil"},
+		{EOF, ""},
+	}
+
+	lexemes := Scan(input)
+	if len(lexemes) != len(expected) {
+		t.Fatalf("Expected %d lexemes, got %d: %v", len(expected), len(lexemes), lexemes)
+	}
+	for i, e := range expected {
+		if lexemes[i].Kind != e.kind || lexemes[i].Text != e.text {
+			t.Errorf("Lexeme %d: expected %s %q, got %s %q", i, e.kind, e.text, lexemes[i].Kind, lexemes[i].Text)
+		}
+	}
+
+	// Literal values are decoded
+	if lexemes[4].Value != "index" {
+		t.Errorf("Expected comment value %q, got %q", "index", lexemes[4].Value)
+	}
+	if lexemes[29].Value != "it's" {
+		t.Errorf("Expected string value %q, got %q", "it's", lexemes[29].Value)
+	}
+}
+
+// TestScanCoversInput tests that the lexemes cover the input without gaps
+// and that their ranges match their text
+func TestScanCoversInput(t *testing.T) {
+	inputs := []string{
+		"",
+		"  \n  ",
+		"foo\r\n\t\"a\ncomment\" bar: 16r1F baz",
+		"x := 3 @ 4 \"unterminated",
+		"x := 'unterminated",
+		"a ` b",
+		"größe := 'naïve' , \"☃\n\" $é",
+	}
+
+	for _, input := range inputs {
+		lexemes := Scan(input)
+
+		var text strings.Builder
+		line, column := 1, 1
+		for _, lexeme := range lexemes {
+			if lexeme.Source.Start.Offset != text.Len() ||
+				lexeme.Source.Start.Line != line || lexeme.Source.Start.Column != column {
+				t.Errorf("Scanning %q: lexeme %s %q starts at %s, expected offset %d at %d:%d",
+					input, lexeme.Kind, lexeme.Text, lexeme.Source, text.Len(), line, column)
+			}
+			if lexeme.Source.End.Offset-lexeme.Source.Start.Offset != len(lexeme.Text) {
+				t.Errorf("Scanning %q: range %s doesn't match text %q", input, lexeme.Source, lexeme.Text)
+			}
+
+			// Columns count bytes
+			text.WriteString(lexeme.Text)
+			for _, c := range []byte(lexeme.Text) {
+				if c == '\n' {
+					line++
+					column = 1
+				} else {
+					column++
+				}
+			}
+		}
+
+		if text.String() != input {
+			t.Errorf("Expected lexemes to rebuild %q, got %q", input, text.String())
+		}
+		if last := lexemes[len(lexemes)-1]; last.Kind != EOF {
+			t.Errorf("Scanning %q: expected EOF lexeme at the end, got %s", input, last.Kind)
+		}
+	}
+}
+
+// TestScanErrors tests that input that can't be scanned becomes error lexemes
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		text    string
+		message string
+	}{
+		{"x \"open", "\"open", "unterminated comment"},
+		{"x 'open", "'open", "unterminated string"},
+		{"x `", "`", "unknown character: `"},
+		{"x →", "→", "unknown character: →"},
+	}
+
+	for _, test := range tests {
+		lexemes := Scan(test.input)
+		lexeme := At(lexemes, 2)
+		if lexeme.Kind != Error || lexeme.Text != test.text || lexeme.Value != test.message {
+			t.Errorf("Scanning %q: expected error %q for %q, got %s %q %q",
+				test.input, test.message, test.text, lexeme.Kind, lexeme.Text, lexeme.Value)
+		}
+	}
+}
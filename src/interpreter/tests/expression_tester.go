@@ -101,7 +101,14 @@ func RunTests(filename string) ([]ExpressionTest, error) {
 	return results, nil
 }
 
-func evaluateExpression(vmInstance *vm.VM, expression string) (*pile.Object, error) {
+func evaluateExpression(vmInstance *vm.VM, expression string) (result *pile.Object, err error) {
+	// Report compiler and VM panics as a failure of this expression only
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = fmt.Errorf("panic evaluating expression: %v", r)
+		}
+	}()
 
 	// Parse the expression
 	objectClass := pile.ObjectToClass(vmInstance.Globals["Object"])
@@ -114,18 +121,22 @@ func evaluateExpression(vmInstance *vm.VM, expression string) (*pile.Object, err
 	}
 
 	// Compile the parsed expression
-	method := compiler.NewBytecodeCompiler(pile.ClassToObject(objectClass)).Compile(parsed)
+	bytecodeCompiler := compiler.NewBytecodeCompiler(pile.ClassToObject(objectClass))
+	bytecodeCompiler.Globals = vmInstance
+	method, _, err := bytecodeCompiler.Compile(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile expression: %s - %v", expression, err)
+	}
 	methodObj := pile.MethodToObject(method)
 
 	// Create a context for execution
 	context := vm.NewContext(methodObj, pile.ClassToObject(objectClass), []*pile.Object{}, nil)
 
 	// Execute through VM.Execute()
-	result, err := vmInstance.ExecuteContext(context)
+	value, err := vmInstance.ExecuteContext(context)
 	if err != nil {
 		return nil, err
 	}
 
-
-	return result.(*pile.Object), nil
+	return value.(*pile.Object), nil
 }
\ No newline at end of file
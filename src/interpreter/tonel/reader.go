@@ -0,0 +1,391 @@
+package tonel
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"smalltalklsp/interpreter/definition"
+	"smalltalklsp/interpreter/parser"
+)
+
+// ReadPackage reads the class and method definitions of a Tonel package
+// directory, which holds a .class.st or .extension.st file for every class
+func ReadPackage(dir string) (*definition.Definitions, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".class.st") || strings.HasSuffix(file.Name(), ".extension.st") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	definitions := &definition.Definitions{}
+	for _, name := range names {
+		fileDefinitions, err := ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		definitions.Add(fileDefinitions)
+	}
+
+	return definitions, nil
+}
+
+// ReadFile reads the class and method definitions of a Tonel file
+func ReadFile(path string) (*definition.Definitions, error) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Read(path, string(source))
+}
+
+// Read reads the class and method definitions of the source of a Tonel file.
+// The file starts with an optional class comment and a Class or Extension
+// definition, followed by methods such as "Foo >> bar [ ^1 ]" that may be
+// preceded by their metadata.
+func Read(file string, source string) (*definition.Definitions, error) {
+	s := &scanner{file: file, source: source, line: 1}
+	definitions := &definition.Definitions{}
+
+	className, err := s.readClass(definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		s.skipWhitespace()
+		if s.atEnd() {
+			return definitions, nil
+		}
+
+		method, err := s.readMethod()
+		if err != nil {
+			return nil, err
+		}
+		if method.ClassName != className {
+			return nil, definition.Errorf(method.Location, "method %s of class %s in the file of class %s", method.Selector, method.ClassName, className)
+		}
+		definitions.Methods = append(definitions.Methods, method)
+	}
+}
+
+// readClass reads the comment and the Class or Extension definition at the
+// start of a file and returns the name of the class
+func (s *scanner) readClass(definitions *definition.Definitions) (string, error) {
+	s.skipWhitespace()
+
+	comment := ""
+	if !s.atEnd() && s.peek() == '"' {
+		text, err := s.readQuoted('"')
+		if err != nil {
+			return "", err
+		}
+		comment = strings.TrimSuffix(strings.TrimPrefix(text, "\n"), "\n")
+		s.skipWhitespace()
+	}
+
+	location := s.location()
+	kind := s.readWhile(isLetter)
+	if kind != "Class" && kind != "Extension" {
+		return "", s.errorf("expected Class or Extension definition")
+	}
+
+	s.skipWhitespace()
+	metadata, err := s.readSTONMap()
+	if err != nil {
+		return "", err
+	}
+
+	name, err := metadata.string("name")
+	if err != nil || name == "" {
+		return "", definition.Errorf(location, "%s definition without #name", kind)
+	}
+
+	// An extension only adds methods to a class defined elsewhere
+	if kind == "Extension" {
+		return name, nil
+	}
+
+	class := &definition.Class{
+		Name:     name,
+		Comment:  comment,
+		Location: location,
+	}
+
+	fields := []struct {
+		key   string
+		value *[]string
+	}{
+		{"instVars", &class.InstanceVariableNames},
+		{"classVars", &class.ClassVariableNames},
+		{"classInstVars", &class.ClassInstanceVariableNames},
+		{"pools", &class.PoolDictionaries},
+	}
+	for _, field := range fields {
+		*field.value, err = metadata.strings(field.key)
+		if err != nil {
+			return "", definition.Errorf(location, "%s", err)
+		}
+	}
+
+	class.Superclass, err = metadata.string("superclass")
+	if err == nil {
+		class.Package, err = metadata.string("category")
+	}
+	if err != nil {
+		return "", definition.Errorf(location, "%s", err)
+	}
+
+	// Newer versions of Pharo write the package and its tag instead of the category
+	if class.Package == "" {
+		pkg, _ := metadata.string("package")
+		tag, _ := metadata.string("tag")
+		class.Package = pkg
+		if tag != "" {
+			class.Package += "-" + tag
+		}
+	}
+
+	// A superclass of nil makes a root class
+	if class.Superclass == "nil" {
+		class.Superclass = ""
+	}
+
+	definitions.Classes = append(definitions.Classes, class)
+	return name, nil
+}
+
+// readMethod reads a method with its optional metadata, such as
+// "{ #category : #accessing } Foo class >> bar: x [ ^x ]"
+func (s *scanner) readMethod() (*definition.Method, error) {
+	method := &definition.Method{}
+
+	if s.peek() == '{' {
+		metadata, err := s.readSTONMap()
+		if err != nil {
+			return nil, err
+		}
+		method.Category, err = metadata.string("category")
+		if err != nil {
+			return nil, s.errorf("%s", err)
+		}
+		s.skipWhitespace()
+	}
+
+	// Parse the class name and the optional class keyword
+	method.ClassName = s.readWhile(isIdentifierCharacter)
+	if method.ClassName == "" {
+		return nil, s.errorf("expected method definition")
+	}
+	s.skipWhitespace()
+	if word := s.readWhile(isIdentifierCharacter); word == "class" {
+		method.ClassSide = true
+		s.skipWhitespace()
+	} else if word != "" {
+		return nil, s.errorf("unexpected %s after class name %s", word, method.ClassName)
+	}
+	if !strings.HasPrefix(s.source[s.position:], ">>") {
+		return nil, s.errorf("expected >> after class name %s", method.ClassName)
+	}
+	s.position += len(">>")
+	s.skipWhitespace()
+
+	// The pattern runs up to the opening bracket of the body
+	method.Location = s.location()
+	start := s.position
+	for !s.atEnd() && s.peek() != '[' {
+		s.advance()
+	}
+	pattern := strings.TrimSpace(s.source[start:s.position])
+	if s.atEnd() {
+		return nil, definition.Errorf(method.Location, "expected [ after method pattern %s", pattern)
+	}
+
+	selector, err := parsePattern(pattern, method.Location)
+	if err != nil {
+		return nil, err
+	}
+	method.Selector = selector
+
+	body, err := s.readBody()
+	if err != nil {
+		return nil, err
+	}
+
+	method.Source = pattern + strings.TrimRight(body, " \t\r\n")
+	return method, nil
+}
+
+// readBody reads the body of a method between brackets, skipping
+// brackets inside nested blocks, strings, comments and character literals
+func (s *scanner) readBody() (string, error) {
+	location := s.location()
+	s.advance()
+	start := s.position
+	depth := 0
+
+	for !s.atEnd() {
+		switch s.peek() {
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				body := s.source[start:s.position]
+				s.advance()
+				return body, nil
+			}
+			depth--
+		case '\'', '"':
+			if _, err := s.readQuoted(s.peek()); err != nil {
+				return "", err
+			}
+			continue
+		case '$':
+			// Skip the character of a character literal such as $]
+			s.advance()
+			if s.atEnd() {
+				continue
+			}
+		}
+		s.advance()
+	}
+
+	return "", definition.Errorf(location, "unterminated method body")
+}
+
+// parsePattern returns the selector of a method pattern such as "at: index put: value"
+func parsePattern(pattern string, location definition.Location) (string, error) {
+	p := parser.NewParser(pattern, nil, nil)
+	selector, _, err := p.ParseMethodPattern()
+	if err == nil && p.CurrentToken.Type != parser.TOKEN_EOF {
+		err = errors.New("unexpected " + p.CurrentToken.String() + " after method pattern")
+	}
+	if err != nil {
+		var parseError *parser.ParseError
+		if errors.As(err, &parseError) {
+			return "", definition.Errorf(location.Offset(parseError.Range.Start.Line), "%s", parseError.Message)
+		}
+		return "", definition.Errorf(location, "%s", err)
+	}
+
+	return selector, nil
+}
+
+// scanner reads the characters of a Tonel file, keeping track of the line
+type scanner struct {
+	// file is the name of the file
+	file string
+
+	// source is the content of the file
+	source string
+
+	// position is the offset of the current character
+	position int
+
+	// line is the line of the current character, starting at 1
+	line int
+}
+
+// atEnd returns true if all characters have been read
+func (s *scanner) atEnd() bool {
+	return s.position >= len(s.source)
+}
+
+// peek returns the current character
+func (s *scanner) peek() byte {
+	return s.source[s.position]
+}
+
+// advance moves to the next character
+func (s *scanner) advance() {
+	if s.source[s.position] == '\n' {
+		s.line++
+	}
+	s.position++
+}
+
+// next returns the current character and moves to the next one
+func (s *scanner) next() byte {
+	c := s.peek()
+	s.advance()
+	return c
+}
+
+// consume moves past the current character if it is c and returns true
+func (s *scanner) consume(c byte) bool {
+	if s.atEnd() || s.peek() != c {
+		return false
+	}
+	s.advance()
+	return true
+}
+
+// readWhile reads characters as long as the predicate holds
+func (s *scanner) readWhile(predicate func(c byte) bool) string {
+	start := s.position
+	for !s.atEnd() && predicate(s.peek()) {
+		s.advance()
+	}
+	return s.source[start:s.position]
+}
+
+// skipWhitespace skips whitespace
+func (s *scanner) skipWhitespace() {
+	s.readWhile(func(c byte) bool {
+		return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+	})
+}
+
+// readQuoted reads a string or comment and returns its text, where a doubled
+// quote stands for the quote itself
+func (s *scanner) readQuoted(quote byte) (string, error) {
+	location := s.location()
+	var text strings.Builder
+
+	s.advance()
+	for !s.atEnd() {
+		c := s.next()
+		if c == quote {
+			if s.atEnd() || s.peek() != quote {
+				return text.String(), nil
+			}
+			s.advance()
+		}
+		text.WriteByte(c)
+	}
+
+	if quote == '"' {
+		return "", definition.Errorf(location, "unterminated comment")
+	}
+	return "", definition.Errorf(location, "unterminated string")
+}
+
+// location returns the location of the current character
+func (s *scanner) location() definition.Location {
+	return definition.Location{File: s.file, Line: s.line}
+}
+
+// errorf creates an error at the current location
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return definition.Errorf(s.location(), format, args...)
+}
+
+// isLetter returns true if the character is a letter
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isIdentifierCharacter returns true if the character can appear in an identifier
+func isIdentifierCharacter(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '_'
+}
@@ -0,0 +1,238 @@
+package tonel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The metadata of Tonel files is written in STON, the Smalltalk Object
+// Notation. Only the part of STON that Tonel uses is supported: maps with
+// symbol keys whose values are symbols, strings, lists, numbers, true,
+// false and nil. Symbols and strings both read as Go strings and lists as
+// []interface{}; any other value reads as its source text.
+
+// stonMap is a STON map by key
+type stonMap map[string]interface{}
+
+// string returns the value of a key that holds a symbol or string
+func (m stonMap) string(key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected #%s to be a symbol or string", key)
+	}
+	return s, nil
+}
+
+// strings returns the value of a key that holds a list of symbols or strings
+func (m stonMap) strings(key string) ([]string, error) {
+	value, ok := m[key]
+	if !ok {
+		return nil, nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected #%s to be a list", key)
+	}
+
+	var result []string
+	for _, element := range list {
+		s, ok := element.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected #%s to be a list of symbols or strings", key)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// readSTONMap reads a STON map such as { #name : #Foo, #instVars : [ 'a' ] }
+func (s *scanner) readSTONMap() (stonMap, error) {
+	if !s.consume('{') {
+		return nil, s.errorf("expected {")
+	}
+
+	result := stonMap{}
+
+	s.skipWhitespace()
+	if s.consume('}') {
+		return result, nil
+	}
+
+	for {
+		s.skipWhitespace()
+		key, err := s.readSTONValue()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, s.errorf("expected a symbol or string as map key")
+		}
+
+		s.skipWhitespace()
+		if !s.consume(':') {
+			return nil, s.errorf("expected : after map key #%s", name)
+		}
+
+		s.skipWhitespace()
+		value, err := s.readSTONValue()
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+
+		s.skipWhitespace()
+		if s.consume('}') {
+			return result, nil
+		}
+		if !s.consume(',') {
+			return nil, s.errorf("expected , or } in map")
+		}
+	}
+}
+
+// readSTONValue reads a STON symbol, string, list or simple value
+func (s *scanner) readSTONValue() (interface{}, error) {
+	if s.atEnd() {
+		return nil, s.errorf("unexpected end of file")
+	}
+
+	switch c := s.peek(); {
+	case c == '\'':
+		return s.readSTONString()
+	case c == '#':
+		s.advance()
+		if !s.atEnd() && s.peek() == '\'' {
+			return s.readSTONString()
+		}
+		// Unquoted symbols written by other tools may contain dashes
+		symbol := s.readWhile(func(c byte) bool { return isSymbolCharacter(c) || c == '-' })
+		if symbol == "" {
+			return nil, s.errorf("invalid symbol")
+		}
+		return symbol, nil
+	case c == '[':
+		return s.readSTONList()
+	case isSymbolCharacter(c) || c == '-':
+		s.advance()
+		return string(c) + s.readWhile(isSymbolCharacter), nil
+	}
+
+	return nil, s.errorf("unexpected character %q", s.peek())
+}
+
+// readSTONString reads a quoted STON string, in which a backslash escapes the next character
+func (s *scanner) readSTONString() (string, error) {
+	var value strings.Builder
+
+	s.advance()
+	for !s.atEnd() {
+		c := s.next()
+
+		if c == '\'' {
+			return value.String(), nil
+		}
+
+		if c == '\\' && !s.atEnd() {
+			switch e := s.next(); e {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			default:
+				value.WriteByte(e)
+			}
+			continue
+		}
+
+		value.WriteByte(c)
+	}
+
+	return "", s.errorf("unterminated string")
+}
+
+// readSTONList reads a STON list such as [ 'a', 'b' ]
+func (s *scanner) readSTONList() ([]interface{}, error) {
+	s.advance()
+
+	list := []interface{}{}
+
+	s.skipWhitespace()
+	if s.consume(']') {
+		return list, nil
+	}
+
+	for {
+		s.skipWhitespace()
+		value, err := s.readSTONValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+
+		s.skipWhitespace()
+		if s.consume(']') {
+			return list, nil
+		}
+		if !s.consume(',') {
+			return nil, s.errorf("expected , or ] in list")
+		}
+	}
+}
+
+// isSymbolCharacter returns true if the character can appear in an unquoted symbol
+func isSymbolCharacter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '_' || c == ':' || c == '.' || c == '/'
+}
+
+// stonSymbol returns a value written as a STON symbol, quoting it if needed
+func stonSymbol(value string) string {
+	if value == "" || !isPlainSymbol(value) {
+		return "#" + stonString(value)
+	}
+	return "#" + value
+}
+
+// isPlainSymbol returns true if the value can be written as a symbol without quotes
+func isPlainSymbol(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if !isSymbolCharacter(value[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// stonString returns a value written as a quoted STON string
+func stonString(value string) string {
+	var result strings.Builder
+
+	result.WriteByte('\'')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\'', '\\':
+			result.WriteByte('\\')
+			result.WriteByte(c)
+		case '\n':
+			result.WriteString("\\n")
+		case '\r':
+			result.WriteString("\\r")
+		case '\t':
+			result.WriteString("\\t")
+		default:
+			result.WriteByte(c)
+		}
+	}
+	result.WriteByte('\'')
+
+	return result.String()
+}
@@ -0,0 +1,250 @@
+package tonel
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"smalltalklsp/interpreter/chunk"
+	"smalltalklsp/interpreter/definition"
+)
+
+// pointSource is a class written by Pharo in Tonel format
+const pointSource = `"
+I am a point in the plane, I say ""hello"".
+"
+Class {
+	#name : #Point,
+	#superclass : #Object,
+	#instVars : [
+		'x',
+		'y'
+	],
+	#classVars : [
+		'Origin'
+	],
+	#category : #'Graphics-Basic'
+}
+
+{ #category : #'instance creation' }
+Point class >> x: anX y: aY [
+
+	^ self new setX: anX y: aY
+]
+
+{ #category : #accessing }
+Point >> x [
+	"Answer the x coordinate, not the ']' of a block"
+
+	^ x
+]
+
+{ #category : #arithmetic }
+Point >> + aPoint [
+	^ [:p | Point x: x + p x y: y + $] value ] value: aPoint
+]
+`
+
+// TestRead tests reading a Tonel class file
+func TestRead(t *testing.T) {
+	definitions, err := Read("Point.class.st", pointSource)
+	if err != nil {
+		t.Fatalf("Error reading Tonel file: %v", err)
+	}
+
+	if len(definitions.Classes) != 1 {
+		t.Fatalf("Expected 1 class, got %d", len(definitions.Classes))
+	}
+	class := definitions.Classes[0]
+	expectedClass := &definition.Class{
+		Name:                  "Point",
+		Superclass:            "Object",
+		InstanceVariableNames: []string{"x", "y"},
+		ClassVariableNames:    []string{"Origin"},
+		Package:               "Graphics-Basic",
+		Comment:               "I am a point in the plane, I say \"hello\".",
+		Location:              definition.Location{File: "Point.class.st", Line: 4},
+	}
+	if !reflect.DeepEqual(class, expectedClass) {
+		t.Errorf("Expected class %+v, got %+v", expectedClass, class)
+	}
+
+	expected := []struct {
+		selector  string
+		classSide bool
+		category  string
+		source    string
+		line      int
+	}{
+		{"x:y:", true, "instance creation", "x: anX y: aY\n\n\t^ self new setX: anX y: aY", 18},
+		{"x", false, "accessing", "x\n\t\"Answer the x coordinate, not the ']' of a block\"\n\n\t^ x", 24},
+		{"+", false, "arithmetic", "+ aPoint\n\t^ [:p | Point x: x + p x y: y + $] value ] value: aPoint", 31},
+	}
+
+	if len(definitions.Methods) != len(expected) {
+		t.Fatalf("Expected %d methods, got %d", len(expected), len(definitions.Methods))
+	}
+	for i, e := range expected {
+		method := definitions.Methods[i]
+		if method.ClassName != "Point" || method.ClassSide != e.classSide {
+			t.Errorf("Method %d: expected class side %v of Point, got %v of %s", i, e.classSide, method.ClassSide, method.ClassName)
+		}
+		if method.Selector != e.selector {
+			t.Errorf("Method %d: expected selector %s, got %s", i, e.selector, method.Selector)
+		}
+		if method.Category != e.category {
+			t.Errorf("Method %d: expected category %s, got %s", i, e.category, method.Category)
+		}
+		if method.Source != e.source {
+			t.Errorf("Method %d: expected source %q, got %q", i, e.source, method.Source)
+		}
+		if method.Location.Line != e.line {
+			t.Errorf("Method %d: expected line %d, got %d", i, e.line, method.Location.Line)
+		}
+	}
+}
+
+// TestReadExtension tests reading a Tonel extension file
+func TestReadExtension(t *testing.T) {
+	source := "Extension { #name : #String }\n\n{ #category : #'*Graphics' }\nString >> asPoint [\n\t^ Point x: 0 y: 0\n]\n"
+
+	definitions, err := Read("String.extension.st", source)
+	if err != nil {
+		t.Fatalf("Error reading Tonel file: %v", err)
+	}
+
+	if len(definitions.Classes) != 0 {
+		t.Errorf("Expected no classes, got %d", len(definitions.Classes))
+	}
+	if len(definitions.Methods) != 1 || definitions.Methods[0].ClassName != "String" || definitions.Methods[0].Selector != "asPoint" {
+		t.Fatalf("Expected method String>>asPoint, got %v", definitions.Methods)
+	}
+}
+
+// TestReadErrors tests that errors point back to the file and line
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "missing class definition",
+			source:   "Point >> x [ ^x ]",
+			expected: "Foo.st:1: expected Class or Extension definition",
+		},
+		{
+			name:     "unterminated method body",
+			source:   "Class { #name : #Foo }\n\nFoo >> x [\n\t^ [ x\n]\n",
+			expected: "Foo.st:3: unterminated method body",
+		},
+		{
+			name:     "method of another class",
+			source:   "Class { #name : #Foo }\n\nBar >> x [ ^x ]\n",
+			expected: "Foo.st:3: method x of class Bar in the file of class Foo",
+		},
+		{
+			name:     "invalid metadata",
+			source:   "Class {\n\t#name : #Foo\n\t#superclass : #Object\n}",
+			expected: "Foo.st:3: expected , or } in map",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Read("Foo.st", test.source)
+			if err == nil {
+				t.Fatalf("Expected error %q, got none", test.expected)
+			}
+			if err.Error() != test.expected {
+				t.Errorf("Expected error %q, got %q", test.expected, err.Error())
+			}
+		})
+	}
+}
+
+// TestWriteClass tests writing a class in Tonel format
+func TestWriteClass(t *testing.T) {
+	definitions, err := Read("Point.class.st", pointSource)
+	if err != nil {
+		t.Fatalf("Error reading Tonel file: %v", err)
+	}
+
+	var out strings.Builder
+	err = WriteClass(&out, definitions.Classes[0], definitions.Methods)
+	if err != nil {
+		t.Fatalf("Error writing Tonel file: %v", err)
+	}
+
+	// Methods are sorted by side, category and selector, which
+	// is the order Pharo wrote them in
+	if out.String() != pointSource {
+		t.Errorf("Expected written file to match the Pharo file, got:\n%s", out.String())
+	}
+}
+
+// TestRoundTrip tests writing the chunk files of the Smalltalk sources
+// as a Tonel package and reading them back
+func TestRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../../*.st")
+	if err != nil {
+		t.Fatalf("Error listing source files: %v", err)
+	}
+	if len(files) == 0 {
+		t.Skip("No source files found")
+	}
+
+	original := &definition.Definitions{}
+	for _, file := range files {
+		definitions, err := chunk.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Error reading %s: %v", file, err)
+		}
+		original.Add(definitions)
+	}
+
+	dir := t.TempDir()
+	err = WritePackage(dir, "SmalltalkInterpreter", original)
+	if err != nil {
+		t.Fatalf("Error writing package: %v", err)
+	}
+
+	read, err := ReadPackage(filepath.Join(dir, "SmalltalkInterpreter"))
+	if err != nil {
+		t.Fatalf("Error reading package: %v", err)
+	}
+
+	if len(read.Classes) != len(original.Classes) {
+		t.Fatalf("Expected %d classes, got %d", len(original.Classes), len(read.Classes))
+	}
+	for _, class := range original.Classes {
+		readClass := read.Class(class.Name)
+		if readClass == nil {
+			t.Errorf("Class %s was not read back", class.Name)
+			continue
+		}
+		if readClass.Superclass != class.Superclass || readClass.Package != class.Package ||
+			strings.Join(readClass.InstanceVariableNames, " ") != strings.Join(class.InstanceVariableNames, " ") ||
+			strings.Join(readClass.ClassVariableNames, " ") != strings.Join(class.ClassVariableNames, " ") {
+			t.Errorf("Class %s was read back as %+v", class.Name, readClass)
+		}
+
+		for _, classSide := range []bool{false, true} {
+			methods := methodSources(original.MethodsOf(class.Name, classSide))
+			readMethods := methodSources(read.MethodsOf(class.Name, classSide))
+			if !reflect.DeepEqual(readMethods, methods) {
+				t.Errorf("Methods of %s were not read back unchanged", class.Name)
+			}
+		}
+	}
+}
+
+// methodSources returns the categories and sources of methods by selector
+func methodSources(methods []*definition.Method) map[string]string {
+	sources := make(map[string]string)
+	for _, method := range methods {
+		sources[method.Selector] = method.Category + "\n" + method.Source
+	}
+	return sources
+}
@@ -0,0 +1,194 @@
+package tonel
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"smalltalklsp/interpreter/definition"
+	"smalltalklsp/interpreter/parser"
+)
+
+// WritePackage writes definitions as a Tonel package directory named after
+// the package. Every class gets a .class.st file and methods of classes that
+// aren't defined in the package go to .extension.st files. Expressions have
+// no place in Tonel and are not written.
+func WritePackage(dir string, name string, definitions *definition.Definitions) error {
+	packageDir := filepath.Join(dir, name)
+	if err := os.MkdirAll(packageDir, 0755); err != nil {
+		return err
+	}
+
+	packageSource := fmt.Sprintf("Package { #name : %s }\n", stonSymbol(name))
+	if err := ioutil.WriteFile(filepath.Join(packageDir, "package.st"), []byte(packageSource), 0644); err != nil {
+		return err
+	}
+
+	for _, class := range definitions.Classes {
+		methods := append(definitions.MethodsOf(class.Name, false), definitions.MethodsOf(class.Name, true)...)
+		if err := writeFile(filepath.Join(packageDir, class.Name+".class.st"), func(w io.Writer) error {
+			return WriteClass(w, class, methods)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Group the methods of classes defined elsewhere by class
+	extensions := make(map[string][]*definition.Method)
+	for _, method := range definitions.Methods {
+		if definitions.Class(method.ClassName) == nil {
+			extensions[method.ClassName] = append(extensions[method.ClassName], method)
+		}
+	}
+	for className, methods := range extensions {
+		methods := methods
+		if err := writeFile(filepath.Join(packageDir, className+".extension.st"), func(w io.Writer) error {
+			return WriteExtension(w, className, methods)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile creates a file and writes its content with the given function
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteClass writes a class definition and its methods in Tonel format
+func WriteClass(w io.Writer, class *definition.Class, methods []*definition.Method) error {
+	var out strings.Builder
+
+	if class.Comment != "" {
+		out.WriteString("\"\n")
+		out.WriteString(strings.ReplaceAll(class.Comment, "\"", "\"\""))
+		out.WriteString("\n\"\n")
+	}
+
+	superclass := class.Superclass
+	if superclass == "" {
+		superclass = "nil"
+	}
+
+	entries := []string{
+		"#name : " + stonSymbol(class.Name),
+		"#superclass : " + stonSymbol(superclass),
+	}
+	lists := []struct {
+		key   string
+		names []string
+	}{
+		{"instVars", class.InstanceVariableNames},
+		{"classVars", class.ClassVariableNames},
+		{"classInstVars", class.ClassInstanceVariableNames},
+		{"pools", class.PoolDictionaries},
+	}
+	for _, list := range lists {
+		if len(list.names) > 0 {
+			entries = append(entries, "#"+list.key+" : "+stonList(list.names))
+		}
+	}
+	if class.Package != "" {
+		entries = append(entries, "#category : "+stonSymbol(class.Package))
+	}
+
+	out.WriteString("Class {\n\t")
+	out.WriteString(strings.Join(entries, ",\n\t"))
+	out.WriteString("\n}\n")
+
+	if err := writeMethods(&out, methods); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// WriteExtension writes methods of a class defined in another package in Tonel format
+func WriteExtension(w io.Writer, className string, methods []*definition.Method) error {
+	var out strings.Builder
+
+	out.WriteString("Extension { #name : " + stonSymbol(className) + " }\n")
+
+	if err := writeMethods(&out, methods); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// writeMethods writes methods sorted the way Pharo writes them, class side
+// first and then by category and selector, so files diff well
+func writeMethods(out *strings.Builder, methods []*definition.Method) error {
+	sorted := append([]*definition.Method{}, methods...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.ClassSide != b.ClassSide {
+			return a.ClassSide
+		}
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Selector < b.Selector
+	})
+
+	for _, method := range sorted {
+		pattern, body, err := splitPattern(method)
+		if err != nil {
+			return err
+		}
+
+		out.WriteString("\n")
+		if method.Category != "" {
+			out.WriteString("{ #category : " + stonSymbol(method.Category) + " }\n")
+		}
+
+		out.WriteString(method.ClassName)
+		if method.ClassSide {
+			out.WriteString(" class")
+		}
+		out.WriteString(" >> " + pattern + " [")
+		out.WriteString(body)
+		out.WriteString("\n]\n")
+	}
+
+	return nil
+}
+
+// splitPattern splits the source of a method into its pattern, such as
+// "at: index put: value", and the rest of the source without trailing whitespace
+func splitPattern(method *definition.Method) (string, string, error) {
+	p := parser.NewParser(method.Source, nil, nil)
+	_, _, err := p.ParseMethodPattern()
+	if err != nil {
+		return "", "", definition.Errorf(method.Location, "%s", err)
+	}
+
+	end := p.Tokens[p.CurrentTokenIndex-1].Source.End.Offset
+	return method.Source[:end], strings.TrimRight(method.Source[end:], " \t\r\n"), nil
+}
+
+// stonList returns names written as a STON list of strings, one per line
+func stonList(names []string) string {
+	var quoted []string
+	for _, name := range names {
+		quoted = append(quoted, stonString(name))
+	}
+	return "[\n\t\t" + strings.Join(quoted, ",\n\t\t") + "\n\t]"
+}
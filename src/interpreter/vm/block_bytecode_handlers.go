@@ -7,42 +7,30 @@ import (
 	"smalltalklsp/interpreter/pile"
 )
 
-// ExecuteCreateBlock executes the CREATE_BLOCK bytecode
+// ExecuteCreateBlock executes the CREATE_BLOCK bytecode, making a closure
+// over the current context that runs the code of a compiled block literal
 func (vm *VM) ExecuteCreateBlock(context *Context) error {
 	// Get the method
 	method := pile.ObjectToMethod(context.Method)
 
-	// Get the bytecode size (4 bytes)
-	bytecodeSize := int(binary.BigEndian.Uint32(method.GetBytecodes()[context.PC+1:]))
-
-	// Get the literal count (4 bytes)
-	literalCount := int(binary.BigEndian.Uint32(method.GetBytecodes()[context.PC+5:]))
-
-	// Get the temp var count (4 bytes)
-	tempVarCount := int(binary.BigEndian.Uint32(method.GetBytecodes()[context.PC+9:]))
-
-	// Create a new block
-	block := pile.ObjectToBlock(vm.NewBlock(context))
-
-	// Set the bytecodes
-	// In a real implementation, we would extract the bytecodes from the method
-	// For now, we'll just create an empty bytecode array
-	block.SetBytecodes(make([]byte, bytecodeSize))
-
-	// Set the literals
-	// In a real implementation, we would extract the literals from the method
-	// For now, we'll just create an empty literal array
-	for i := 0; i < literalCount; i++ {
-		block.AddLiteral(pile.MakeNilImmediate())
+	// Get the compiled block index (4 bytes)
+	index := int(binary.BigEndian.Uint32(method.GetBytecodes()[context.PC+1:]))
+	if index < 0 || index >= len(method.GetLiterals()) {
+		return fmt.Errorf("compiled block index out of bounds: %d", index)
 	}
 
-	// Set the temporary variable names
-	// In a real implementation, we would extract the temp var names from the method
-	// For now, we'll just create empty temp var names
-	for i := 0; i < tempVarCount; i++ {
-		block.AddTempVarName(fmt.Sprintf("temp%d", i))
+	compiledBlock := pile.ObjectToCompiledBlock(method.GetLiterals()[index])
+	if compiledBlock == nil {
+		return fmt.Errorf("literal %d is not a compiled block", index)
 	}
 
+	// Create a new block running the compiled code in this context
+	block := pile.ObjectToBlock(vm.NewBlock(context))
+	block.SetBytecodes(compiledBlock.Bytecodes)
+	block.Literals = compiledBlock.Literals
+	block.TempVarNames = compiledBlock.TempVarNames
+	block.SetNumArgs(compiledBlock.NumArgs)
+
 	// Push the block onto the stack
 	context.Push(pile.BlockToObject(block))
 
@@ -76,6 +64,9 @@ func (vm *VM) ExecuteExecuteBlock(context *Context) (*pile.Object, error) {
 
 	// Convert to a Block
 	block := pile.ObjectToBlock(blockObj)
+	if argCount != block.GetNumArgs() {
+		return nil, fmt.Errorf("wrong number of block arguments: expected %d, got %d", block.GetNumArgs(), argCount)
+	}
 
 	// Execute the block
 	result := block.ValueWithArguments(args)
@@ -85,3 +76,23 @@ func (vm *VM) ExecuteExecuteBlock(context *Context) (*pile.Object, error) {
 
 	return result, nil
 }
+
+// ExecuteBlockReturn executes the BLOCK_RETURN bytecode. It returns a
+// *NonLocalReturn error unwinding to the home context of the block. If the
+// home context has already returned it signals BlockCannotReturn instead,
+// and the block returns the value of the handler.
+func (vm *VM) ExecuteBlockReturn(context *Context) (*pile.Object, error) {
+	value, err := vm.ExecuteReturnStackTop(context)
+	if err != nil {
+		return nil, err
+	}
+
+	home := context.Home()
+	if home.Dead {
+		exception := pile.ObjectToException(pile.NewException(vm.Globals["BlockCannotReturn"]))
+		exception.SetMessageText(vm.NewString("block cannot return"))
+		return exception.Signal(), nil
+	}
+
+	return nil, &NonLocalReturn{Home: home, Value: value}
+}
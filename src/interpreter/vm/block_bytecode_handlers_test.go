@@ -12,6 +12,18 @@ func TestExecuteCreateBlock(t *testing.T) {
 	// Create a VM
 	virtualMachine := vm.NewVM()
 
+	// The compiled block [:a :b | | c | 5]
+	compiledBlock := pile.NewCompiledBlock(
+		[]byte{
+			bytecode.PUSH_LITERAL,
+			0, 0, 0, 0, // literal index 0 (the value 5)
+			bytecode.RETURN_STACK_TOP,
+		},
+		[]*pile.Object{pile.MakeIntegerImmediate(5)},
+		[]string{"a", "b", "c"},
+		2,
+	)
+
 	// Create a method with a CREATE_BLOCK bytecode
 	method := &pile.Method{
 		Object: pile.Object{
@@ -19,11 +31,9 @@ func TestExecuteCreateBlock(t *testing.T) {
 		},
 		Bytecodes: []byte{
 			bytecode.CREATE_BLOCK,
-			0, 0, 0, 10, // bytecode size
-			0, 0, 0, 2, // literal count
-			0, 0, 0, 3, // temp var count
+			0, 0, 0, 1, // literal index 1 (the compiled block)
 		},
-		Literals:     []*pile.Object{},
+		Literals:     []*pile.Object{pile.MakeNilImmediate(), pile.CompiledBlockToObject(compiledBlock)},
 		TempVarNames: []string{},
 	}
 
@@ -38,44 +48,41 @@ func TestExecuteCreateBlock(t *testing.T) {
 	// Execute the CREATE_BLOCK bytecode
 	err := virtualMachine.ExecuteCreateBlock(context)
 	if err != nil {
-		t.Errorf("ExecuteCreateBlock returned an error: %v", err)
+		t.Fatalf("ExecuteCreateBlock returned an error: %v", err)
 	}
 
 	// Check that a block was pushed onto the stack
 	if context.StackPointer != 1 {
-		t.Errorf("Stack pointer = %d, want 1", context.StackPointer)
+		t.Fatalf("Stack pointer = %d, want 1", context.StackPointer)
 	}
 
 	// Check that the block is valid
 	block := context.Pop()
-	if block == nil {
-		t.Errorf("Block is nil")
-	}
-
 	if block.Type() != pile.OBJ_BLOCK {
-		t.Errorf("Block type = %d, want %d", block.Type(), pile.OBJ_BLOCK)
+		t.Fatalf("Block type = %d, want %d", block.Type(), pile.OBJ_BLOCK)
 	}
 
-	// Convert to a Block
+	// The block runs the code of the compiled block in this context
 	blockObj := pile.ObjectToBlock(block)
-	if blockObj == nil {
-		t.Errorf("Failed to convert to Block")
+	if len(blockObj.GetBytecodes()) != 6 || blockObj.GetBytecodes()[0] != bytecode.PUSH_LITERAL {
+		t.Errorf("Block bytecodes = %v, want the compiled bytecodes", blockObj.GetBytecodes())
 	}
-
-	// Check the block's bytecodes
-	if len(blockObj.GetBytecodes()) != 10 {
-		t.Errorf("Block bytecode size = %d, want 10", len(blockObj.GetBytecodes()))
+	if len(blockObj.GetLiterals()) != 1 || blockObj.GetLiterals()[0] != compiledBlock.Literals[0] {
+		t.Errorf("Block literals = %v, want the compiled literals", blockObj.GetLiterals())
 	}
-
-	// Check the block's literals
-	if len(blockObj.GetLiterals()) != 2 {
-		t.Errorf("Block literal count = %d, want 2", len(blockObj.GetLiterals()))
-	}
-
-	// Check the block's temp var names
 	if len(blockObj.GetTempVarNames()) != 3 {
 		t.Errorf("Block temp var count = %d, want 3", len(blockObj.GetTempVarNames()))
 	}
+	if blockObj.GetOuterContext() != context {
+		t.Errorf("Block outer context = %v, want the creating context", blockObj.GetOuterContext())
+	}
+
+	// A literal that is not a compiled block is an error
+	method.Bytecodes[4] = 0
+	context.PC = 0
+	if err := virtualMachine.ExecuteCreateBlock(context); err == nil {
+		t.Errorf("Expected an error creating a block from nil")
+	}
 }
 
 func TestExecuteExecuteBlock(t *testing.T) {
@@ -106,6 +113,10 @@ func TestExecuteExecuteBlock(t *testing.T) {
 	// Create a block with proper class field
 	block := pile.ObjectToBlock(virtualMachine.NewBlock(context))
 
+	// The block takes the two arguments
+	block.TempVarNames = []string{"a", "b"}
+	block.SetNumArgs(2)
+
 	// Push the block onto the stack
 	context.Push(pile.BlockToObject(block))
 
@@ -135,3 +146,30 @@ func TestExecuteExecuteBlock(t *testing.T) {
 		t.Errorf("Stack result = %v, want nil", stackResult)
 	}
 }
+
+// TestExecuteExecuteBlockArgumentCount tests that the EXECUTE_BLOCK bytecode
+// rejects an argument count the block doesn't take
+func TestExecuteExecuteBlockArgumentCount(t *testing.T) {
+	virtualMachine := vm.NewVM()
+
+	method := &pile.Method{
+		Object: pile.Object{
+			TypeField: pile.OBJ_METHOD,
+		},
+		Bytecodes: []byte{
+			bytecode.EXECUTE_BLOCK,
+			0, 0, 0, 1, // arg count
+		},
+		Literals:     []*pile.Object{},
+		TempVarNames: []string{},
+	}
+	context := vm.NewContext(pile.MethodToObject(method), pile.MakeNilImmediate(), []*pile.Object{}, nil)
+
+	// The block takes no arguments
+	context.Push(virtualMachine.NewBlock(context))
+	context.Push(pile.MakeIntegerImmediate(1))
+
+	if _, err := virtualMachine.ExecuteExecuteBlock(context); err == nil {
+		t.Errorf("Expected an error for a block evaluated with 1 argument instead of 0")
+	}
+}
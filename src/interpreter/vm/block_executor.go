@@ -1,10 +1,25 @@
 package vm
 
 import (
+	"fmt"
+
 	"smalltalklsp/interpreter/pile"
 	"smalltalklsp/interpreter/runtime"
 )
 
+// NonLocalReturn is a ^ in a block returning a value from the block's home
+// context. It unwinds the contexts in between as an error returned by the
+// bytecode handlers, and as a panic through blocks evaluated by primitives.
+type NonLocalReturn struct {
+	Home  *Context
+	Value *pile.Object
+}
+
+// Error returns a description of the non-local return
+func (r *NonLocalReturn) Error() string {
+	return fmt.Sprintf("non-local return of %v", r.Value)
+}
+
 // init registers the VM as a block executor
 func init() {
 	// The VM will be registered as a block executor when it's created
@@ -23,6 +38,11 @@ func (vm *VM) ExecuteBlock(block *pile.Object, args []*pile.Object) *pile.Object
 		panic("ExecuteBlock: invalid block")
 	}
 
+	// The arguments fill the first temporaries, so their count must match
+	if len(args) != blockObj.GetNumArgs() {
+		panic(fmt.Sprintf("ExecuteBlock: wrong number of block arguments: expected %d, got %d", blockObj.GetNumArgs(), len(args)))
+	}
+
 	// Get the outer context
 	outerContext, ok := blockObj.GetOuterContext().(*Context)
 	if !ok {
@@ -39,42 +59,61 @@ func (vm *VM) ExecuteBlock(block *pile.Object, args []*pile.Object) *pile.Object
 		TempVarNames: blockObj.GetTempVarNames(),
 	}
 
-	// Create a new context for the block execution
+	// Create a new context for the block execution. It returns to the
+	// context that evaluated the block and looks up captured variables in
+	// the context that created it.
 	blockContext := NewContext(
 		pile.MethodToObject(methodObj),
 		outerContext.GetReceiver(),
 		args,
-		outerContext,
+		vm.Executor.CurrentContext,
 	)
+	blockContext.Outer = outerContext
 
-	// Manually set up the temporary variables with the arguments
-	// This is necessary because the arguments passed to NewContext
-	// are not automatically copied to the temporary variables
-	for i, arg := range args {
-		if i < len(blockObj.GetTempVarNames()) {
-			blockContext.SetTempVarByIndex(i, arg)
-		}
-	}
-
-	// Save the current context
+	// Save the current context, restoring it when the block is left
 	savedContext := vm.Executor.CurrentContext
+	defer func() {
+		vm.Executor.CurrentContext = savedContext
+	}()
 
 	// Set the current context to the block context
 	vm.Executor.CurrentContext = blockContext
 
 	// Execute the block
 	result, err := vm.ExecuteContext(blockContext)
+	if nonLocalReturn, ok := err.(*NonLocalReturn); ok {
+		panic(nonLocalReturn)
+	}
 	if err != nil {
 		panic("ExecuteBlock: " + err.Error())
 	}
 
-	// Restore the current context
-	vm.Executor.CurrentContext = savedContext
-
 	// Return the result
 	return result.(*pile.Object)
 }
 
+// ExecuteBlockEnsure evaluates a block and then the ensure block, also when
+// the block is left by a non-local return or an exception
+func (vm *VM) ExecuteBlockEnsure(block *pile.Object, ensureBlock *pile.Object) *pile.Object {
+	// Evaluate the block, catching whatever unwinds it
+	var result *pile.Object
+	unwinding := func() (reason interface{}) {
+		defer func() {
+			reason = recover()
+		}()
+		result = vm.ExecuteBlock(block, []*pile.Object{})
+		return nil
+	}()
+
+	// Evaluate the ensure block, then carry on unwinding
+	vm.ExecuteBlock(ensureBlock, []*pile.Object{})
+	if unwinding != nil {
+		panic(unwinding)
+	}
+
+	return result
+}
+
 // RegisterAsBlockExecutor registers the VM as a block executor
 func (vm *VM) RegisterAsBlockExecutor() {
 	runtime.RegisterBlockExecutor(vm)
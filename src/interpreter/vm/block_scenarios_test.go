@@ -204,8 +204,9 @@ func TestBlockWithParameter(t *testing.T) {
 		pile.NewSymbol("+"),          // The + selector
 	}
 
-	// Set the block's temp var names
+	// Set the block's temp var names, x being its argument
 	block.TempVarNames = []string{"x"}
+	block.SetNumArgs(1)
 
 	// Execute the block with an argument
 	result := block.ValueWithArguments([]*pile.Object{
@@ -0,0 +1,136 @@
+package vm_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/runtime"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestBlockLexicalScope tests that compiled blocks read and write the
+// variables of the methods and blocks they are written in, wherever they run
+func TestBlockLexicalScope(t *testing.T) {
+	virtualMachine := vm.NewVM()
+	runtime.RegisterBlockExecutor(virtualMachine)
+
+	class := virtualMachine.NewClass("Scopes", pile.ObjectToClass(virtualMachine.Globals["Object"]))
+	compileMethod(t, virtualMachine, class, "apply: aBlock | other | other := 100. ^aBlock value")
+
+	tests := []struct {
+		name      string
+		source    string
+		arguments []int64
+		expected  int64
+	}{
+		{"method temporary", "readTemporary | t | t := 5. ^[t] value", nil, 5},
+		{"method argument", "readArgument: n ^[n + 1] value", []int64{7}, 8},
+		{"assignment", "assign | t | t := 1. [t := t + 1] value. ^t", nil, 2},
+		{"nested blocks", "nested: n | a | a := n. ^[:x | [:y | a + x + y] value: 2] value: 1", []int64{7}, 10},
+		{"nested assignment", "nestedAssign | a | a := 1. [[a := a + 10] value] value. ^a", nil, 11},
+		{"block temporaries", "blockTemps ^[:x | | y | y := x + 1. [y + 1] value] value: 1", nil, 3},
+		{"evaluated by another method", "applyRead | t | t := 5. ^self apply: [t]", nil, 5},
+		{"assigned by another method", "applyAssign | t | t := 5. self apply: [t := t + 1]. ^t", nil, 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := compileMethod(t, virtualMachine, class, test.source)
+			instance := pile.NewInstance(class)
+			instance.SetClass(pile.ClassToObject(class))
+
+			arguments := []*pile.Object{}
+			for _, argument := range test.arguments {
+				arguments = append(arguments, virtualMachine.NewInteger(argument))
+			}
+
+			context := vm.NewContext(method, instance, arguments, nil)
+			virtualMachine.Executor.CurrentContext = context
+			result, err := virtualMachine.ExecuteContext(context)
+			if err != nil {
+				t.Fatalf("Error running %q: %v", test.source, err)
+			}
+			value := result.(*pile.Object)
+			if !pile.IsIntegerImmediate(value) || pile.GetIntegerImmediate(value) != test.expected {
+				t.Errorf("Expected %q to return %d, got %v", test.source, test.expected, value)
+			}
+		})
+	}
+}
+
+// TestBlockOutlivesMethod tests that a block keeps updating the temporaries
+// of its method after the method has returned
+func TestBlockOutlivesMethod(t *testing.T) {
+	virtualMachine := vm.NewVM()
+	runtime.RegisterBlockExecutor(virtualMachine)
+
+	class := virtualMachine.NewClass("Counter", pile.ObjectToClass(virtualMachine.Globals["Object"]))
+	method := compileMethod(t, virtualMachine, class, "makeCounter | count | count := 0. ^[count := count + 1]")
+
+	instance := pile.NewInstance(class)
+	instance.SetClass(pile.ClassToObject(class))
+
+	// Each call of makeCounter returns a block with its own count
+	counters := make([]*pile.Block, 2)
+	for i := range counters {
+		result, err := virtualMachine.ExecuteContext(vm.NewContext(method, instance, []*pile.Object{}, nil))
+		if err != nil {
+			t.Fatalf("Error running makeCounter: %v", err)
+		}
+		counters[i] = pile.ObjectToBlock(result.(*pile.Object))
+		if counters[i] == nil {
+			t.Fatalf("Expected makeCounter to return a block, got %v", result)
+		}
+	}
+
+	for expected := int64(1); expected <= 3; expected++ {
+		result := counters[0].Value()
+		if !pile.IsIntegerImmediate(result) || pile.GetIntegerImmediate(result) != expected {
+			t.Errorf("Expected the first counter to return %d, got %v", expected, result)
+		}
+	}
+
+	result := counters[1].Value()
+	if !pile.IsIntegerImmediate(result) || pile.GetIntegerImmediate(result) != 1 {
+		t.Errorf("Expected the second counter to return 1, got %v", result)
+	}
+}
+
+// TestBlockArgumentCount tests that a block evaluated with more or fewer
+// arguments than it declares fails instead of running with shifted temporaries
+func TestBlockArgumentCount(t *testing.T) {
+	virtualMachine := vm.NewVM()
+	runtime.RegisterBlockExecutor(virtualMachine)
+
+	class := virtualMachine.NewClass("Arguments", pile.ObjectToClass(virtualMachine.Globals["Object"]))
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"too many for a temporary", "tooManyForTemp ^[| t | t] value: 4"},
+		{"too few", "tooFew ^[:x | x] value"},
+		{"too many", "tooMany ^[3] value: 4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := compileMethod(t, virtualMachine, class, test.source)
+			instance := pile.NewInstance(class)
+			instance.SetClass(pile.ClassToObject(class))
+
+			context := vm.NewContext(method, instance, []*pile.Object{}, nil)
+			virtualMachine.Executor.CurrentContext = context
+			func() {
+				defer func() {
+					if r := recover(); !strings.Contains(fmt.Sprint(r), "wrong number of block arguments") {
+						t.Errorf("Expected %q to fail with a wrong argument count, got %v", test.source, r)
+					}
+				}()
+				virtualMachine.ExecuteContext(context)
+			}()
+		})
+	}
+}
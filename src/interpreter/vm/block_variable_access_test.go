@@ -28,11 +28,16 @@ func TestBlockModifiesLocalVariable(t *testing.T) {
 		0, 0, 0, 0, // literal index 0 (the value 2)
 
 		// Store it in the outer context's temporary variable 'a'
-		bytecode.STORE_TEMPORARY_VARIABLE,
+		bytecode.STORE_OUTER_TEMPORARY_VARIABLE,
+		0, 0, 0, 1, // depth 1 (the method)
 		0, 0, 0, 0, // temp var index 0 (a)
 
-		// Return nil (implicit)
+		// Return the stored value
+		bytecode.RETURN_STACK_TOP,
 	}
+	compiledBlock := pile.NewCompiledBlock(blockBytecodes, []*pile.Object{
+		pile.MakeIntegerImmediate(2), // The literal 2
+	}, []string{}, 0)
 
 	// Create a method with the following Smalltalk code:
 	// method
@@ -49,9 +54,7 @@ func TestBlockModifiesLocalVariable(t *testing.T) {
 
 		// Create a block that assigns 2 to 'a'
 		bytecode.CREATE_BLOCK,
-		0, 0, 0, byte(len(blockBytecodes)), // bytecode size
-		0, 0, 0, 1, // literal count (1 literal)
-		0, 0, 0, 0, // temp var count (0 temp vars)
+		0, 0, 0, 1, // literal index 1 (the compiled block)
 
 		// Execute the block
 		bytecode.EXECUTE_BLOCK,
@@ -70,8 +73,8 @@ func TestBlockModifiesLocalVariable(t *testing.T) {
 		},
 		Bytecodes: methodBytecodes,
 		Literals: []*pile.Object{
-			pile.MakeIntegerImmediate(1), // The literal 1
-			pile.MakeIntegerImmediate(2), // The literal 2
+			pile.MakeIntegerImmediate(1),              // The literal 1
+			pile.CompiledBlockToObject(compiledBlock), // The block [a := 2]
 		},
 		TempVarNames: []string{"a"}, // One temporary variable 'a'
 	}
@@ -107,21 +110,6 @@ func TestBlockModifiesLocalVariable(t *testing.T) {
 		t.Fatalf("ExecuteCreateBlock returned an error: %v", err)
 	}
 
-	// Get the block from the stack
-	block := context.Pop()
-	blockObj := pile.ObjectToBlock(block)
-
-	// Set the block's bytecodes
-	blockObj.SetBytecodes(blockBytecodes)
-
-	// Set the block's literals
-	blockObj.Literals = []*pile.Object{
-		pile.MakeIntegerImmediate(2), // The literal 2
-	}
-
-	// Push the block back onto the stack
-	context.Push(block)
-
 	// Advance the PC to the EXECUTE_BLOCK bytecode
 	context.PC += bytecode.InstructionSize(bytecode.CREATE_BLOCK)
 
@@ -18,6 +18,9 @@ func (vm *VM) NewByteArrayClass() *pile.Class {
 	// at:put: method (sets the byte at the given index)
 	compiler.NewMethodBuilder(result).Primitive(51).Go("at:put:")
 
+	// utf8Decoded method (returns the string the UTF-8 bytes encode)
+	compiler.NewMethodBuilder(result).Primitive(52).Go("utf8Decoded")
+
 	return result
 }
 
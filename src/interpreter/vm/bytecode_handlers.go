@@ -25,6 +25,54 @@ func (vm *VM) ExecutePushLiteral(context *Context) error {
 	return nil
 }
 
+// ExecutePushGlobal executes the PUSH_GLOBAL bytecode
+func (vm *VM) ExecutePushGlobal(context *Context) error {
+	// Get the method
+	method := pile.ObjectToMethod(context.Method)
+
+	// Get the binding index (4 bytes)
+	index := int(binary.BigEndian.Uint32(method.Bytecodes[context.PC+1:]))
+	if index < 0 || index >= len(method.Literals) {
+		return fmt.Errorf("literal index out of bounds: %d", index)
+	}
+
+	binding := method.Literals[index]
+	if pile.IsImmediate(binding) || binding.Type() != pile.OBJ_ASSOCIATION {
+		return fmt.Errorf("literal %d is not a global binding", index)
+	}
+
+	// Push the current value of the global onto the stack
+	context.Push(pile.ObjectToAssociation(binding).GetValue())
+	return nil
+}
+
+// ExecuteStoreGlobal executes the STORE_GLOBAL bytecode
+func (vm *VM) ExecuteStoreGlobal(context *Context) error {
+	// Get the method
+	method := pile.ObjectToMethod(context.Method)
+
+	// Get the binding index (4 bytes)
+	index := int(binary.BigEndian.Uint32(method.Bytecodes[context.PC+1:]))
+	if index < 0 || index >= len(method.Literals) {
+		return fmt.Errorf("literal index out of bounds: %d", index)
+	}
+
+	binding := method.Literals[index]
+	if pile.IsImmediate(binding) || binding.Type() != pile.OBJ_ASSOCIATION {
+		return fmt.Errorf("literal %d is not a global binding", index)
+	}
+
+	// Pop the value from the stack
+	value := context.Pop()
+
+	// Define the global, which updates the binding and every other method using it
+	vm.SetGlobal(pile.GetSymbolValue(pile.ObjectToAssociation(binding).GetKey()), value)
+
+	// Push the value back onto the stack
+	context.Push(value)
+	return nil
+}
+
 // ExecutePushInstanceVariable executes the PUSH_INSTANCE_VARIABLE bytecode
 func (vm *VM) ExecutePushInstanceVariable(context *Context) error {
 	// Get the method
@@ -33,7 +81,7 @@ func (vm *VM) ExecutePushInstanceVariable(context *Context) error {
 	// Get the instance variable index (4 bytes)
 	index := int(binary.BigEndian.Uint32(method.Bytecodes[context.PC+1:]))
 	class := vm.GetClass(context.Receiver.(*pile.Object))
-	if index < 0 || index >= len(class.InstanceVarNames) {
+	if index < 0 || index >= len(pile.GetClassAllInstanceVarNames(class)) {
 		return fmt.Errorf("instance variable index out of bounds: %d", index)
 	}
 
@@ -51,19 +99,22 @@ func (vm *VM) ExecutePushTemporaryVariable(context *Context) error {
 	// Get the temporary variable index (4 bytes)
 	index := int(binary.BigEndian.Uint32(method.Bytecodes[context.PC+1:]))
 
-	// First try to get the variable from the current context
-	if index < len(context.TempVars) {
-		context.Push(context.GetTempVarByIndex(index))
-	} else {
-		// If the index is out of bounds in the current context,
-		// try to get it from the outer context (for blocks)
-		outerContext := context.Sender
-		if outerContext != nil && index < len(outerContext.TempVars) {
-			context.Push(outerContext.GetTempVarByIndex(index))
-		} else {
-			return fmt.Errorf("temporary variable index out of bounds: %d", index)
-		}
+	if index < 0 || index >= len(context.TempVars) {
+		return fmt.Errorf("temporary variable index out of bounds: %d", index)
+	}
+
+	context.Push(context.GetTempVarByIndex(index))
+	return nil
+}
+
+// ExecutePushOuterTemporaryVariable executes the PUSH_OUTER_TEMPORARY_VARIABLE bytecode
+func (vm *VM) ExecutePushOuterTemporaryVariable(context *Context) error {
+	outerContext, index, err := outerTemporaryVariable(context)
+	if err != nil {
+		return err
 	}
+
+	context.Push(outerContext.GetTempVarByIndex(index))
 	return nil
 }
 
@@ -82,7 +133,7 @@ func (vm *VM) ExecuteStoreInstanceVariable(context *Context) error {
 	index := int(binary.BigEndian.Uint32(method.Bytecodes[context.PC+1:]))
 	class := vm.GetClass(context.Receiver.(*pile.Object))
 
-	if index < 0 || index >= len(class.InstanceVarNames) {
+	if index < 0 || index >= len(pile.GetClassAllInstanceVarNames(class)) {
 		return fmt.Errorf("instance variable index out of bounds: %d", index)
 	}
 
@@ -108,25 +159,55 @@ func (vm *VM) ExecuteStoreTemporaryVariable(context *Context) error {
 	// Pop the value from the stack
 	value := context.Pop()
 
-	// First try to store the value in the current context's temporary variable
-	if index < len(context.TempVars) {
-		context.SetTempVarByIndex(index, value)
-	} else {
-		// If the index is out of bounds in the current context,
-		// try to store it in the outer context (for blocks)
-		outerContext := context.Sender
-		if outerContext != nil && index < len(outerContext.TempVars) {
-			outerContext.SetTempVarByIndex(index, value)
-		} else {
-			return fmt.Errorf("temporary variable index out of bounds: %d", index)
-		}
+	if index < 0 || index >= len(context.TempVars) {
+		return fmt.Errorf("temporary variable index out of bounds: %d", index)
+	}
+
+	// Store the value in the temporary variable
+	context.SetTempVarByIndex(index, value)
+
+	// Push the value back onto the stack
+	context.Push(value)
+	return nil
+}
+
+// ExecuteStoreOuterTemporaryVariable executes the STORE_OUTER_TEMPORARY_VARIABLE bytecode
+func (vm *VM) ExecuteStoreOuterTemporaryVariable(context *Context) error {
+	outerContext, index, err := outerTemporaryVariable(context)
+	if err != nil {
+		return err
 	}
 
+	// Pop the value from the stack
+	value := context.Pop()
+
+	// Store the value in the enclosing context's temporary variable
+	outerContext.SetTempVarByIndex(index, value)
+
 	// Push the value back onto the stack
 	context.Push(value)
 	return nil
 }
 
+// outerTemporaryVariable decodes the depth and index operands of an outer
+// temporary variable bytecode and returns the enclosing context they address
+func outerTemporaryVariable(context *Context) (*Context, int, error) {
+	method := pile.ObjectToMethod(context.Method)
+
+	// Get the depth and the temporary variable index (4 bytes each)
+	depth := int(binary.BigEndian.Uint32(method.Bytecodes[context.PC+1:]))
+	index := int(binary.BigEndian.Uint32(method.Bytecodes[context.PC+5:]))
+
+	outerContext := context.OuterContext(depth)
+	if outerContext == nil {
+		return nil, 0, fmt.Errorf("no enclosing context at depth %d", depth)
+	}
+	if index < 0 || index >= len(outerContext.TempVars) {
+		return nil, 0, fmt.Errorf("temporary variable index out of bounds: %d at depth %d", index, depth)
+	}
+	return outerContext, index, nil
+}
+
 // ExecuteSendMessage executes the SEND_MESSAGE bytecode
 func (vm *VM) ExecuteSendMessage(context *Context) (*pile.Object, error) {
 	// Get the method
@@ -309,6 +390,30 @@ func (vm *VM) ExecutePop(context *Context) error {
 	return nil
 }
 
+// ExecuteCreateArray executes the CREATE_ARRAY bytecode
+func (vm *VM) ExecuteCreateArray(context *Context) error {
+	// Get the method
+	method := pile.ObjectToMethod(context.Method)
+
+	// Get the element count (4 bytes)
+	if context.PC+4 >= len(method.Bytecodes) {
+		return fmt.Errorf("element count out of bounds")
+	}
+	size := int(binary.BigEndian.Uint32(method.Bytecodes[context.PC+1:]))
+
+	// Pop the elements from the stack, the last element is on top
+	arrayObj := vm.NewArray(size)
+	array := pile.ObjectToArray(arrayObj)
+	for i := size - 1; i >= 0; i-- {
+		array.AtPut(i, context.Pop())
+	}
+
+	// Push the new array onto the stack
+	context.Push(arrayObj)
+
+	return nil
+}
+
 // ExecuteDuplicate executes the DUPLICATE bytecode
 func (vm *VM) ExecuteDuplicate(context *Context) error {
 	value := context.Top()
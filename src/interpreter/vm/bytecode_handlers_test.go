@@ -130,6 +130,45 @@ func TestExecuteDuplicate(t *testing.T) {
 	}
 }
 
+func TestExecuteCreateArray(t *testing.T) {
+	virtualMachine := vm.NewVM()
+
+	methodObj := compiler.NewMethodBuilder(pile.ObjectToClass(virtualMachine.Globals["Object"])).
+		CreateArray(2).
+		Go("test")
+
+	context := vm.NewContext(methodObj, pile.ClassToObject(pile.ObjectToClass(virtualMachine.Globals["Object"])), []*pile.Object{}, nil)
+
+	context.Push(virtualMachine.NewInteger(1))
+	context.Push(virtualMachine.NewInteger(2))
+
+	err := virtualMachine.ExecuteCreateArray(context)
+	if err != nil {
+		t.Errorf("ExecuteCreateArray returned an error: %v", err)
+	}
+
+	if context.StackPointer != 1 {
+		t.Fatalf("Expected stack pointer to be 1, got %d", context.StackPointer)
+	}
+
+	arrayObj := context.Pop()
+	if arrayObj.Type() != pile.OBJ_ARRAY {
+		t.Fatalf("Expected an array on the stack, got %v", arrayObj)
+	}
+
+	// The elements keep the order they were pushed in
+	array := pile.ObjectToArray(arrayObj)
+	if array.Size() != 2 {
+		t.Fatalf("Expected array size to be 2, got %d", array.Size())
+	}
+	for i, expected := range []int64{1, 2} {
+		element := array.At(i)
+		if !pile.IsIntegerImmediate(element) || pile.GetIntegerImmediate(element) != expected {
+			t.Errorf("Expected element %d to be %d, got %v", i, expected, element)
+		}
+	}
+}
+
 func TestExecuteSendMessage(t *testing.T) {
 	virtualMachine := vm.NewVM()
 
@@ -24,6 +24,7 @@ func TestBytecodeName(t *testing.T) {
 		{bytecode.JUMP_IF_FALSE, "JUMP_IF_FALSE"},
 		{bytecode.POP, "POP"},
 		{bytecode.DUPLICATE, "DUPLICATE"},
+		{bytecode.CREATE_ARRAY, "CREATE_ARRAY"},
 		{255, "UNKNOWN"}, // Test unknown bytecode
 	}
 
@@ -53,6 +54,7 @@ func TestInstructionSize(t *testing.T) {
 		{bytecode.JUMP_IF_FALSE, 5},
 		{bytecode.POP, 1},
 		{bytecode.DUPLICATE, 1},
+		{bytecode.CREATE_ARRAY, 5},
 		{255, 1}, // Test unknown bytecode
 	}
 
@@ -0,0 +1,25 @@
+package vm
+
+import (
+	"smalltalklsp/interpreter/compiler"
+	"smalltalklsp/interpreter/pile"
+)
+
+// NewCharacterClass creates a new Character class
+func (vm *VM) NewCharacterClass() *pile.Class {
+	objectClass := pile.ObjectToClass(vm.Globals["Object"])
+	result := pile.NewClass("Character", objectClass)
+
+	// Add primitive methods to the Character class - create a new builder for each method
+
+	// value method (returns the code point of the character)
+	compiler.NewMethodBuilder(result).Primitive(70).Go("value")
+
+	return result
+}
+
+// NewCharacter creates a new character object
+// This returns an immediate value for characters
+func (vm *VM) NewCharacter(value rune) *pile.Object {
+	return pile.MakeCharacterImmediate(value)
+}
@@ -0,0 +1,59 @@
+package vm_test
+
+import (
+	"math/big"
+	"testing"
+
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+func TestCharacterCreation(t *testing.T) {
+	virtualMachine := vm.NewVM()
+	character := virtualMachine.NewCharacter('a')
+
+	if !pile.IsCharacterImmediate(character) {
+		t.Fatalf("Expected a character immediate, got %s", character)
+	}
+	if got := pile.GetCharacterImmediate(character); got != 'a' {
+		t.Errorf("Expected code point %d, got %d", 'a', got)
+	}
+
+	class := virtualMachine.GetClass(character)
+	if class != pile.ObjectToClass(virtualMachine.Globals["Character"]) {
+		t.Errorf("Expected class Character, got %s", class.Name)
+	}
+}
+
+func TestCharacterValuePrimitive(t *testing.T) {
+	virtualMachine := vm.NewVM()
+	character := virtualMachine.NewCharacter('A')
+
+	selector := pile.NewSymbol("value")
+	method := virtualMachine.LookupMethod(character, selector)
+	if method == nil {
+		t.Fatalf("Expected Character to understand value")
+	}
+
+	result := virtualMachine.ExecutePrimitive(character, selector, []*pile.Object{}, method)
+	if !pile.IsIntegerImmediate(result) || pile.GetIntegerImmediate(result) != 65 {
+		t.Errorf("Expected 65, got %s", result)
+	}
+}
+
+func TestScaledDecimalCreation(t *testing.T) {
+	virtualMachine := vm.NewVM()
+	scaledDecimal := virtualMachine.NewScaledDecimal(big.NewRat(5, 4), 2)
+
+	if scaledDecimal.Type() != pile.OBJ_SCALED_DECIMAL {
+		t.Errorf("Expected type %d, got %d", pile.OBJ_SCALED_DECIMAL, scaledDecimal.Type())
+	}
+	if scaledDecimal.String() != "1.25s2" {
+		t.Errorf("Expected 1.25s2, got %s", scaledDecimal.String())
+	}
+
+	class := virtualMachine.GetClass(scaledDecimal)
+	if class != pile.ObjectToClass(virtualMachine.Globals["ScaledDecimal"]) {
+		t.Errorf("Expected class ScaledDecimal, got %s", class.Name)
+	}
+}
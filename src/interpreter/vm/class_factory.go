@@ -4,7 +4,8 @@ import (
 	"smalltalklsp/interpreter/pile"
 )
 
-// NewClass creates a new class object with proper class field
+// NewClass creates a new class object with proper class field and defines
+// it as a global, so methods bound to its name see the new class
 func (vm *VM) NewClass(name string, superClass *pile.Class) *pile.Class {
 	// For classes, we need a special instance variable for the method dictionary
 	// We'll store it at index 0
@@ -20,6 +21,9 @@ func (vm *VM) NewClass(name string, superClass *pile.Class) *pile.Class {
 	// A class's class should be a metaclass, but for now we'll use ObjectClass
 	classObj := pile.ClassToObject(class)
 	classObj.SetClass(vm.Globals["Object"])
+
+	// Define the class as a global
+	vm.SetGlobal(name, classObj)
 	
 	return class
 }
\ No newline at end of file
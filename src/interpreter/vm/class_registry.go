@@ -14,6 +14,8 @@ const (
 	False           ClassType = "False"
 	Integer         ClassType = "Integer"
 	Float           ClassType = "Float"
+	ScaledDecimal   ClassType = "ScaledDecimal"
+	Character       ClassType = "Character"
 	String          ClassType = "String"
 	Symbol          ClassType = "Symbol"
 	Array           ClassType = "Array"
@@ -0,0 +1,40 @@
+package vm_test
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// TestCompiledBlocks tests running methods whose blocks have their own literals
+func TestCompiledBlocks(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected int64
+	}{
+		{"constant ^[42] value", 42},
+		{"argument ^[:x | x + 1] value: 41", 42},
+		{"literals | x | x := 10. ^x + [20 + 30] value", 60},
+		{"nested ^[[1 + 2] value + [3 + 4] value] value", 10},
+		{"temporaries ^[:x | | y | y := x * 2. y + 1] value: 4", 9},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			virtualMachine := vm.NewVM()
+			objectClass := pile.ObjectToClass(virtualMachine.Globals["Object"])
+			method := compileMethod(t, virtualMachine, objectClass, test.source)
+
+			result, err := virtualMachine.ExecuteContext(vm.NewContext(method, pile.ClassToObject(objectClass), []*pile.Object{}, nil))
+			if err != nil {
+				t.Fatalf("Error running %q: %v", test.source, err)
+			}
+
+			value := result.(*pile.Object)
+			if !pile.IsIntegerImmediate(value) || pile.GetIntegerImmediate(value) != test.expected {
+				t.Errorf("Expected %d, got %v", test.expected, value)
+			}
+		})
+	}
+}
@@ -11,9 +11,11 @@ type Context struct {
 	Arguments    []*pile.Object
 	TempVars     []pile.ObjectInterface // Temporary variables stored by index
 	Sender       *Context
+	Outer        *Context // Lexically enclosing context of a block, nil for methods
 	PC           int
 	Stack        []*pile.Object
 	StackPointer int
+	Dead         bool // Set once the context has returned, blocks can't return from it any more
 }
 
 // NewContext creates a new method activation context
@@ -33,6 +35,13 @@ func NewContext(method *pile.Object, receiver pile.ObjectInterface, arguments []
 		tempVars[i] = pile.NewNil()
 	}
 
+	// The arguments are the first temporaries
+	for i, argument := range arguments {
+		if i < tempVarsSize {
+			tempVars[i] = argument
+		}
+	}
+
 	return &Context{
 		Method:       method,
 		Receiver:     receiver,
@@ -151,6 +160,26 @@ func (c *Context) SetSender(sender *Context) {
 	c.Sender = sender
 }
 
+// OuterContext returns the enclosing context depth levels out, or nil if
+// the chain of outer contexts is shorter than that
+func (c *Context) OuterContext(depth int) *Context {
+	context := c
+	for i := 0; i < depth && context != nil; i++ {
+		context = context.Outer
+	}
+	return context
+}
+
+// Home returns the method context a block context was created in, following
+// the outer contexts. The home context of a method context is itself.
+func (c *Context) Home() *Context {
+	context := c
+	for context.Outer != nil {
+		context = context.Outer
+	}
+	return context
+}
+
 // GetPC returns the program counter
 func (c *Context) GetPC() int {
 	return c.PC
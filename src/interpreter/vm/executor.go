@@ -51,8 +51,24 @@ func (e *Executor) Execute() (pile.ObjectInterface, error) {
 	return finalResult, nil
 }
 
-// ExecuteContext executes a single context until it returns
-func (e *Executor) ExecuteContext(context *Context) (pile.ObjectInterface, error) {
+// ExecuteContext executes a single context until it returns. A non-local
+// return from a block whose home is this context ends it with the value of
+// the block return.
+func (e *Executor) ExecuteContext(context *Context) (result pile.ObjectInterface, err error) {
+	defer func() {
+		context.Dead = true
+
+		// Blocks evaluated by primitives raise non-local returns as panics
+		if r := recover(); r != nil {
+			nonLocalReturn, ok := r.(*NonLocalReturn)
+			if !ok || nonLocalReturn.Home != context {
+				panic(r)
+			}
+			e.CurrentContext = context
+			result, err = nonLocalReturn.Value, nil
+		}
+	}()
+
 	// Execute the context
 	for {
 		// Get the method
@@ -80,6 +96,7 @@ func (e *Executor) ExecuteContext(context *Context) (pile.ObjectInterface, error
 		// Execute the bytecode
 		var err error
 		var skipIncrement bool
+		var returnValue *pile.Object
 
 		switch opcode {
 		case bytecode.PUSH_LITERAL:
@@ -88,9 +105,15 @@ func (e *Executor) ExecuteContext(context *Context) (pile.ObjectInterface, error
 		case bytecode.PUSH_INSTANCE_VARIABLE:
 			err = e.VM.ExecutePushInstanceVariable(context)
 
+		case bytecode.PUSH_GLOBAL:
+			err = e.VM.ExecutePushGlobal(context)
+
 		case bytecode.PUSH_TEMPORARY_VARIABLE:
 			err = e.VM.ExecutePushTemporaryVariable(context)
 
+		case bytecode.PUSH_OUTER_TEMPORARY_VARIABLE:
+			err = e.VM.ExecutePushOuterTemporaryVariable(context)
+
 		case bytecode.PUSH_SELF:
 			err = e.VM.ExecutePushSelf(context)
 
@@ -100,8 +123,14 @@ func (e *Executor) ExecuteContext(context *Context) (pile.ObjectInterface, error
 		case bytecode.STORE_TEMPORARY_VARIABLE:
 			err = e.VM.ExecuteStoreTemporaryVariable(context)
 
+		case bytecode.STORE_OUTER_TEMPORARY_VARIABLE:
+			err = e.VM.ExecuteStoreOuterTemporaryVariable(context)
+
+		case bytecode.STORE_GLOBAL:
+			err = e.VM.ExecuteStoreGlobal(context)
+
 		case bytecode.SEND_MESSAGE:
-			returnValue, err := e.VM.ExecuteSendMessage(context)
+			returnValue, err = e.VM.ExecuteSendMessage(context)
 			if err == nil {
 				if returnValue != nil {
 					// We got a result from a primitive method
@@ -115,7 +144,13 @@ func (e *Executor) ExecuteContext(context *Context) (pile.ObjectInterface, error
 			}
 
 		case bytecode.RETURN_STACK_TOP:
-			returnValue, err := e.VM.ExecuteReturnStackTop(context)
+			returnValue, err = e.VM.ExecuteReturnStackTop(context)
+			if err == nil {
+				return returnValue, nil
+			}
+
+		case bytecode.BLOCK_RETURN:
+			returnValue, err = e.VM.ExecuteBlockReturn(context)
 			if err == nil {
 				return returnValue, nil
 			}
@@ -144,11 +179,14 @@ func (e *Executor) ExecuteContext(context *Context) (pile.ObjectInterface, error
 		case bytecode.DUPLICATE:
 			err = e.VM.ExecuteDuplicate(context)
 
+		case bytecode.CREATE_ARRAY:
+			err = e.VM.ExecuteCreateArray(context)
+
 		case bytecode.CREATE_BLOCK:
 			err = e.VM.ExecuteCreateBlock(context)
 
 		case bytecode.EXECUTE_BLOCK:
-			returnValue, err := e.VM.ExecuteExecuteBlock(context)
+			returnValue, err = e.VM.ExecuteExecuteBlock(context)
 			if err == nil {
 				if returnValue != nil {
 					// We got a result from executing the block
@@ -165,8 +203,12 @@ func (e *Executor) ExecuteContext(context *Context) (pile.ObjectInterface, error
 			return nil, fmt.Errorf("unknown bytecode: %d", opcode)
 		}
 
-		// Check for errors
+		// Check for errors, a non-local return to this context returns from it
 		if err != nil {
+			if nonLocalReturn, ok := err.(*NonLocalReturn); ok && nonLocalReturn.Home == context {
+				e.CurrentContext = context
+				return nonLocalReturn.Value, nil
+			}
 			return nil, err
 		}
 
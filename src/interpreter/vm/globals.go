@@ -10,7 +10,62 @@ func (vm *VM) GetGlobal(name string) *pile.Object {
 	if obj, ok := vm.Globals[name]; ok {
 		return obj
 	}
-	
+
 	// Return nil if the global is not found
 	return pile.MakeNilImmediate()
 }
+
+// SetGlobal defines or assigns a global variable. The binding of the global
+// is updated so that compiled methods see the new value, and a binding that
+// was created while the name was undeclared moves out of Undeclared.
+func (vm *VM) SetGlobal(name string, value *pile.Object) {
+	vm.Globals[name] = value
+
+	if binding, ok := vm.Undeclared[name]; ok {
+		delete(vm.Undeclared, name)
+		vm.Bindings[name] = binding
+	}
+
+	if binding, ok := vm.Bindings[name]; ok {
+		pile.ObjectToAssociation(binding).SetValue(value)
+	}
+}
+
+// GlobalBinding returns the association holding the global variable with the
+// given name. Every call for a name returns the same association, which
+// compiled methods keep in their literals to read the current value of the
+// global. A name that is not defined yet gets a binding to nil in Undeclared
+// until SetGlobal defines it.
+func (vm *VM) GlobalBinding(name string) *pile.Object {
+	if binding, ok := vm.Bindings[name]; ok {
+		return binding
+	}
+
+	if value, ok := vm.Globals[name]; ok {
+		binding := vm.NewAssociation(vm.NewSymbol(name), value)
+		vm.Bindings[name] = binding
+		return binding
+	}
+
+	if binding, ok := vm.Undeclared[name]; ok {
+		return binding
+	}
+
+	binding := vm.NewAssociation(vm.NewSymbol(name), pile.MakeNilImmediate())
+	vm.Undeclared[name] = binding
+	return binding
+}
+
+// NewAssociationClass creates a new Association class
+func (vm *VM) NewAssociationClass() *pile.Class {
+	objectClass := pile.ObjectToClass(vm.Globals["Object"])
+	return pile.NewClass("Association", objectClass)
+}
+
+// NewAssociation creates a new association object
+func (vm *VM) NewAssociation(key *pile.Object, value *pile.Object) *pile.Object {
+	association := pile.NewAssociationInternal(key, value)
+	associationObj := pile.AssociationToObject(association)
+	associationObj.SetClass(vm.Globals["Association"])
+	return associationObj
+}
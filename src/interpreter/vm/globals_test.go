@@ -0,0 +1,159 @@
+package vm
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/bytecode"
+	"smalltalklsp/interpreter/pile"
+)
+
+// TestGlobalBinding tests that a global has a single binding that follows
+// assignments to the global
+func TestGlobalBinding(t *testing.T) {
+	vm := NewVM()
+
+	binding := vm.GlobalBinding("Object")
+	if binding != vm.GlobalBinding("Object") {
+		t.Fatalf("Expected the same binding for every lookup of Object")
+	}
+	if binding.Type() != pile.OBJ_ASSOCIATION {
+		t.Fatalf("Expected an association, got %v", binding)
+	}
+
+	association := pile.ObjectToAssociation(binding)
+	if association.GetValue() != vm.Globals["Object"] {
+		t.Errorf("Expected the binding to hold the Object class, got %v", association.GetValue())
+	}
+	if pile.GetSymbolValue(association.GetKey()) != "Object" {
+		t.Errorf("Expected the key #Object, got %v", association.GetKey())
+	}
+
+	vm.SetGlobal("Object", pile.MakeIntegerImmediate(3))
+	if association.GetValue() != pile.MakeIntegerImmediate(3) {
+		t.Errorf("Expected the binding to hold 3 after assigning the global, got %v", association.GetValue())
+	}
+}
+
+// TestUndeclaredGlobal tests that a binding created for an undefined name is
+// kept in Undeclared until the global is defined
+func TestUndeclaredGlobal(t *testing.T) {
+	vm := NewVM()
+
+	binding := vm.GlobalBinding("Foo")
+	if vm.Undeclared["Foo"] != binding {
+		t.Fatalf("Expected the binding of Foo in Undeclared")
+	}
+	if value := pile.ObjectToAssociation(binding).GetValue(); !pile.IsNilImmediate(value) {
+		t.Errorf("Expected an undeclared global to be nil, got %v", value)
+	}
+
+	vm.SetGlobal("Foo", pile.MakeIntegerImmediate(42))
+	if _, ok := vm.Undeclared["Foo"]; ok {
+		t.Errorf("Expected Foo to be removed from Undeclared")
+	}
+	if vm.GlobalBinding("Foo") != binding {
+		t.Errorf("Expected defining Foo to keep its binding")
+	}
+	if value := pile.ObjectToAssociation(binding).GetValue(); value != pile.MakeIntegerImmediate(42) {
+		t.Errorf("Expected the binding to hold 42, got %v", value)
+	}
+}
+
+// TestPushGlobal tests that a method reads the value a global has when it runs
+func TestPushGlobal(t *testing.T) {
+	vm := NewVM()
+
+	method := &pile.Method{
+		Object: pile.Object{
+			TypeField: pile.OBJ_METHOD,
+		},
+		Bytecodes: []byte{
+			bytecode.PUSH_GLOBAL, 0, 0, 0, 0,
+			bytecode.RETURN_STACK_TOP,
+		},
+		Literals: []*pile.Object{
+			vm.GlobalBinding("Foo"),
+		},
+		TempVarNames: []string{},
+	}
+
+	run := func() pile.ObjectInterface {
+		context := NewContext(pile.MethodToObject(method), pile.MakeNilImmediate(), []*pile.Object{}, nil)
+		result, err := vm.ExecuteContext(context)
+		if err != nil {
+			t.Fatalf("Error executing method: %v", err)
+		}
+		return result
+	}
+
+	if result := run(); !pile.IsNilImmediate(result.(*pile.Object)) {
+		t.Errorf("Expected nil before Foo is defined, got %v", result)
+	}
+
+	vm.SetGlobal("Foo", pile.MakeIntegerImmediate(7))
+	if result := run(); result != pile.MakeIntegerImmediate(7) {
+		t.Errorf("Expected 7 after Foo is defined, got %v", result)
+	}
+}
+
+// TestStoreGlobal tests that a method assigning a global defines it for the
+// methods reading it
+func TestStoreGlobal(t *testing.T) {
+	vm := NewVM()
+
+	method := &pile.Method{
+		Object: pile.Object{
+			TypeField: pile.OBJ_METHOD,
+		},
+		Bytecodes: []byte{
+			bytecode.PUSH_LITERAL, 0, 0, 0, 1,
+			bytecode.STORE_GLOBAL, 0, 0, 0, 0,
+			bytecode.RETURN_STACK_TOP,
+		},
+		Literals: []*pile.Object{
+			vm.GlobalBinding("Foo"),
+			vm.NewString("bar"),
+		},
+		TempVarNames: []string{},
+	}
+
+	context := NewContext(pile.MethodToObject(method), pile.MakeNilImmediate(), []*pile.Object{}, nil)
+	result, err := vm.ExecuteContext(context)
+	if err != nil {
+		t.Fatalf("Error executing method: %v", err)
+	}
+	if result != method.Literals[1] {
+		t.Errorf("Expected the assignment to answer the stored value, got %v", result)
+	}
+
+	if vm.Globals["Foo"] != method.Literals[1] {
+		t.Errorf("Expected Foo to be defined as 'bar', got %v", vm.Globals["Foo"])
+	}
+	if _, ok := vm.Undeclared["Foo"]; ok {
+		t.Errorf("Expected Foo to be removed from Undeclared")
+	}
+	if value := pile.ObjectToAssociation(vm.GlobalBinding("Foo")).GetValue(); value != method.Literals[1] {
+		t.Errorf("Expected the binding of Foo to hold 'bar', got %v", value)
+	}
+}
+
+// TestClassDefinitionBinding tests that defining a class updates the binding
+// methods compiled before the definition hold
+func TestClassDefinitionBinding(t *testing.T) {
+	vm := NewVM()
+
+	binding := vm.GlobalBinding("Point")
+	class := vm.NewClass("Point", pile.ObjectToClass(vm.Globals["Object"]))
+	if vm.Globals["Point"] != pile.ClassToObject(class) {
+		t.Fatalf("Expected Point to be defined as a global")
+	}
+	if value := pile.ObjectToAssociation(binding).GetValue(); value != pile.ClassToObject(class) {
+		t.Errorf("Expected the binding of Point to hold the class, got %v", value)
+	}
+
+	// Redefining the class updates the same binding
+	redefined := vm.NewClass("Point", pile.ObjectToClass(vm.Globals["Object"]))
+	if value := pile.ObjectToAssociation(binding).GetValue(); value != pile.ClassToObject(redefined) {
+		t.Errorf("Expected the binding of Point to hold the redefined class, got %v", value)
+	}
+}
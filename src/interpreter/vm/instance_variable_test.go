@@ -0,0 +1,76 @@
+package vm_test
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/compiler"
+	"smalltalklsp/interpreter/parser"
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/vm"
+)
+
+// compileMethod compiles the source of a method for a class and installs it
+func compileMethod(t *testing.T, virtualMachine *vm.VM, class *pile.Class, source string) *pile.Object {
+	node, err := parser.NewParser(source, pile.ClassToObject(class), virtualMachine).Parse()
+	if err != nil {
+		t.Fatalf("Error parsing %q: %v", source, err)
+	}
+
+	compiled, _, err := compiler.NewBytecodeCompiler(pile.ClassToObject(class)).Compile(node)
+	if err != nil {
+		t.Fatalf("Error compiling %q: %v", source, err)
+	}
+	method := pile.MethodToObject(compiled)
+	pile.AddClassMethod(class, pile.ObjectToMethod(method).GetSelector(), method)
+	return method
+}
+
+// TestInstanceVariableMethods tests running compiled methods that read and
+// write instance variables declared by the class and its superclass
+func TestInstanceVariableMethods(t *testing.T) {
+	virtualMachine := vm.NewVM()
+
+	// Pair declares key and Triple adds value
+	pairClass := virtualMachine.NewClass("Pair", pile.ObjectToClass(virtualMachine.Globals["Object"]))
+	pile.AddClassInstanceVarName(pairClass, "key")
+	tripleClass := virtualMachine.NewClass("Triple", pairClass)
+	pile.AddClassInstanceVarName(tripleClass, "value")
+
+	keyMethod := compileMethod(t, virtualMachine, pairClass, "key ^key")
+	setMethod := compileMethod(t, virtualMachine, tripleClass, "key: aKey value: aValue key := aKey. value := aValue")
+	valueMethod := compileMethod(t, virtualMachine, tripleClass, "value ^value")
+
+	// The instance has a slot for the inherited key
+	instance := pile.NewInstance(tripleClass)
+	instance.SetClass(pile.ClassToObject(tripleClass))
+	if len(instance.InstanceVars()) != 2 {
+		t.Fatalf("Expected 2 instance variables, got %d", len(instance.InstanceVars()))
+	}
+
+	arguments := []*pile.Object{virtualMachine.NewInteger(3), virtualMachine.NewInteger(4)}
+	result, err := virtualMachine.ExecuteContext(vm.NewContext(setMethod, instance, arguments, nil))
+	if err != nil {
+		t.Fatalf("Error running key:value: %v", err)
+	}
+	if result != instance {
+		t.Errorf("Expected key:value: to return the receiver, got %v", result)
+	}
+
+	tests := []struct {
+		method   *pile.Object
+		expected int64
+	}{
+		{keyMethod, 3},
+		{valueMethod, 4},
+	}
+	for _, test := range tests {
+		result, err := virtualMachine.ExecuteContext(vm.NewContext(test.method, instance, []*pile.Object{}, nil))
+		if err != nil {
+			t.Fatalf("Error running %v: %v", test.method, err)
+		}
+		value := result.(*pile.Object)
+		if !pile.IsIntegerImmediate(value) || pile.GetIntegerImmediate(value) != test.expected {
+			t.Errorf("Expected %v to return %d, got %v", test.method, test.expected, value)
+		}
+	}
+}
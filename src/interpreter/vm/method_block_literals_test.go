@@ -14,6 +14,20 @@ func TestSimpleBlockLiteral(t *testing.T) {
 	vm := NewVM()
 	runtime.RegisterBlockExecutor(vm)
 
+	// The compiled block [5] with its own literal frame
+	compiledBlock := pile.NewCompiledBlock(
+		[]byte{
+			bytecode.PUSH_LITERAL,
+			0, 0, 0, 0, // literal index 0 (the value 5)
+			bytecode.RETURN_STACK_TOP,
+		},
+		[]*pile.Object{
+			pile.MakeIntegerImmediate(5), // The literal 5
+		},
+		[]string{},
+		0,
+	)
+
 	// Create a method that will return a block
 	method := &pile.Method{
 		Object: pile.Object{
@@ -22,15 +36,13 @@ func TestSimpleBlockLiteral(t *testing.T) {
 		Bytecodes: []byte{
 			// Create a block and push it onto the stack
 			bytecode.CREATE_BLOCK,
-			0, 0, 0, 6, // bytecode size (PUSH_LITERAL + index + RETURN_STACK_TOP)
-			0, 0, 0, 1, // literal count (just the 5)
-			0, 0, 0, 0, // temp var count (none)
+			0, 0, 0, 0, // literal index 0 (the compiled block)
 
 			// Return the block
 			bytecode.RETURN_STACK_TOP,
 		},
 		Literals: []*pile.Object{
-			pile.MakeIntegerImmediate(5), // The literal 5
+			pile.CompiledBlockToObject(compiledBlock),
 		},
 		TempVarNames: []string{},
 	}
@@ -56,20 +68,6 @@ func TestSimpleBlockLiteral(t *testing.T) {
 
 	// Execute the block
 	block := pile.ObjectToBlock(blockObj.(*pile.Object))
-
-	// Set the block's bytecodes (this would normally be done by the VM)
-	blockBytecodes := []byte{
-		bytecode.PUSH_LITERAL,
-		0, 0, 0, 0, // literal index 0 (the value 5)
-		bytecode.RETURN_STACK_TOP,
-	}
-	block.SetBytecodes(blockBytecodes)
-
-	// Set the block's literals
-	block.Literals = []*pile.Object{
-		pile.MakeIntegerImmediate(5), // The literal 5
-	}
-
 	result := block.Value()
 
 	// Verify the result is 5
@@ -103,6 +101,27 @@ func TestBlockWithMethodVariables(t *testing.T) {
 	}
 	pile.AddClassMethod(integerClass, pile.NewSymbol("+"), pile.MethodToObject(addMethod))
 
+	// The compiled block [temp + 3], reading temp from the method
+	compiledBlock := pile.NewCompiledBlock(
+		[]byte{
+			bytecode.PUSH_OUTER_TEMPORARY_VARIABLE,
+			0, 0, 0, 1, // depth 1 (the method)
+			0, 0, 0, 1, // temp var index 1 (temp)
+			bytecode.PUSH_LITERAL,
+			0, 0, 0, 0, // literal index 0 (the value 3)
+			bytecode.SEND_MESSAGE,
+			0, 0, 0, 1, // selector index 1 (the + selector)
+			0, 0, 0, 1, // arg count 1
+			bytecode.RETURN_STACK_TOP,
+		},
+		[]*pile.Object{
+			pile.MakeIntegerImmediate(3), // The literal 3
+			pile.NewSymbol("+"),          // The + selector
+		},
+		[]string{},
+		0,
+	)
+
 	// Create a method that will store a value in a temporary variable and then return a block that accesses it
 	method := &pile.Method{
 		Object: pile.Object{
@@ -111,7 +130,7 @@ func TestBlockWithMethodVariables(t *testing.T) {
 		Bytecodes: []byte{
 			// Push 7 onto the stack as the temp value
 			bytecode.PUSH_LITERAL,
-			0, 0, 0, 2, // literal index 2 (the value 7)
+			0, 0, 0, 0, // literal index 0 (the value 7)
 
 			// Store it in temp
 			bytecode.STORE_TEMPORARY_VARIABLE,
@@ -120,18 +139,14 @@ func TestBlockWithMethodVariables(t *testing.T) {
 
 			// Create a block that accesses temp
 			bytecode.CREATE_BLOCK,
-			0, 0, 0, 15, // bytecode size
-			0, 0, 0, 2, // literal count (3 and +)
-			0, 0, 0, 0, // temp var count (none)
+			0, 0, 0, 1, // literal index 1 (the compiled block)
 
 			// Return the block
 			bytecode.RETURN_STACK_TOP,
 		},
 		Literals: []*pile.Object{
-			pile.MakeIntegerImmediate(2), // The literal 2
-			pile.NewSymbol("+"),       // The + selector
 			pile.MakeIntegerImmediate(7), // The literal 7 (for temp)
-			pile.MakeIntegerImmediate(3), // The literal 3
+			pile.CompiledBlockToObject(compiledBlock),
 		},
 		TempVarNames: []string{"arg", "temp"},
 	}
@@ -157,29 +172,6 @@ func TestBlockWithMethodVariables(t *testing.T) {
 
 	// Execute the block
 	block := pile.ObjectToBlock(blockObj.(*pile.Object))
-
-	// Set the block's bytecodes (this would normally be done by the VM)
-	blockBytecodes := []byte{
-		bytecode.PUSH_TEMPORARY_VARIABLE,
-		0, 0, 0, 1, // temp var index 1 (temp)
-		bytecode.PUSH_LITERAL,
-		0, 0, 0, 0, // literal index 0 (the value 3)
-		bytecode.SEND_MESSAGE,
-		0, 0, 0, 1, // selector index 1 (the + selector)
-		0, 0, 0, 1, // arg count 1
-		bytecode.RETURN_STACK_TOP,
-	}
-	block.SetBytecodes(blockBytecodes)
-
-	// Set the block's literals
-	block.Literals = []*pile.Object{
-		pile.MakeIntegerImmediate(3), // The literal 3
-		pile.NewSymbol("+"),       // The + selector
-	}
-
-	// Set the block's outer context to the method context
-	block.SetOuterContext(context)
-
 	result := block.Value()
 
 	// Verify the result is 7 + 3 = 10
@@ -199,6 +191,38 @@ func TestBlockWithNestedBlocks(t *testing.T) {
 	vm := NewVM()
 	runtime.RegisterBlockExecutor(vm)
 
+	// The inner block [42]
+	innerBlock := pile.NewCompiledBlock(
+		[]byte{
+			bytecode.PUSH_LITERAL,
+			0, 0, 0, 0, // literal index 0 (the value 42)
+			bytecode.RETURN_STACK_TOP,
+		},
+		[]*pile.Object{
+			pile.MakeIntegerImmediate(42), // The literal 42
+		},
+		[]string{},
+		0,
+	)
+
+	// The outer block [[42] value], the inner block is one of its literals
+	outerBlock := pile.NewCompiledBlock(
+		[]byte{
+			bytecode.CREATE_BLOCK,
+			0, 0, 0, 0, // literal index 0 (the inner block)
+			bytecode.SEND_MESSAGE,
+			0, 0, 0, 1, // selector index 1 (value)
+			0, 0, 0, 0, // arg count 0
+			bytecode.RETURN_STACK_TOP,
+		},
+		[]*pile.Object{
+			pile.CompiledBlockToObject(innerBlock),
+			pile.NewSymbol("value"), // The value selector
+		},
+		[]string{},
+		0,
+	)
+
 	// Create a method that will return a block that creates and executes another block
 	method := &pile.Method{
 		Object: pile.Object{
@@ -207,16 +231,13 @@ func TestBlockWithNestedBlocks(t *testing.T) {
 		Bytecodes: []byte{
 			// Create a block and push it onto the stack
 			bytecode.CREATE_BLOCK,
-			0, 0, 0, 20, // bytecode size
-			0, 0, 0, 2, // literal count (42 and value)
-			0, 0, 0, 0, // temp var count (none)
+			0, 0, 0, 0, // literal index 0 (the outer block)
 
 			// Return the block
 			bytecode.RETURN_STACK_TOP,
 		},
 		Literals: []*pile.Object{
-			pile.MakeIntegerImmediate(42), // The literal 42
-			pile.NewSymbol("value"),    // The value selector
+			pile.CompiledBlockToObject(outerBlock),
 		},
 		TempVarNames: []string{},
 	}
@@ -240,39 +261,8 @@ func TestBlockWithNestedBlocks(t *testing.T) {
 		t.Fatalf("Expected a block, got %v", outerBlockObj)
 	}
 
-	// Execute the outer block, which should create and execute the inner block
-	outerBlock := pile.ObjectToBlock(outerBlockObj.(*pile.Object))
-
-	// Set the outer block's bytecodes (this would normally be done by the VM)
-	outerBlockBytecodes := []byte{
-		bytecode.CREATE_BLOCK,
-		0, 0, 0, 6, // bytecode size (PUSH_LITERAL + index + RETURN_STACK_TOP)
-		0, 0, 0, 1, // literal count (just the 42)
-		0, 0, 0, 0, // temp var count (none)
-		bytecode.SEND_MESSAGE,
-		0, 0, 0, 1, // selector index 1 (value)
-		0, 0, 0, 0, // arg count 0
-		bytecode.RETURN_STACK_TOP,
-	}
-	outerBlock.SetBytecodes(outerBlockBytecodes)
-
-	// Set the outer block's literals
-	outerBlock.Literals = []*pile.Object{
-		pile.MakeIntegerImmediate(42), // The literal 42
-		pile.NewSymbol("value"),    // The value selector
-	}
-
-	// Set the outer block's outer context to the method context
-	outerBlock.SetOuterContext(context)
-
-	// When the outer block is executed, it will create an inner block with these bytecodes
-	// and then send the value message to it
-
-	// Instead of trying to execute the block, which would require a more complex setup,
-	// let's just verify that the block has the correct structure
-
-	// Create a mock result
-	result := pile.MakeIntegerImmediate(42)
+	// Execute the outer block, which creates and executes the inner block
+	result := pile.ObjectToBlock(outerBlockObj.(*pile.Object)).Value()
 
 	// Verify the result is 42 (from the inner block)
 	if !pile.IsIntegerImmediate(result) {
@@ -291,20 +281,43 @@ func TestMethodBlockWithNonLocalReturn(t *testing.T) {
 	vm := NewVM()
 	runtime.RegisterBlockExecutor(vm)
 
-	// For simplicity, we'll just create a method that returns 99 directly
-	// In a real implementation, we would test non-local returns from blocks
+	// The compiled block [^99]
+	compiledBlock := pile.NewCompiledBlock(
+		[]byte{
+			bytecode.PUSH_LITERAL,
+			0, 0, 0, 0, // literal index 0 (the value 99)
+			bytecode.BLOCK_RETURN,
+		},
+		[]*pile.Object{
+			pile.MakeIntegerImmediate(99), // The literal 99
+		},
+		[]string{},
+		0,
+	)
+	compiledBlock.NonLocalReturn = true
+
+	// Create a method evaluating the block and then returning 1, which the
+	// non-local return skips
 	method := &pile.Method{
 		Object: pile.Object{
 			TypeField: pile.OBJ_METHOD,
 		},
 		Bytecodes: []byte{
-			// Push 99 and return
+			// Create and evaluate the block
+			bytecode.CREATE_BLOCK,
+			0, 0, 0, 1, // literal index 1 (the compiled block)
+			bytecode.EXECUTE_BLOCK,
+			0, 0, 0, 0, // arg count 0
+			bytecode.POP,
+
+			// Push 1 and return
 			bytecode.PUSH_LITERAL,
-			0, 0, 0, 0, // literal index 0 (the value 99)
+			0, 0, 0, 0, // literal index 0 (the value 1)
 			bytecode.RETURN_STACK_TOP,
 		},
 		Literals: []*pile.Object{
-			pile.MakeIntegerImmediate(99), // The literal 99
+			pile.MakeIntegerImmediate(1), // The literal 1
+			pile.CompiledBlockToObject(compiledBlock),
 		},
 		TempVarNames: []string{},
 	}
@@ -332,170 +345,92 @@ func TestMethodBlockWithNonLocalReturn(t *testing.T) {
 	if value != 99 {
 		t.Errorf("Expected 99 (from non-local return), got %d", value)
 	}
+	if vm.Executor.CurrentContext != context {
+		t.Errorf("Expected the method context to be current after the return, got %v", vm.Executor.CurrentContext)
+	}
 }
 
-// TestMethodReturningDifferentBlocks tests a method that returns different blocks based on a condition
-func TestMethodReturningDifferentBlocks(t *testing.T) {
-	// Create a VM and register it as a block executor
-	vm := NewVM()
-	runtime.RegisterBlockExecutor(vm)
-
-	// We don't need to add primitive methods for this simplified test
-	// For simplicity, we'll create two separate methods that return different values
-	// In a real implementation, we would test conditional block creation
-
-	// Method that returns a block that returns 10
-	methodTrue := &pile.Method{
-		Object: pile.Object{
-			TypeField: pile.OBJ_METHOD,
-		},
-		Bytecodes: []byte{
-			// Create a block and push it onto the stack
-			bytecode.CREATE_BLOCK,
-			0, 0, 0, 6, // bytecode size (PUSH_LITERAL + index + RETURN_STACK_TOP)
-			0, 0, 0, 1, // literal count (just the 10)
-			0, 0, 0, 0, // temp var count (none)
-
-			// Return the block
+// blockReturning returns a method answering a block that answers the value
+func blockReturning(value int64) *pile.Method {
+	compiledBlock := pile.NewCompiledBlock(
+		[]byte{
+			bytecode.PUSH_LITERAL,
+			0, 0, 0, 0, // literal index 0 (the value)
 			bytecode.RETURN_STACK_TOP,
 		},
-		Literals: []*pile.Object{
-			pile.MakeIntegerImmediate(10), // The literal 10
+		[]*pile.Object{
+			pile.MakeIntegerImmediate(value),
 		},
-		TempVarNames: []string{},
-	}
+		[]string{},
+		0,
+	)
 
-	// Method that returns a block that returns 20
-	methodFalse := &pile.Method{
+	return &pile.Method{
 		Object: pile.Object{
 			TypeField: pile.OBJ_METHOD,
 		},
 		Bytecodes: []byte{
 			// Create a block and push it onto the stack
 			bytecode.CREATE_BLOCK,
-			0, 0, 0, 6, // bytecode size (PUSH_LITERAL + index + RETURN_STACK_TOP)
-			0, 0, 0, 1, // literal count (just the 20)
-			0, 0, 0, 0, // temp var count (none)
+			0, 0, 0, 0, // literal index 0 (the compiled block)
 
 			// Return the block
 			bytecode.RETURN_STACK_TOP,
 		},
 		Literals: []*pile.Object{
-			pile.MakeIntegerImmediate(20), // The literal 20
+			pile.CompiledBlockToObject(compiledBlock),
 		},
 		TempVarNames: []string{},
 	}
+}
 
-	// Skip this test for now
-	t.Skip("Skipping TestMethodReturningDifferentBlocks due to memory issues")
-
-	// Test with the "true" method
-	t.Run("Condition True", func(t *testing.T) {
-		// Create a context for the method
-		context := NewContext(
-			pile.MethodToObject(methodTrue),
-			pile.MakeNilImmediate(),
-			[]*pile.Object{}, // No arguments needed
-			nil,
-		)
-
-		// Execute the method to get the block
-		blockObj, err := vm.ExecuteContext(context)
-		if err != nil {
-			t.Fatalf("Error executing method: %v", err)
-		}
-
-		// Verify that we got a valid object
-		if blockObj == nil {
-			t.Fatalf("Expected a block, got nil")
-		}
-
-		// Verify that it's a block
-		if blockObj.Type() != pile.OBJ_BLOCK {
-			t.Fatalf("Expected a block, got %v", blockObj)
-		}
-
-		// Execute the block
-		block := pile.ObjectToBlock(blockObj.(*pile.Object))
-
-		// Set the block's bytecodes (this would normally be done by the VM)
-		blockBytecodes := []byte{
-			bytecode.PUSH_LITERAL,
-			0, 0, 0, 0, // literal index 0 (the value 10)
-			bytecode.RETURN_STACK_TOP,
-		}
-		block.SetBytecodes(blockBytecodes)
-
-		// Set the block's literals
-		block.Literals = []*pile.Object{
-			pile.MakeIntegerImmediate(10), // The literal 10
-		}
-
-		result := block.Value()
-
-		// Verify the result is 10
-		if !pile.IsIntegerImmediate(result) {
-			t.Fatalf("Expected an integer, got %v", result)
-		}
-
-		value := pile.GetIntegerImmediate(result)
-		if value != 10 {
-			t.Errorf("Expected 10, got %d", value)
-		}
-	})
-
-	// Test with the "false" method
-	t.Run("Condition False", func(t *testing.T) {
-		// Create a context for the method
-		context := NewContext(
-			pile.MethodToObject(methodFalse),
-			pile.MakeNilImmediate(),
-			[]*pile.Object{}, // No arguments needed
-			nil,
-		)
-
-		// Execute the method to get the block
-		blockObj, err := vm.ExecuteContext(context)
-		if err != nil {
-			t.Fatalf("Error executing method: %v", err)
-		}
-
-		// Verify that we got a valid object
-		if blockObj == nil {
-			t.Fatalf("Expected a block, got nil")
-		}
-
-		// Verify that it's a block
-		if blockObj.Type() != pile.OBJ_BLOCK {
-			t.Fatalf("Expected a block, got %v", blockObj)
-		}
-
-		// Execute the block
-		block := pile.ObjectToBlock(blockObj.(*pile.Object))
-
-		// Set the block's bytecodes (this would normally be done by the VM)
-		blockBytecodes := []byte{
-			bytecode.PUSH_LITERAL,
-			0, 0, 0, 0, // literal index 0 (the value 20)
-			bytecode.RETURN_STACK_TOP,
-		}
-		block.SetBytecodes(blockBytecodes)
-
-		// Set the block's literals
-		block.Literals = []*pile.Object{
-			pile.MakeIntegerImmediate(20), // The literal 20
-		}
-
-		result := block.Value()
-
-		// Verify the result is 20
-		if !pile.IsIntegerImmediate(result) {
-			t.Fatalf("Expected an integer, got %v", result)
-		}
-
-		value := pile.GetIntegerImmediate(result)
-		if value != 20 {
-			t.Errorf("Expected 20, got %d", value)
-		}
-	})
-}
\ No newline at end of file
+// TestMethodReturningDifferentBlocks tests methods that return blocks with different literals
+func TestMethodReturningDifferentBlocks(t *testing.T) {
+	// Create a VM and register it as a block executor
+	vm := NewVM()
+	runtime.RegisterBlockExecutor(vm)
+
+	tests := []struct {
+		name     string
+		method   *pile.Method
+		expected int64
+	}{
+		{"Condition True", blockReturning(10), 10},
+		{"Condition False", blockReturning(20), 20},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Create a context for the method
+			context := NewContext(
+				pile.MethodToObject(test.method),
+				pile.MakeNilImmediate(),
+				[]*pile.Object{}, // No arguments needed
+				nil,
+			)
+
+			// Execute the method to get the block
+			blockObj, err := vm.ExecuteContext(context)
+			if err != nil {
+				t.Fatalf("Error executing method: %v", err)
+			}
+
+			// Verify that it's a block
+			if blockObj == nil || blockObj.Type() != pile.OBJ_BLOCK {
+				t.Fatalf("Expected a block, got %v", blockObj)
+			}
+
+			// Execute the block
+			result := pile.ObjectToBlock(blockObj.(*pile.Object)).Value()
+
+			if !pile.IsIntegerImmediate(result) {
+				t.Fatalf("Expected an integer, got %v", result)
+			}
+
+			value := pile.GetIntegerImmediate(result)
+			if value != test.expected {
+				t.Errorf("Expected %d, got %d", test.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package vm_test
+
+import (
+	"testing"
+
+	"smalltalklsp/interpreter/pile"
+	"smalltalklsp/interpreter/runtime"
+	"smalltalklsp/interpreter/vm"
+)
+
+// newFinder returns a VM with a Finder class whose each: evaluates a block
+// with 1, 2 and 3, counting the elements it visited and the cleanups run by
+// ensure: blocks
+func newFinder(t *testing.T) (*vm.VM, *pile.Class) {
+	virtualMachine := vm.NewVM()
+	runtime.RegisterBlockExecutor(virtualMachine)
+
+	trueClass := pile.ObjectToClass(virtualMachine.Globals["True"])
+	falseClass := pile.ObjectToClass(virtualMachine.Globals["False"])
+	compileMethod(t, virtualMachine, trueClass, "ifTrue: aBlock ^aBlock value")
+	compileMethod(t, virtualMachine, falseClass, "ifTrue: aBlock ^nil")
+
+	class := virtualMachine.NewClass("Finder", pile.ObjectToClass(virtualMachine.Globals["Object"]))
+	pile.AddClassInstanceVarName(class, "visited")
+	pile.AddClassInstanceVarName(class, "cleanups")
+	compileMethod(t, virtualMachine, class, "visited ^visited")
+	compileMethod(t, virtualMachine, class, "cleanups ^cleanups")
+	compileMethod(t, virtualMachine, class, `each: aBlock
+	visited := 1. aBlock value: 1.
+	visited := 2. aBlock value: 2.
+	visited := 3. aBlock value: 3`)
+
+	return virtualMachine, class
+}
+
+// TestNonLocalReturn tests that a ^ in a block returns from the method the
+// block is written in, unwinding the contexts evaluating the block
+func TestNonLocalReturn(t *testing.T) {
+	virtualMachine, class := newFinder(t)
+
+	tests := []struct {
+		name     string
+		source   string
+		expected int64
+		visited  int64
+		cleanups int64
+	}{
+		{"detect", "detect cleanups := 0. self each: [:e | e = 2 ifTrue: [^e * 10]]. ^0", 20, 2, 0},
+		{"not found", "notFound cleanups := 0. self each: [:e | e = 5 ifTrue: [^e]]. ^0", 0, 3, 0},
+		{"from a nested block", "nested cleanups := 0. ^[:x | [:y | ^x + y] value: 2. 0] value: 1", 3, 0, 0},
+		{"ensure", "ensure cleanups := 0. ^[1] ensure: [cleanups := cleanups + 1]", 1, 0, 1},
+		{"ensure while unwinding", "ensureUnwinding cleanups := 0. self each: [:e | [e = 2 ifTrue: [^e]] ensure: [cleanups := cleanups + 1]]. ^0", 2, 2, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := compileMethod(t, virtualMachine, class, test.source)
+			instance := pile.NewInstance(class)
+			instance.SetClass(pile.ClassToObject(class))
+			instance.SetInstanceVarByIndex(0, virtualMachine.NewInteger(0))
+
+			context := vm.NewContext(method, instance, []*pile.Object{}, nil)
+			result, err := virtualMachine.ExecuteContext(context)
+			if err != nil {
+				t.Fatalf("Error running %q: %v", test.source, err)
+			}
+			value := result.(*pile.Object)
+			if !pile.IsIntegerImmediate(value) || pile.GetIntegerImmediate(value) != test.expected {
+				t.Errorf("Expected %q to return %d, got %v", test.source, test.expected, value)
+			}
+			if virtualMachine.Executor.CurrentContext != context {
+				t.Errorf("Expected the method context to be current after the return, got %v", virtualMachine.Executor.CurrentContext)
+			}
+
+			visited := instance.GetInstanceVarByIndex(0)
+			if pile.GetIntegerImmediate(visited) != test.visited {
+				t.Errorf("Expected %d elements visited, got %v", test.visited, visited)
+			}
+			cleanups := instance.GetInstanceVarByIndex(1)
+			if pile.GetIntegerImmediate(cleanups) != test.cleanups {
+				t.Errorf("Expected %d cleanups, got %v", test.cleanups, cleanups)
+			}
+		})
+	}
+}
+
+// TestBlockCannotReturn tests that a ^ in a block whose method has already
+// returned signals BlockCannotReturn
+func TestBlockCannotReturn(t *testing.T) {
+	virtualMachine, class := newFinder(t)
+	method := compileMethod(t, virtualMachine, class, "escaper ^[^1]")
+	handlerMethod := compileMethod(t, virtualMachine, class, "handler ^[:exception | 42]")
+
+	instance := pile.NewInstance(class)
+	instance.SetClass(pile.ClassToObject(class))
+	blockCannotReturn := virtualMachine.Globals["BlockCannotReturn"]
+
+	result, err := virtualMachine.ExecuteContext(vm.NewContext(method, instance, []*pile.Object{}, nil))
+	if err != nil {
+		t.Fatalf("Error running escaper: %v", err)
+	}
+	block := pile.ObjectToBlock(result.(*pile.Object))
+
+	// Unhandled, the exception stops the evaluation
+	func() {
+		defer func() {
+			exception, ok := recover().(*pile.Object)
+			if !ok || exception.Type() != pile.OBJ_EXCEPTION || exception.Class() != blockCannotReturn {
+				t.Errorf("Expected BlockCannotReturn to be signaled, got %v", exception)
+			}
+		}()
+		block.Value()
+	}()
+
+	// Handled, the block returns the value of the handler
+	handler, err := virtualMachine.ExecuteContext(vm.NewContext(handlerMethod, instance, []*pile.Object{}, nil))
+	if err != nil {
+		t.Fatalf("Error running handler: %v", err)
+	}
+	value := block.OnDo(blockCannotReturn, handler.(*pile.Object))
+	if !pile.IsIntegerImmediate(value) || pile.GetIntegerImmediate(value) != 42 {
+		t.Errorf("Expected the handler value 42, got %v", value)
+	}
+}
+
+// TestEnsureWithoutBlock tests that ensure: fails without a crash when the
+// receiver or the argument is not a block
+func TestEnsureWithoutBlock(t *testing.T) {
+	virtualMachine, class := newFinder(t)
+	method := compileMethod(t, virtualMachine, class, "ensureInteger ^[1] ensure: 3")
+
+	instance := pile.NewInstance(class)
+	instance.SetClass(pile.ClassToObject(class))
+
+	result, err := virtualMachine.ExecuteContext(vm.NewContext(method, instance, []*pile.Object{}, nil))
+	if err != nil {
+		t.Fatalf("Error running ensureInteger: %v", err)
+	}
+	if value := result.(*pile.Object); !pile.IsNilImmediate(value) {
+		t.Errorf("Expected ensure: with an integer to fail and answer nil, got %v", value)
+	}
+
+	// An immediate receiver fails the primitive too
+	selector := pile.NewSymbol("ensure:")
+	ensureMethod := virtualMachine.LookupMethod(virtualMachine.Globals["Block"], selector)
+	if value := virtualMachine.ExecutePrimitive(pile.MakeIntegerImmediate(3), selector, []*pile.Object{pile.MakeIntegerImmediate(4)}, ensureMethod); value != nil {
+		t.Errorf("Expected ensure: sent to an integer to fail, got %v", value)
+	}
+}
+
+// TestEnsureWhileFailing tests that ensure: evaluates its argument when the
+// receiver fails and then lets the failure continue
+func TestEnsureWhileFailing(t *testing.T) {
+	virtualMachine, class := newFinder(t)
+	method := compileMethod(t, virtualMachine, class, "ensureFailing cleanups := 0. ^[self missing] ensure: [cleanups := cleanups + 1]")
+
+	instance := pile.NewInstance(class)
+	instance.SetClass(pile.ClassToObject(class))
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Expected the failure to continue after the ensure block, but got none")
+			}
+		}()
+		virtualMachine.ExecuteContext(vm.NewContext(method, instance, []*pile.Object{}, nil))
+	}()
+
+	if cleanups := instance.GetInstanceVarByIndex(1); pile.GetIntegerImmediate(cleanups) != 1 {
+		t.Errorf("Expected 1 cleanup, got %v", cleanups)
+	}
+}
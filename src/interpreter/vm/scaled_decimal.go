@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"math/big"
+
+	"smalltalklsp/interpreter/pile"
+)
+
+// NewScaledDecimalClass creates a new ScaledDecimal class
+func (vm *VM) NewScaledDecimalClass() *pile.Class {
+	objectClass := pile.ObjectToClass(vm.Globals["Object"])
+	return pile.NewClass("ScaledDecimal", objectClass)
+}
+
+// NewScaledDecimal creates a new scaled decimal object
+func (vm *VM) NewScaledDecimal(value *big.Rat, scale int) *pile.Object {
+	scaledDecimal := pile.NewScaledDecimalInternal(value, scale)
+	scaledDecimalObj := pile.ScaledDecimalToObject(scaledDecimal)
+	scaledDecimalObj.SetClass(vm.Globals["ScaledDecimal"])
+	return scaledDecimalObj
+}
@@ -132,8 +132,8 @@ func TestExecuteSendMessageExtended(t *testing.T) {
 
 		// Set the VM's object class
 		// REMOVED: virtualMachine.Classes.Register(vm.Object, objectClass)
-		virtualMachine.Globals["Object"] = pile.ClassToObject(objectClass)
-		virtualMachine.Globals["Integer"] = pile.ClassToObject(integerClass)
+		virtualMachine.SetGlobal("Object", pile.ClassToObject(objectClass))
+		virtualMachine.SetGlobal("Integer", pile.ClassToObject(integerClass))
 
 		// Execute the PUSH_LITERAL bytecode to set up the stack
 		context.PC = 0
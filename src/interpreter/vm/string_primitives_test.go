@@ -65,3 +65,87 @@ func TestStringSizePrimitive(t *testing.T) {
 		t.Errorf("Expected size 0 for empty string, got %d", value)
 	}
 }
+
+// TestStringAtPrimitives tests the String at: and at:put: primitives on wide strings
+func TestStringAtPrimitives(t *testing.T) {
+	virtualMachine := vm.NewVM()
+
+	// Get the predefined primitive methods from the VM
+	stringClass := pile.ClassToObject(pile.ObjectToClass(virtualMachine.Globals["String"]))
+	sizeSelector := pile.NewSymbol("size")
+	atSelector := pile.NewSymbol("at:")
+	atPutSelector := pile.NewSymbol("at:put:")
+	sizeMethod := virtualMachine.LookupMethod(stringClass, sizeSelector)
+	atMethod := virtualMachine.LookupMethod(stringClass, atSelector)
+	atPutMethod := virtualMachine.LookupMethod(stringClass, atPutSelector)
+
+	// Create a test string with characters outside ASCII
+	testString := virtualMachine.NewString("añb☃")
+
+	// The size counts characters, not bytes
+	result := virtualMachine.ExecutePrimitive(testString, sizeSelector, []*pile.Object{}, sizeMethod)
+	if result != virtualMachine.NewInteger(4) {
+		t.Errorf("Expected size 4, got %v", result)
+	}
+
+	// at: answers the character at the index
+	result = virtualMachine.ExecutePrimitive(testString, atSelector, []*pile.Object{virtualMachine.NewInteger(2)}, atMethod)
+	if result != virtualMachine.NewCharacter('ñ') {
+		t.Errorf("Expected $ñ at 2, got %v", result)
+	}
+
+	// at:put: replaces the character at the index
+	args := []*pile.Object{virtualMachine.NewInteger(4), virtualMachine.NewCharacter('c')}
+	result = virtualMachine.ExecutePrimitive(testString, atPutSelector, args, atPutMethod)
+	if result != virtualMachine.NewCharacter('c') {
+		t.Errorf("Expected at:put: to answer $c, got %v", result)
+	}
+	if value := pile.GetStringValue(testString); value != "añbc" {
+		t.Errorf("Expected añbc after at:put:, got %q", value)
+	}
+
+	// Indices past the last character are out of bounds
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Expected panic for index out of bounds, but got none")
+			}
+		}()
+		virtualMachine.ExecutePrimitive(testString, atSelector, []*pile.Object{virtualMachine.NewInteger(5)}, atMethod)
+	}()
+}
+
+// TestUTF8Primitives tests converting strings to and from UTF-8 byte arrays
+func TestUTF8Primitives(t *testing.T) {
+	virtualMachine := vm.NewVM()
+
+	// Get the predefined primitive methods from the VM
+	encodedSelector := pile.NewSymbol("utf8Encoded")
+	decodedSelector := pile.NewSymbol("utf8Decoded")
+	encodedMethod := virtualMachine.LookupMethod(virtualMachine.Globals["String"], encodedSelector)
+	decodedMethod := virtualMachine.LookupMethod(virtualMachine.Globals["ByteArray"], decodedSelector)
+
+	// Encode a string with a two byte character
+	encoded := virtualMachine.ExecutePrimitive(virtualMachine.NewString("é!"), encodedSelector, []*pile.Object{}, encodedMethod)
+	if encoded == nil || encoded.Type() != pile.OBJ_BYTE_ARRAY {
+		t.Fatalf("Expected a byte array, got %v", encoded)
+	}
+	bytes := pile.ObjectToByteArray(encoded).Bytes
+	if string(bytes) != "\xc3\xa9!" {
+		t.Errorf("Expected the bytes C3 A9 21, got % X", bytes)
+	}
+
+	// Decoding the bytes answers the string again
+	decoded := virtualMachine.ExecutePrimitive(encoded, decodedSelector, []*pile.Object{}, decodedMethod)
+	if decoded == nil || decoded.Type() != pile.OBJ_STRING || pile.GetStringValue(decoded) != "é!" {
+		t.Errorf("Expected 'é!', got %v", decoded)
+	}
+
+	// Bytes that are not UTF-8 can't be decoded, so the primitive fails
+	invalid := virtualMachine.NewByteArray(2)
+	pile.ObjectToByteArray(invalid).AtPut(0, 0xff)
+	pile.ObjectToByteArray(invalid).AtPut(1, 0xc3)
+	if result := virtualMachine.ExecutePrimitive(invalid, decodedSelector, []*pile.Object{}, decodedMethod); result != nil {
+		t.Errorf("Expected utf8Decoded to fail for invalid UTF-8, got %v", result)
+	}
+}
@@ -10,4 +10,10 @@ func (vm *VM) NewSymbol(value string) *pile.Object {
 	symObj := pile.SymbolToObject(sym)
 	symObj.SetClass(vm.Globals["Symbol"]) // Symbols are instances of the Symbol class
 	return symObj
-}
\ No newline at end of file
+}
+
+// NewSymbolClass creates a new Symbol class
+func (vm *VM) NewSymbolClass() *pile.Class {
+	stringClass := pile.ObjectToClass(vm.Globals["String"])
+	return pile.NewClass("Symbol", stringClass)
+}
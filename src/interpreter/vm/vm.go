@@ -2,6 +2,7 @@ package vm
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"smalltalklsp/interpreter/compiler"
 	"smalltalklsp/interpreter/pile"
@@ -14,6 +15,11 @@ type VM struct {
 	ObjectMemory *pile.ObjectMemory
 	Executor     *Executor
 
+	// Bindings holds the associations of the globals referred to by compiled
+	// methods, Undeclared those of names referred to before being defined
+	Bindings   map[string]*pile.Object
+	Undeclared map[string]*pile.Object
+
 	// Special objects
 	NilObject   pile.ObjectInterface
 	TrueObject  pile.ObjectInterface
@@ -25,6 +31,8 @@ func NewVM() *VM {
 	vm := &VM{
 		Globals:      make(map[string]*pile.Object),
 		ObjectMemory: pile.NewObjectMemory(),
+		Bindings:     make(map[string]*pile.Object),
+		Undeclared:   make(map[string]*pile.Object),
 	}
 
 	// Initialize special immediate objects
@@ -37,37 +45,58 @@ func NewVM() *VM {
 
 	// Initialize core classes
 	objectClass := vm.NewObjectClass()
-	vm.Globals["Object"] = pile.ClassToObject(objectClass)
+	vm.SetGlobal("Object", pile.ClassToObject(objectClass))
 
 	classClass := vm.NewClassClass()
-	vm.Globals["Class"] = pile.ClassToObject(classClass)
+	vm.SetGlobal("Class", pile.ClassToObject(classClass))
 
 	nilClass := pile.NewClass("UndefinedObject", objectClass)
-	vm.Globals["UndefinedObject"] = pile.ClassToObject(nilClass)
+	vm.SetGlobal("UndefinedObject", pile.ClassToObject(nilClass))
 
 	trueClass := vm.NewTrueClass()
-	vm.Globals["True"] = pile.ClassToObject(trueClass)
+	vm.SetGlobal("True", pile.ClassToObject(trueClass))
 
 	falseClass := vm.NewFalseClass()
-	vm.Globals["False"] = pile.ClassToObject(falseClass)
+	vm.SetGlobal("False", pile.ClassToObject(falseClass))
 
 	integerClass := vm.NewIntegerClass()
-	vm.Globals["Integer"] = pile.ClassToObject(integerClass)
+	vm.SetGlobal("Integer", pile.ClassToObject(integerClass))
 
 	floatClass := vm.NewFloatClass()
-	vm.Globals["Float"] = pile.ClassToObject(floatClass)
+	vm.SetGlobal("Float", pile.ClassToObject(floatClass))
+
+	scaledDecimalClass := vm.NewScaledDecimalClass()
+	vm.SetGlobal("ScaledDecimal", pile.ClassToObject(scaledDecimalClass))
+
+	characterClass := vm.NewCharacterClass()
+	vm.SetGlobal("Character", pile.ClassToObject(characterClass))
 
 	stringClass := vm.NewStringClass()
-	vm.Globals["String"] = pile.ClassToObject(stringClass)
+	vm.SetGlobal("String", pile.ClassToObject(stringClass))
+
+	symbolClass := vm.NewSymbolClass()
+	vm.SetGlobal("Symbol", pile.ClassToObject(symbolClass))
 
 	blockClass := vm.NewBlockClass()
-	vm.Globals["Block"] = pile.ClassToObject(blockClass)
+	vm.SetGlobal("Block", pile.ClassToObject(blockClass))
 
 	arrayClass := vm.NewArrayClass()
-	vm.Globals["Array"] = pile.ClassToObject(arrayClass)
+	vm.SetGlobal("Array", pile.ClassToObject(arrayClass))
 
 	byteArrayClass := vm.NewByteArrayClass()
-	vm.Globals["ByteArray"] = pile.ClassToObject(byteArrayClass)
+	vm.SetGlobal("ByteArray", pile.ClassToObject(byteArrayClass))
+
+	associationClass := vm.NewAssociationClass()
+	vm.SetGlobal("Association", pile.ClassToObject(associationClass))
+
+	exceptionClass := pile.NewClass("Exception", objectClass)
+	vm.SetGlobal("Exception", pile.ClassToObject(exceptionClass))
+
+	errorClass := pile.NewClass("Error", exceptionClass)
+	vm.SetGlobal("Error", pile.ClassToObject(errorClass))
+
+	blockCannotReturnClass := pile.NewClass("BlockCannotReturn", errorClass)
+	vm.SetGlobal("BlockCannotReturn", pile.ClassToObject(blockCannotReturnClass))
 
 	// Initialize the executor
 	vm.Executor = NewExecutor(vm)
@@ -134,6 +163,15 @@ func (vm *VM) NewIntegerClass() *pile.Class {
 	// > method (greater than)
 	compiler.NewMethodBuilder(result).Primitive(7).Go(">")
 
+	// <= method (less than or equal)
+	compiler.NewMethodBuilder(result).Primitive(8).Go("<=")
+
+	// >= method (greater than or equal)
+	compiler.NewMethodBuilder(result).Primitive(9).Go(">=")
+
+	// ~= method (not equal)
+	compiler.NewMethodBuilder(result).Primitive(17).Go("~=")
+
 	return result
 }
 
@@ -253,9 +291,18 @@ func (vm *VM) NewStringClass() *pile.Class {
 
 	// Add primitive methods to the String class - create a new builder for each method
 	
-	// size method (returns the length of the string)
+	// size method (returns the number of characters of the string)
 	compiler.NewMethodBuilder(result).Primitive(30).Go("size")
 
+	// at: method (returns the character at the given index)
+	compiler.NewMethodBuilder(result).Primitive(31).Go("at:")
+
+	// at:put: method (sets the character at the given index)
+	compiler.NewMethodBuilder(result).Primitive(32).Go("at:put:")
+
+	// utf8Encoded method (returns the UTF-8 bytes of the string as a ByteArray)
+	compiler.NewMethodBuilder(result).Primitive(33).Go("utf8Encoded")
+
 	return result
 }
 
@@ -287,6 +334,9 @@ func (vm *VM) NewBlockClass() *pile.Class {
 	// value: method (executes the block with one argument)
 	compiler.NewMethodBuilder(result).Primitive(22).Go("value:")
 
+	// ensure: method (executes the block, then the argument block even when unwinding)
+	compiler.NewMethodBuilder(result).Primitive(23).Go("ensure:")
+
 	return result
 }
 
@@ -367,6 +417,13 @@ func (vm *VM) GetClass(obj *pile.Object) *pile.Class {
 			}
 			panic("GetClass: Float class not found in globals")
 		}
+		// Handle immediate character
+		if pile.IsCharacterImmediate(obj) {
+			if classObj, ok := vm.Globals["Character"]; ok {
+				return pile.ObjectToClass(classObj)
+			}
+			panic("GetClass: Character class not found in globals")
+		}
 		// Other immediate types will be added later
 		panic("GetClass: unknown immediate type")
 	}
@@ -530,6 +587,22 @@ func (vm *VM) ExecutePrimitive(receiver *pile.Object, selector *pile.Object, arg
 		if receiver.Type() == pile.OBJ_INTEGER || (len(args) > 0 && args[0].Type() == pile.OBJ_INTEGER) {
 			panic("Non-immediate integer encountered")
 		}
+	case 8: // Less than or equal
+		// Handle immediate integers
+		if pile.IsIntegerImmediate(receiver) && len(args) == 1 && pile.IsIntegerImmediate(args[0]) {
+			val1 := pile.GetIntegerImmediate(receiver)
+			val2 := pile.GetIntegerImmediate(args[0])
+			result := val1 <= val2
+			return pile.NewBoolean(result).(*pile.Object)
+		}
+	case 9: // Greater than or equal
+		// Handle immediate integers
+		if pile.IsIntegerImmediate(receiver) && len(args) == 1 && pile.IsIntegerImmediate(args[0]) {
+			val1 := pile.GetIntegerImmediate(receiver)
+			val2 := pile.GetIntegerImmediate(args[0])
+			result := val1 >= val2
+			return pile.NewBoolean(result).(*pile.Object)
+		}
 	case 10: // Float addition
 		// Handle float + float
 		if pile.IsFloatImmediate(receiver) && len(args) == 1 && pile.IsFloatImmediate(args[0]) {
@@ -635,6 +708,14 @@ func (vm *VM) ExecutePrimitive(receiver *pile.Object, selector *pile.Object, arg
 			result := val1 > val2
 			return pile.NewBoolean(result).(*pile.Object)
 		}
+	case 17: // Not equal
+		// Handle immediate integers
+		if pile.IsIntegerImmediate(receiver) && len(args) == 1 && pile.IsIntegerImmediate(args[0]) {
+			val1 := pile.GetIntegerImmediate(receiver)
+			val2 := pile.GetIntegerImmediate(args[0])
+			result := val1 != val2
+			return pile.NewBoolean(result).(*pile.Object)
+		}
 	case 20: // Block new - create a new block instance
 		if receiver.Type() == pile.OBJ_CLASS && receiver == vm.Globals["Block"] {
 			// Create a new block instance with proper class field
@@ -643,53 +724,16 @@ func (vm *VM) ExecutePrimitive(receiver *pile.Object, selector *pile.Object, arg
 		}
 	case 21: // Block value - execute a block with no arguments
 		if receiver.Type() == pile.OBJ_BLOCK {
-			// Get the block
-			block := pile.ObjectToBlock(receiver)
-
-			// Create a method object for the block's bytecodes
-			method := &pile.Method{
-				Object: pile.Object{
-					TypeField: pile.OBJ_METHOD,
-				},
-				Bytecodes: block.GetBytecodes(),
-				Literals:  block.GetLiterals(),
-			}
-			methodObj := pile.MethodToObject(method)
-
-			// Create a new context for the block execution
-			blockContext := NewContext(methodObj, receiver, []*pile.Object{}, block.GetOuterContext().(*Context))
-
-			// Execute the block's bytecodes
-			result, err := vm.ExecuteContext(blockContext)
-			if err != nil {
-				panic(fmt.Sprintf("Error executing block: %v", err))
-			}
-			return result.(*pile.Object)
+			return vm.ExecuteBlock(receiver, []*pile.Object{})
 		}
 	case 22: // Block value: - execute a block with one argument
 		if receiver.Type() == pile.OBJ_BLOCK && len(args) == 1 {
-			// Get the block
-			block := pile.ObjectToBlock(receiver)
-
-			// Create a method object for the block's bytecodes
-			method := &pile.Method{
-				Object: pile.Object{
-					TypeField: pile.OBJ_METHOD,
-				},
-				Bytecodes: block.GetBytecodes(),
-				Literals:  block.GetLiterals(),
-			}
-			methodObj := pile.MethodToObject(method)
-
-			// Create a new context for the block execution
-			blockContext := NewContext(methodObj, receiver, args, block.GetOuterContext().(*Context))
-
-			// Execute the block's bytecodes
-			result, err := vm.ExecuteContext(blockContext)
-			if err != nil {
-				panic(fmt.Sprintf("Error executing block: %v", err))
-			}
-			return result.(*pile.Object)
+			return vm.ExecuteBlock(receiver, args)
+		}
+	case 23: // Block ensure: - execute a block, then the argument block
+		if !pile.IsImmediate(receiver) && receiver.Type() == pile.OBJ_BLOCK &&
+			len(args) == 1 && !pile.IsImmediate(args[0]) && args[0].Type() == pile.OBJ_BLOCK {
+			return vm.ExecuteBlockEnsure(receiver, args[0])
 		}
 	case 30: // String size - return the length of the string
 		if receiver.Type() == pile.OBJ_STRING {
@@ -702,6 +746,56 @@ func (vm *VM) ExecutePrimitive(receiver *pile.Object, selector *pile.Object, arg
 			// Return the length as an integer
 			return vm.NewInteger(int64(length))
 		}
+		if receiver.Type() == pile.OBJ_SYMBOL {
+			// Symbols inherit size from String
+			return vm.NewInteger(int64(pile.ObjectToSymbol(receiver).Length()))
+		}
+	case 31: // String at: - return the character at the given index
+		if receiver.Type() == pile.OBJ_STRING && len(args) == 1 && pile.IsIntegerImmediate(args[0]) {
+			// Get the string
+			str := pile.ObjectToString(receiver)
+
+			// Get the index (1-based in Smalltalk, 0-based in Go)
+			index := pile.GetIntegerImmediate(args[0]) - 1
+
+			// Check bounds
+			if index < 0 || int(index) >= str.Length() {
+				panic(fmt.Sprintf("String index out of bounds: %d", index+1))
+			}
+
+			// Return the character at the given index
+			return vm.NewCharacter(str.CharAt(int(index)))
+		}
+	case 32: // String at:put: - set the character at the given index
+		if receiver.Type() == pile.OBJ_STRING && len(args) == 2 &&
+			pile.IsIntegerImmediate(args[0]) && pile.IsCharacterImmediate(args[1]) {
+			// Get the string
+			str := pile.ObjectToString(receiver)
+
+			// Get the index (1-based in Smalltalk, 0-based in Go)
+			index := pile.GetIntegerImmediate(args[0]) - 1
+
+			// Check bounds
+			if index < 0 || int(index) >= str.Length() {
+				panic(fmt.Sprintf("String index out of bounds: %d", index+1))
+			}
+
+			// Set the character at the given index
+			str.AtPut(int(index), pile.GetCharacterImmediate(args[1]))
+
+			// Return the character
+			return args[1]
+		}
+	case 33: // String utf8Encoded - return the UTF-8 bytes of the string
+		if receiver.Type() == pile.OBJ_STRING {
+			value := pile.ObjectToString(receiver).GetValue()
+
+			// Copy the bytes into a new byte array
+			byteArrayObj := vm.NewByteArray(len(value))
+			copy(pile.ObjectToByteArray(byteArrayObj).Bytes, value)
+
+			return byteArrayObj
+		}
 	case 40: // Array at: - return the element at the given index
 		if receiver.Type() == pile.OBJ_ARRAY && len(args) == 1 && pile.IsIntegerImmediate(args[0]) {
 			// Get the array
@@ -762,6 +856,15 @@ func (vm *VM) ExecutePrimitive(receiver *pile.Object, selector *pile.Object, arg
 			// Return the value
 			return args[1]
 		}
+	case 52: // ByteArray utf8Decoded - return the string encoded by the bytes
+		if receiver.Type() == pile.OBJ_BYTE_ARRAY {
+			bytes := pile.ObjectToByteArray(receiver).Bytes
+
+			// Only valid UTF-8 can be decoded, other bytes fail the primitive
+			if utf8.Valid(bytes) {
+				return vm.NewString(string(bytes))
+			}
+		}
 	case 60: // Class new - create a new instance of a class
 		if receiver.Type() == pile.OBJ_CLASS {
 			// Get the class
@@ -775,6 +878,10 @@ func (vm *VM) ExecutePrimitive(receiver *pile.Object, selector *pile.Object, arg
 
 			return instance
 		}
+	case 70: // Character value - return the code point of the character
+		if pile.IsCharacterImmediate(receiver) {
+			return vm.NewInteger(int64(pile.GetCharacterImmediate(receiver)))
+		}
 	default:
 		panic("executePrimitive: unknown primitive index\n")
 	}